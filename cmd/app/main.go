@@ -27,7 +27,12 @@ func main() {
 	ctx := context.Background()
 	cfg, err := config.LoadConfig("config/config.yaml")
 	if err != nil {
-		panic("config load failed")
+		panic("config load failed: " + err.Error())
+	}
+	// aws:SourceIp of queue policies, never taken from headers a client can forge
+	ipExtractor, err := imiddle.IPExtractor(cfg.TrustedProxies)
+	if err != nil {
+		panic("config load failed: " + err.Error())
 	}
 
 	glogger.GlobalLogger(cfg)
@@ -63,22 +68,54 @@ func main() {
 	ackDispatcher.Start()
 	defer ackDispatcher.Stop()
 
+	receipts := service.NewReceiptCodec(cfg.Message.ReceiptSecret)
+
 	ackTimeout := 30 * time.Second
-	messageSvc := service.NewMessageService(ackDispatcher, ackTimeout, natsRepo, valkeyRepo)
-	queueSvc := service.NewQueueService(natsRepo, cfg)
+	messageSvc := service.NewMessageService(ackDispatcher, ackTimeout, natsRepo, valkeyRepo, receipts)
+	queueSvc := service.NewQueueService(natsRepo, valkeyRepo, cfg)
+
+	// Message move tasks resume from valkey after a restart
+	moveTaskSvc := service.NewMoveTaskService(natsRepo, valkeyRepo, cfg)
+	moveTaskSvc.Start(logs.WithLogger(ctx, logger))
+	defer moveTaskSvc.Stop()
+
+	// Topic subscriptions deliver to their queues in the background
+	topicSvc := service.NewTopicService(natsRepo, queueSvc, messageSvc, cfg)
+	topicSvc.Start(logs.WithLogger(ctx, logger))
+	defer topicSvc.Stop()
 
 	// Handler resource create
-	accountBase := handler.AccountBaseHandlers(queueSvc)
+	accountBase := handler.AccountBaseHandlers(queueSvc, moveTaskSvc, topicSvc)
 	accountQueueBase := handler.AccountQueueBaseHandlers(queueSvc, messageSvc)
+	awsOperations := handler.AwsOperations(queueSvc, messageSvc, moveTaskSvc, cfg.DefaultAccount)
 
 	// echo start
 	e := echo.New()
 	e.Any("/metrics", echo.WrapHandler(promhttp.Handler()))
 	imiddle.AttachMiddlewares(e, logger)
+	e.IPExtractor = ipExtractor
+
+	// SigV4 authentication, keys from the config or from valkey
+	var authMiddlewares []echo.MiddlewareFunc
+	if cfg.Auth.Enabled {
+		keyStore := imiddle.NewConfigKeyStore(cfg.Auth.Keys)
+		if cfg.Auth.Store == "valkey" {
+			keyStore = valkeyRepo
+		}
+		authMiddlewares = append(authMiddlewares, imiddle.SigV4(imiddle.SigV4Config{
+			Store:        keyStore,
+			Region:       cfg.Region,
+			MaxClockSkew: time.Duration(cfg.Auth.MaxClockSkewSeconds) * time.Second,
+			ErrorHandler: handler.WriteError,
+		}))
+	} else {
+		glogger.Warn(ctx, "auth.enabled is false. Requests are not authenticated.")
+	}
 
 	// Setup router
-	apiRouter := handler.NewApiRouter(accountBase, accountQueueBase)
-	apiRouter.Register(e.Group(apiVer))
+	apiRouter := handler.NewApiRouter(accountBase, accountQueueBase, awsOperations, queueSvc)
+	apiRouter.Register(e.Group(apiVer, authMiddlewares...))
+	apiRouter.RegisterAwsProtocols(e, authMiddlewares...)
 
 	go func() {
 		glogger.Info(ctx, "API server is running", "url", "http://localhost:8080")
@@ -8,8 +8,9 @@ import (
 )
 
 type AckResult struct {
-	Status   string `json:"status"`   // "PENDING", "ACK", "FAILED", "TIMEOUT"
-	Sequence uint64 `json:"sequence"` // JetStream Sequence if ACK
+	Status    string `json:"status"`              // "PENDING", "ACK", "FAILED", "TIMEOUT"
+	Sequence  uint64 `json:"sequence"`            // JetStream Sequence if ACK
+	Duplicate bool   `json:"duplicate,omitempty"` // dropped by the deduplication window, Sequence is the original
 }
 
 // AckTask represents an individual publish ack to be tracked.
@@ -0,0 +1,73 @@
+package entity
+
+// BatchResultErrorEntry reports a batch entry that could not be processed.
+type BatchResultErrorEntry struct {
+	Id          string `json:"Id"`
+	SenderFault bool   `json:"SenderFault"`
+	Code        string `json:"Code"`
+	Message     string `json:"Message"`
+}
+
+type DeleteMessageBatchRequestEntry struct {
+	Id            string `json:"Id" validate:"required"`
+	ReceiptHandle string `json:"ReceiptHandle" validate:"required"`
+}
+
+type DeleteMessageBatchResultEntry struct {
+	Id string `json:"Id"`
+}
+
+type DeleteMessageBatchResult struct {
+	Successful []DeleteMessageBatchResultEntry `json:"Successful"`
+	Failed     []BatchResultErrorEntry         `json:"Failed"`
+}
+
+type ChangeMessageVisibilityBatchRequestEntry struct {
+	Id                string `json:"Id" validate:"required"`
+	ReceiptHandle     string `json:"ReceiptHandle" validate:"required"`
+	VisibilityTimeout int    `json:"VisibilityTimeout" validate:"min=0,max=43200"`
+}
+
+type ChangeMessageVisibilityBatchResultEntry struct {
+	Id string `json:"Id"`
+}
+
+type ChangeMessageVisibilityBatchResult struct {
+	Successful []ChangeMessageVisibilityBatchResultEntry `json:"Successful"`
+	Failed     []BatchResultErrorEntry                   `json:"Failed"`
+}
+
+type SendMessageBatchRequestEntry struct {
+	Id                      string                           `json:"Id" validate:"required"`
+	MessageBody             string                           `json:"MessageBody" validate:"required"`
+	DelaySeconds            *int                             `json:"DelaySeconds"`
+	MessageGroupId          string                           `json:"MessageGroupId"`
+	MessageDeduplicationId  string                           `json:"MessageDeduplicationId"`
+	MessageAttributes       map[string]MessageAttributeValue `json:"MessageAttributes"`
+	MessageSystemAttributes map[string]MessageAttributeValue `json:"MessageSystemAttributes"`
+}
+
+// SendOptions returns the per-message parameters of the entry.
+func (e SendMessageBatchRequestEntry) SendOptions() SendOptions {
+	return SendOptions{
+		MessageGroupId:          e.MessageGroupId,
+		MessageDeduplicationId:  e.MessageDeduplicationId,
+		DelaySeconds:            e.DelaySeconds,
+		MessageAttributes:       e.MessageAttributes,
+		MessageSystemAttributes: e.MessageSystemAttributes,
+	}
+}
+
+type SendMessageBatchResultEntry struct {
+	Id                           string `json:"Id"`
+	MessageId                    string `json:"MessageId"`
+	MD5OfMessageBody             string `json:"MD5OfMessageBody"`
+	MD5OfMessageAttributes       string `json:"MD5OfMessageAttributes,omitempty"`
+	MD5OfMessageSystemAttributes string `json:"MD5OfMessageSystemAttributes,omitempty"`
+	SequenceNumber               string `json:"SequenceNumber,omitempty"`
+}
+
+type SendMessageBatchResult struct {
+	Successful []SendMessageBatchResultEntry `json:"Successful"`
+	Failed     []BatchResultErrorEntry       `json:"Failed"`
+}
@@ -0,0 +1,8 @@
+package entity
+
+// Credential is an access key and the account requests signed with it act for.
+type Credential struct {
+	AccessKeyId     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	Account         string `json:"Account"`
+}
@@ -1,5 +1,10 @@
 package entity
 
+import (
+	"errors"
+	"strings"
+)
+
 // Error represents the error structure returned by SCP SNS.
 type Error struct {
 	Type    string `json:"Type"`
@@ -30,6 +35,51 @@ var (
 		},
 	}
 
+	MissingAuthenticationToken = ErrorResponse{
+		HTTPCode: 403,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "MissingAuthenticationToken",
+			Message: "The request must contain either a valid (registered) access key ID or X.509 certificate.",
+		},
+	}
+
+	IncompleteSignature = ErrorResponse{
+		HTTPCode: 400,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "IncompleteSignature",
+			Message: "The request signature does not conform to AWS standards.",
+		},
+	}
+
+	InvalidClientTokenId = ErrorResponse{
+		HTTPCode: 403,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "InvalidClientTokenId",
+			Message: "The access key ID provided does not exist in our records.",
+		},
+	}
+
+	SignatureDoesNotMatch = ErrorResponse{
+		HTTPCode: 403,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "SignatureDoesNotMatch",
+			Message: "The request signature we calculated does not match the signature you provided.",
+		},
+	}
+
+	RequestExpired = ErrorResponse{
+		HTTPCode: 403,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "RequestExpired",
+			Message: "The request reached the service outside the allowed clock skew or after its expiration date.",
+		},
+	}
+
 	InternalError = ErrorResponse{
 		HTTPCode: 500,
 		Error: Error{
@@ -48,6 +98,123 @@ var (
 		},
 	}
 
+	MissingParameter = ErrorResponse{
+		HTTPCode: 400,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "MissingParameter",
+			Message: "A required parameter for the specified action is not supplied.",
+		},
+	}
+
+	QueueAlreadyExists = ErrorResponse{
+		HTTPCode: 400,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "QueueAlreadyExists",
+			Message: "A queue with this name already exists with different attributes.",
+		},
+	}
+
+	PurgeQueueInProgress = ErrorResponse{
+		HTTPCode: 403,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "PurgeQueueInProgress",
+			Message: "Indicates that the specified queue previously received a PurgeQueue request within the last 60 seconds.",
+		},
+	}
+
+	InvalidAttributeName = ErrorResponse{
+		HTTPCode: 400,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "InvalidAttributeName",
+			Message: "The specified attribute doesn't exist.",
+		},
+	}
+
+	InvalidAttributeValue = ErrorResponse{
+		HTTPCode: 400,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "InvalidAttributeValue",
+			Message: "A queue attribute value is invalid.",
+		},
+	}
+
+	ReceiptHandleIsInvalid = ErrorResponse{
+		HTTPCode: 400,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "ReceiptHandleIsInvalid",
+			Message: "The specified receipt handle isn't valid.",
+		},
+	}
+
+	EmptyBatchRequest = ErrorResponse{
+		HTTPCode: 400,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "EmptyBatchRequest",
+			Message: "The batch request doesn't contain any entries.",
+		},
+	}
+
+	TooManyEntriesInBatchRequest = ErrorResponse{
+		HTTPCode: 400,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "TooManyEntriesInBatchRequest",
+			Message: "The batch request contains more entries than permissible.",
+		},
+	}
+
+	BatchRequestTooLong = ErrorResponse{
+		HTTPCode: 400,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "BatchRequestTooLong",
+			Message: "The length of all the messages put together is more than the limit.",
+		},
+	}
+
+	BatchEntryIdsNotDistinct = ErrorResponse{
+		HTTPCode: 400,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "BatchEntryIdsNotDistinct",
+			Message: "Two or more batch entries in the request have the same Id.",
+		},
+	}
+
+	InvalidBatchEntryId = ErrorResponse{
+		HTTPCode: 400,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "InvalidBatchEntryId",
+			Message: "The Id of a batch entry in a batch request doesn't abide by the specification.",
+		},
+	}
+
+	UnsupportedOperation = ErrorResponse{
+		HTTPCode: 400,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "UnsupportedOperation",
+			Message: "The specified action is not supported in the current state.",
+		},
+	}
+
+	InvalidAction = ErrorResponse{
+		HTTPCode: 400,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "InvalidAction",
+			Message: "The action or operation requested is invalid.",
+		},
+	}
+
 	NotFound = ErrorResponse{
 		HTTPCode: 404,
 		Error: Error{
@@ -57,3 +224,156 @@ var (
 		},
 	}
 )
+
+// Sentinel errors returned by the service layer. ErrorResponseOf maps them to
+// the predefined ErrorResponse values above.
+var (
+	ErrQueueNotFound        = errors.New("queue does not exist")
+	ErrAuthorization        = errors.New("access to the queue is denied")
+	ErrQueueAlreadyExists   = errors.New("queue already exists with different attributes")
+	ErrPurgeQueueInProgress = errors.New("queue was purged within the last 60 seconds")
+	ErrInvalidParameter     = errors.New("invalid parameter")
+	ErrMissingParameter     = errors.New("missing parameter")
+	ErrInvalidAction        = errors.New("invalid action")
+
+	ErrInvalidAttributeName  = errors.New("invalid attribute name")
+	ErrInvalidAttributeValue = errors.New("invalid attribute value")
+
+	ErrReceiptHandleInvalid     = errors.New("receipt handle is invalid")
+	ErrEmptyBatchRequest        = errors.New("batch request is empty")
+	ErrTooManyEntriesInBatch    = errors.New("too many entries in batch request")
+	ErrBatchRequestTooLong      = errors.New("batch request is too long")
+	ErrBatchEntryIdsNotDistinct = errors.New("batch entry ids are not distinct")
+	ErrInvalidBatchEntryId      = errors.New("invalid batch entry id")
+
+	ErrMissingAuthenticationToken = errors.New("request is not signed")
+	ErrIncompleteSignature        = errors.New("incomplete signature")
+	ErrInvalidClientTokenId       = errors.New("access key does not exist")
+	ErrSignatureDoesNotMatch      = errors.New("signature does not match")
+	ErrRequestExpired             = errors.New("request expired")
+
+	ErrMoveTaskNotFound       = errors.New("message move task does not exist")
+	ErrMoveTaskAlreadyRunning = errors.New("a message move task is already running for the source queue")
+	ErrMoveTaskNotRunning     = errors.New("message move task is not running")
+
+	ErrTopicNotFound        = errors.New("topic does not exist")
+	ErrSubscriptionNotFound = errors.New("subscription does not exist")
+)
+
+// ErrorResponseOf maps an error returned by the service layer to the SQS error response.
+func ErrorResponseOf(err error) ErrorResponse {
+	switch {
+	case errors.Is(err, ErrQueueNotFound), errors.Is(err, ErrMoveTaskNotFound),
+		errors.Is(err, ErrTopicNotFound), errors.Is(err, ErrSubscriptionNotFound):
+		return NotFound
+	case errors.Is(err, ErrAuthorization):
+		return AuthorizationError
+	case errors.Is(err, ErrMissingAuthenticationToken):
+		return MissingAuthenticationToken
+	case errors.Is(err, ErrIncompleteSignature):
+		return withDetail(IncompleteSignature, err, ErrIncompleteSignature)
+	case errors.Is(err, ErrInvalidClientTokenId):
+		return InvalidClientTokenId
+	case errors.Is(err, ErrSignatureDoesNotMatch):
+		return SignatureDoesNotMatch
+	case errors.Is(err, ErrRequestExpired):
+		return RequestExpired
+	case errors.Is(err, ErrQueueAlreadyExists):
+		return QueueAlreadyExists
+	case errors.Is(err, ErrPurgeQueueInProgress):
+		return PurgeQueueInProgress
+	case errors.Is(err, ErrInvalidParameter):
+		return withDetail(InvalidParameter, err, ErrInvalidParameter)
+	case errors.Is(err, ErrMissingParameter):
+		return MissingParameter
+	case errors.Is(err, ErrInvalidAction):
+		return InvalidAction
+	case errors.Is(err, ErrInvalidAttributeName):
+		return InvalidAttributeName
+	case errors.Is(err, ErrInvalidAttributeValue):
+		return InvalidAttributeValue
+	case errors.Is(err, ErrReceiptHandleInvalid):
+		return ReceiptHandleIsInvalid
+	case errors.Is(err, ErrEmptyBatchRequest):
+		return EmptyBatchRequest
+	case errors.Is(err, ErrTooManyEntriesInBatch):
+		return TooManyEntriesInBatchRequest
+	case errors.Is(err, ErrBatchRequestTooLong):
+		return BatchRequestTooLong
+	case errors.Is(err, ErrBatchEntryIdsNotDistinct):
+		return BatchEntryIdsNotDistinct
+	case errors.Is(err, ErrInvalidBatchEntryId):
+		return InvalidBatchEntryId
+	case errors.Is(err, ErrMoveTaskAlreadyRunning), errors.Is(err, ErrMoveTaskNotRunning):
+		return UnsupportedOperation
+	default:
+		return InternalError
+	}
+}
+
+// withDetail replaces the generic message with what err adds to the sentinel,
+// so clients see which parameter was rejected.
+func withDetail(resp ErrorResponse, err, sentinel error) ErrorResponse {
+	if detail, ok := strings.CutPrefix(err.Error(), sentinel.Error()+": "); ok && detail != "" {
+		resp.Error.Message = detail
+	}
+	return resp
+}
+
+// NewBatchResultErrorEntry reports a failed batch entry using the same code as the single action.
+func NewBatchResultErrorEntry(id string, err error) BatchResultErrorEntry {
+	resp := ErrorResponseOf(err)
+	return BatchResultErrorEntry{
+		Id:          id,
+		SenderFault: strings.EqualFold(resp.Error.Type, "Sender"),
+		Code:        resp.Error.Code,
+		Message:     resp.Error.Message,
+	}
+}
+
+// AwsError names an error the way the AWS SQS protocols report it.
+type AwsError struct {
+	Code      string // JSON protocol __type shape
+	QueryCode string // Query protocol code, also sent in x-amzn-query-error
+	HTTPCode  int
+}
+
+// awsErrors maps the ErrorResponse codes to their AWS SQS counterparts.
+var awsErrors = map[string]AwsError{
+	AuthorizationError.Error.Code:           {"AccessDenied", "AccessDenied", 403},
+	InternalError.Error.Code:                {"InternalError", "InternalError", 500},
+	MissingAuthenticationToken.Error.Code:   {"MissingAuthenticationToken", "MissingAuthenticationToken", 403},
+	IncompleteSignature.Error.Code:          {"IncompleteSignature", "IncompleteSignature", 400},
+	InvalidClientTokenId.Error.Code:         {"InvalidClientTokenId", "InvalidClientTokenId", 403},
+	SignatureDoesNotMatch.Error.Code:        {"SignatureDoesNotMatch", "SignatureDoesNotMatch", 403},
+	RequestExpired.Error.Code:               {"RequestExpired", "RequestExpired", 403},
+	InvalidParameter.Error.Code:             {"InvalidParameterValue", "InvalidParameterValue", 400},
+	MissingParameter.Error.Code:             {"MissingParameter", "MissingParameter", 400},
+	InvalidAction.Error.Code:                {"UnknownOperationException", "InvalidAction", 400},
+	QueueAlreadyExists.Error.Code:           {"QueueNameExists", "QueueAlreadyExists", 400},
+	PurgeQueueInProgress.Error.Code:         {"PurgeQueueInProgress", "AWS.SimpleQueueService.PurgeQueueInProgress", 403},
+	InvalidAttributeName.Error.Code:         {"InvalidAttributeName", "InvalidAttributeName", 400},
+	InvalidAttributeValue.Error.Code:        {"InvalidAttributeValue", "InvalidAttributeValue", 400},
+	ReceiptHandleIsInvalid.Error.Code:       {"ReceiptHandleIsInvalid", "ReceiptHandleIsInvalid", 400},
+	EmptyBatchRequest.Error.Code:            {"EmptyBatchRequest", "AWS.SimpleQueueService.EmptyBatchRequest", 400},
+	TooManyEntriesInBatchRequest.Error.Code: {"TooManyEntriesInBatchRequest", "AWS.SimpleQueueService.TooManyEntriesInBatchRequest", 400},
+	BatchRequestTooLong.Error.Code:          {"BatchRequestTooLong", "AWS.SimpleQueueService.BatchRequestTooLong", 400},
+	BatchEntryIdsNotDistinct.Error.Code:     {"BatchEntryIdsNotDistinct", "AWS.SimpleQueueService.BatchEntryIdsNotDistinct", 400},
+	InvalidBatchEntryId.Error.Code:          {"InvalidBatchEntryId", "AWS.SimpleQueueService.InvalidBatchEntryId", 400},
+	UnsupportedOperation.Error.Code:         {"UnsupportedOperation", "AWS.SimpleQueueService.UnsupportedOperation", 400},
+	NotFound.Error.Code:                     {"QueueDoesNotExist", "AWS.SimpleQueueService.NonExistentQueue", 400},
+}
+
+// AwsErrorOf maps an error returned by the service layer to the AWS SQS error
+// and the message to report with it.
+func AwsErrorOf(err error) (AwsError, ErrorResponse) {
+	resp := ErrorResponseOf(err)
+	if errors.Is(err, ErrMoveTaskNotFound) {
+		return AwsError{"ResourceNotFoundException", "ResourceNotFoundException", 404}, resp
+	}
+	awsErr, ok := awsErrors[resp.Error.Code]
+	if !ok {
+		return awsErrors[InternalError.Error.Code], InternalError
+	}
+	return awsErr, resp
+}
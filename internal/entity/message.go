@@ -0,0 +1,68 @@
+package entity
+
+// NATS headers stored alongside a message.
+const (
+	// HeaderMessageId carries the SQS MessageId assigned at send time.
+	HeaderMessageId = "Sqs-Message-Id"
+	// HeaderMessageGroupId carries the MessageGroupId of a FIFO queue message.
+	HeaderMessageGroupId = "Sqs-Message-Group-Id"
+	// HeaderDelaySeconds carries the DelaySeconds given at send time.
+	HeaderDelaySeconds = "Sqs-Delay-Seconds"
+)
+
+// MaxMessageSize is the SQS limit (256 KiB) of a message, attributes included,
+// and of all the messages of a batch together.
+const MaxMessageSize = 262144
+
+// Message is a single message handed out by ReceiveMessage.
+type Message struct {
+	MessageId              string                           `json:"MessageId"`
+	ReceiptHandle          string                           `json:"ReceiptHandle"`
+	Body                   string                           `json:"Body"`
+	MD5OfBody              string                           `json:"MD5OfBody"`
+	Attributes             map[string]string                `json:"Attributes,omitempty"`
+	MessageAttributes      map[string]MessageAttributeValue `json:"MessageAttributes,omitempty"`
+	MD5OfMessageAttributes string                           `json:"MD5OfMessageAttributes,omitempty"`
+}
+
+// SendOptions carries the optional SendMessage parameters.
+type SendOptions struct {
+	MessageGroupId          string // required for FIFO queues
+	MessageDeduplicationId  string // FIFO queues only, stored as the Nats-Msg-Id header
+	DelaySeconds            *int   // nil uses the queue DelaySeconds attribute
+	MessageAttributes       map[string]MessageAttributeValue
+	MessageSystemAttributes map[string]MessageAttributeValue
+}
+
+// SendMessageResult is the outcome of SendMessage. SequenceNumber and Duplicate
+// are only known to synchronous sends; Duplicate is reported for FIFO queues.
+type SendMessageResult struct {
+	MessageId                    string `json:"MessageId"`
+	MD5OfMessageBody             string `json:"MD5OfMessageBody"`
+	MD5OfMessageAttributes       string `json:"MD5OfMessageAttributes,omitempty"`
+	MD5OfMessageSystemAttributes string `json:"MD5OfMessageSystemAttributes,omitempty"`
+	SequenceNumber               string `json:"SequenceNumber,omitempty"`
+	Duplicate                    *bool  `json:"Duplicate,omitempty"` // the deduplication window dropped the message, MessageId is the original one
+}
+
+// ReceiveOptions carries the optional ReceiveMessage parameters.
+type ReceiveOptions struct {
+	MaxNumberOfMessages int
+	VisibilityTimeout   *int // seconds, nil keeps the queue default
+	WaitTimeSeconds     *int // seconds, nil uses the queue ReceiveMessageWaitTimeSeconds
+
+	AttributeNames        []string // system attributes to return, "All" for every one
+	MessageAttributeNames []string // message attributes to return, "All", ".*" or "prefix.*"
+}
+
+// ReceiptHandle identifies one delivery of a message. It carries everything
+// needed to rebuild the JetStream ack subject, so any API instance can
+// acknowledge a message without shared state.
+type ReceiptHandle struct {
+	Stream       string `json:"s"`
+	Consumer     string `json:"c"`
+	StreamSeq    uint64 `json:"ss"`
+	ConsumerSeq  uint64 `json:"cs"`
+	NumDelivered uint64 `json:"nd"`
+	Timestamp    int64  `json:"ts"`
+}
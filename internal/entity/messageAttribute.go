@@ -0,0 +1,38 @@
+package entity
+
+// Message attributes are stored as one NATS header per attribute, named
+// prefix + attribute name, holding the JSON encoded MessageAttributeValue.
+const (
+	HeaderMessageAttributePrefix       = "Sqs-Attr-"
+	HeaderMessageSystemAttributePrefix = "Sqs-System-Attr-"
+)
+
+// MaxMessageAttributes is the SQS limit of message attributes per message.
+const MaxMessageAttributes = 10
+
+// Message attribute data types. A custom type appends ".<label>", e.g. "Number.float".
+const (
+	DataTypeString = "String"
+	DataTypeNumber = "Number"
+	DataTypeBinary = "Binary"
+)
+
+// MessageSystemAttributeAWSTraceHeader is the only system attribute a sender may set.
+const MessageSystemAttributeAWSTraceHeader = "AWSTraceHeader"
+
+// System attribute names returned by ReceiveMessage when requested.
+const (
+	SystemAttrAll                     = "All"
+	SystemAttrSentTimestamp           = "SentTimestamp"
+	SystemAttrApproximateReceiveCount = "ApproximateReceiveCount"
+	SystemAttrMessageGroupId          = "MessageGroupId"
+	SystemAttrMessageDeduplicationId  = "MessageDeduplicationId"
+)
+
+// MessageAttributeValue is a typed user or system message attribute.
+// BinaryValue is base64 encoded in JSON.
+type MessageAttributeValue struct {
+	DataType    string `json:"DataType" validate:"required"`
+	StringValue string `json:"StringValue,omitempty"`
+	BinaryValue []byte `json:"BinaryValue,omitempty"`
+}
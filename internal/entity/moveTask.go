@@ -0,0 +1,35 @@
+package entity
+
+// Message move task statuses.
+const (
+	MoveTaskRunning    = "RUNNING"
+	MoveTaskCompleted  = "COMPLETED"
+	MoveTaskCancelling = "CANCELLING"
+	MoveTaskCancelled  = "CANCELLED"
+	MoveTaskFailed     = "FAILED"
+)
+
+// MessageMoveTask describes a dead-letter queue redrive as returned by ListMessageMoveTasks.
+type MessageMoveTask struct {
+	TaskHandle                        string `json:"TaskHandle,omitempty"`
+	SourceArn                         string `json:"SourceArn"`
+	DestinationArn                    string `json:"DestinationArn,omitempty"`
+	MaxNumberOfMessagesPerSecond      int    `json:"MaxNumberOfMessagesPerSecond,omitempty"`
+	Status                            string `json:"Status"`
+	FailureReason                     string `json:"FailureReason,omitempty"`
+	ApproximateNumberOfMessagesMoved  int64  `json:"ApproximateNumberOfMessagesMoved"`
+	ApproximateNumberOfMessagesToMove int64  `json:"ApproximateNumberOfMessagesToMove"`
+	StartedTimestamp                  int64  `json:"StartedTimestamp"`
+}
+
+// MessageMoveTaskRecord is the persisted task state, including the cursor used to resume after a restart.
+type MessageMoveTaskRecord struct {
+	MessageMoveTask
+	NextSequence uint64 `json:"NextSequence"`
+	EndSequence  uint64 `json:"EndSequence"`
+}
+
+// Active reports whether the task still owns its source queue.
+func (t MessageMoveTask) Active() bool {
+	return t.Status == MoveTaskRunning || t.Status == MoveTaskCancelling
+}
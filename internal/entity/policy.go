@@ -0,0 +1,276 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// PolicyVersion is the policy language version written by AddPermission.
+const PolicyVersion = "2012-10-17"
+
+// SqsActionPrefix prefixes the action names used in policy statements.
+const SqsActionPrefix = "sqs:"
+
+// Policy statement effects.
+const (
+	EffectAllow = "Allow"
+	EffectDeny  = "Deny"
+)
+
+// Condition keys set on an AccessRequest.
+const (
+	ConditionSourceArn     = "aws:SourceArn"
+	ConditionSourceIp      = "aws:SourceIp"
+	ConditionSourceAccount = "aws:SourceAccount"
+)
+
+// StringList is a policy element written either as a single string or as a list.
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = StringList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("must be a string or a list of strings")
+	}
+	*l = list
+	return nil
+}
+
+// PolicyPrincipal is "*" or the accounts and services a statement applies to.
+type PolicyPrincipal struct {
+	Any     bool       `json:"-"`
+	AWS     StringList `json:"AWS,omitempty"`
+	Service StringList `json:"Service,omitempty"`
+}
+
+func (p *PolicyPrincipal) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		if single != "*" {
+			return fmt.Errorf("principal %q must be \"*\" or an object", single)
+		}
+		p.Any = true
+		return nil
+	}
+	type principal PolicyPrincipal
+	return json.Unmarshal(data, (*principal)(p))
+}
+
+func (p PolicyPrincipal) MarshalJSON() ([]byte, error) {
+	if p.Any {
+		return json.Marshal("*")
+	}
+	type principal PolicyPrincipal
+	return json.Marshal(principal(p))
+}
+
+// matches reports whether the principal names the requester. AWS principals
+// are account ids, "*" or ARNs ending with :<account>:root.
+func (p PolicyPrincipal) matches(req AccessRequest) bool {
+	if p.Any {
+		return true
+	}
+	if req.Account != "" {
+		for _, value := range p.AWS {
+			if value == "*" || value == req.Account || strings.HasSuffix(value, ":"+req.Account+":root") {
+				return true
+			}
+		}
+	}
+	if req.Service != "" {
+		for _, value := range p.Service {
+			if value == req.Service {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type PolicyStatement struct {
+	Sid       string                           `json:"Sid,omitempty"`
+	Effect    string                           `json:"Effect"`
+	Principal *PolicyPrincipal                 `json:"Principal"`
+	Action    StringList                       `json:"Action"`
+	Resource  StringList                       `json:"Resource,omitempty"`
+	Condition map[string]map[string]StringList `json:"Condition,omitempty"`
+}
+
+// Policy is the value of the Policy queue attribute.
+type Policy struct {
+	Version   string            `json:"Version,omitempty"`
+	Id        string            `json:"Id,omitempty"`
+	Statement []PolicyStatement `json:"Statement"`
+}
+
+// AccessRequest describes a request on a queue for policy evaluation.
+type AccessRequest struct {
+	Account    string            // account of the signer, empty for service principals
+	Service    string            // service principal, e.g. sns.amazonaws.com
+	Action     string            // SQS action name without prefix, e.g. SendMessage
+	Resource   string            // queue SRN
+	Conditions map[string]string // condition keys such as aws:SourceIp
+}
+
+// PolicyDecision is the outcome of evaluating a policy.
+type PolicyDecision int
+
+const (
+	PolicyNoMatch PolicyDecision = iota // no statement applies, access is denied by default
+	PolicyAllow
+	PolicyDeny
+)
+
+// conditionOperators maps the supported condition operators to their test of
+// a request value against one policy value.
+var conditionOperators = map[string]func(value, pattern string) bool{
+	"StringEquals":    func(value, pattern string) bool { return value == pattern },
+	"StringNotEquals": func(value, pattern string) bool { return value != pattern },
+	"StringLike":      matchWildcard,
+	"StringNotLike":   func(value, pattern string) bool { return !matchWildcard(value, pattern) },
+	"ArnEquals":       func(value, pattern string) bool { return value == pattern },
+	"ArnNotEquals":    func(value, pattern string) bool { return value != pattern },
+	"ArnLike":         matchWildcard,
+	"ArnNotLike":      func(value, pattern string) bool { return !matchWildcard(value, pattern) },
+	"IpAddress":       matchIp,
+	"NotIpAddress":    func(value, pattern string) bool { return !matchIp(value, pattern) },
+}
+
+// negatedOperator reports whether the operator matches requests lacking the condition key.
+func negatedOperator(operator string) bool {
+	return strings.Contains(operator, "Not")
+}
+
+// Validate checks the statements of a policy parsed from JSON.
+func (p Policy) Validate() error {
+	for i, statement := range p.Statement {
+		if statement.Effect != EffectAllow && statement.Effect != EffectDeny {
+			return fmt.Errorf("statement %d: Effect must be %s or %s", i, EffectAllow, EffectDeny)
+		}
+		if statement.Principal == nil {
+			return fmt.Errorf("statement %d: Principal is required", i)
+		}
+		if len(statement.Action) == 0 {
+			return fmt.Errorf("statement %d: Action is required", i)
+		}
+		for operator := range statement.Condition {
+			if _, ok := conditionOperators[operator]; !ok {
+				return fmt.Errorf("statement %d: unsupported condition operator %s", i, operator)
+			}
+		}
+	}
+	return nil
+}
+
+// Evaluate returns PolicyDeny if a matching statement denies the request,
+// otherwise PolicyAllow if one allows it.
+func (p Policy) Evaluate(req AccessRequest) PolicyDecision {
+	decision := PolicyNoMatch
+	for _, statement := range p.Statement {
+		if !statement.matches(req) {
+			continue
+		}
+		if statement.Effect == EffectDeny {
+			return PolicyDeny
+		}
+		decision = PolicyAllow
+	}
+	return decision
+}
+
+func (s PolicyStatement) matches(req AccessRequest) bool {
+	if s.Principal == nil || !s.Principal.matches(req) {
+		return false
+	}
+	if !matchAny(SqsActionPrefix+req.Action, s.Action, true) {
+		return false
+	}
+	if len(s.Resource) > 0 && !matchAny(req.Resource, s.Resource, false) {
+		return false
+	}
+	for operator, keys := range s.Condition {
+		test := conditionOperators[operator]
+		for key, patterns := range keys {
+			value, ok := req.Conditions[key]
+			if !ok {
+				if negatedOperator(operator) {
+					continue
+				}
+				return false
+			}
+			if !matchCondition(test, value, patterns, negatedOperator(operator)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchCondition needs one pattern to match, or every pattern for a negated operator.
+func matchCondition(test func(value, pattern string) bool, value string, patterns []string, negated bool) bool {
+	for _, pattern := range patterns {
+		if test(value, pattern) != negated {
+			return !negated
+		}
+	}
+	return negated
+}
+
+func matchAny(value string, patterns []string, ignoreCase bool) bool {
+	for _, pattern := range patterns {
+		if ignoreCase {
+			if matchWildcard(strings.ToLower(value), strings.ToLower(pattern)) {
+				return true
+			}
+		} else if matchWildcard(value, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWildcard matches value against a pattern where * matches any sequence
+// of characters and ? any single character.
+func matchWildcard(value, pattern string) bool {
+	v, p := 0, 0
+	star, mark := -1, 0
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == value[v]):
+			v++
+			p++
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, v
+			p++
+		case star >= 0:
+			mark++
+			v, p = mark, star+1
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchIp reports whether the IP address value lies in the CIDR or equals the address pattern.
+func matchIp(value, pattern string) bool {
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return false
+	}
+	if prefix, err := netip.ParsePrefix(pattern); err == nil {
+		return prefix.Contains(addr.Unmap())
+	}
+	other, err := netip.ParseAddr(pattern)
+	return err == nil && other == addr
+}
@@ -1,5 +1,239 @@
 package entity
 
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
 type Queue struct {
 	QueueSrn string `json:"QueueSrn"`
+	QueueUrl string `json:"QueueUrl"`
+}
+
+// ListQueuesOptions narrows the queues returned by ListQueues.
+type ListQueuesOptions struct {
+	QueueNamePrefix string
+	MaxResults      int    // page size, 0 returns up to MaxListQueuesResults without a NextToken
+	NextToken       string // from the previous page
+	TagKey          string // only queues carrying this tag
+	TagValue        string // with this value, when set
+}
+
+// ListQueuesResult is one page of queue URLs.
+type ListQueuesResult struct {
+	QueueUrls []string `json:"QueueUrls"`
+	NextToken string   `json:"NextToken,omitempty"`
+}
+
+// MaxListQueuesResults is the SQS upper bound of MaxResults.
+const MaxListQueuesResults = 1000
+
+// Queue attribute names.
+const (
+	AttrVisibilityTimeout             = "VisibilityTimeout"
+	AttrReceiveMessageWaitTimeSeconds = "ReceiveMessageWaitTimeSeconds"
+	AttrRedrivePolicy                 = "RedrivePolicy"
+	AttrRedriveAllowPolicy            = "RedriveAllowPolicy"
+	AttrFifoQueue                     = "FifoQueue"
+	AttrContentBasedDeduplication     = "ContentBasedDeduplication"
+	AttrDelaySeconds                  = "DelaySeconds"
+	AttrMessageRetentionPeriod        = "MessageRetentionPeriod"
+	AttrMaximumMessageSize            = "MaximumMessageSize"
+	AttrPolicy                        = "Policy"
+)
+
+// Read-only queue attributes computed from the stream and consumer state.
+const (
+	AttrApproximateNumberOfMessages           = "ApproximateNumberOfMessages"
+	AttrApproximateNumberOfMessagesNotVisible = "ApproximateNumberOfMessagesNotVisible"
+	AttrApproximateNumberOfMessagesDelayed    = "ApproximateNumberOfMessagesDelayed"
+	AttrCreatedTimestamp                      = "CreatedTimestamp"
+	AttrLastModifiedTimestamp                 = "LastModifiedTimestamp"
+	AttrQueueArn                              = "QueueArn"
+)
+
+// AttrAll requests every queue attribute.
+const AttrAll = "All"
+
+// DefaultVisibilityTimeout applies when a queue has no VisibilityTimeout attribute.
+const DefaultVisibilityTimeout = 30 * time.Second
+
+// MaxVisibilityTimeout is the SQS upper bound (12 hours) in seconds.
+const MaxVisibilityTimeout = 43200
+
+// MaxWaitTimeSeconds is the SQS upper bound for long polling.
+const MaxWaitTimeSeconds = 20
+
+// MaxDelaySeconds is the SQS upper bound (15 minutes) for message delay.
+const MaxDelaySeconds = 900
+
+// MessageRetentionPeriod bounds in seconds (1 minute to 14 days).
+const (
+	MinMessageRetentionPeriod = 60
+	MaxMessageRetentionPeriod = 1209600
+)
+
+// MinMaximumMessageSize is the lower bound of the MaximumMessageSize attribute.
+const MinMaximumMessageSize = 1024
+
+// queueMetadataPrefix namespaces queue attributes inside StreamConfig.Metadata.
+const queueMetadataPrefix = "sqs."
+
+// QueueMetadataKey returns the StreamConfig.Metadata key that stores a queue attribute.
+func QueueMetadataKey(attr string) string {
+	return queueMetadataPrefix + attr
+}
+
+// QueueAttributes extracts the queue attributes stored in StreamConfig.Metadata.
+func QueueAttributes(meta map[string]string) map[string]string {
+	attrs := make(map[string]string, len(meta))
+	for key, value := range meta {
+		if name, ok := strings.CutPrefix(key, queueMetadataPrefix); ok {
+			attrs[name] = value
+		}
+	}
+	return attrs
+}
+
+// queueTagPrefix namespaces queue tags inside StreamConfig.Metadata.
+const queueTagPrefix = "tag."
+
+// QueueTagKey returns the StreamConfig.Metadata key that stores a queue tag.
+func QueueTagKey(key string) string {
+	return queueTagPrefix + key
+}
+
+// IsQueueTagKey reports whether a StreamConfig.Metadata key stores a queue tag.
+func IsQueueTagKey(key string) bool {
+	return strings.HasPrefix(key, queueTagPrefix)
+}
+
+// MaxQueueTags is the number of tags a queue can carry.
+const MaxQueueTags = 50
+
+// QueueTags extracts the queue tags stored in StreamConfig.Metadata.
+func QueueTags(meta map[string]string) map[string]string {
+	tags := make(map[string]string)
+	for key, value := range meta {
+		if name, ok := strings.CutPrefix(key, queueTagPrefix); ok {
+			tags[name] = value
+		}
+	}
+	return tags
+}
+
+// FifoQueueSuffix marks the name of a FIFO queue.
+const FifoQueueSuffix = ".fifo"
+
+// MaxQueueNameLength is the SQS limit of a queue name, .fifo suffix included.
+const MaxQueueNameLength = 80
+
+// maxAccountIdLength bounds the account id, which is part of every stream name.
+const maxAccountIdLength = 64
+
+// maxStreamNameLength is the longest stream name JetStream accepts.
+const maxStreamNameLength = 255
+
+// namePattern is the character set of queue names (without .fifo) and account
+// ids. None of them is special in stream names or subject tokens.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidateQueueName checks the SQS naming rules: 1 to 80 alphanumerics,
+// hyphens and underscores, optionally followed by .fifo.
+func ValidateQueueName(name string) error {
+	base := strings.TrimSuffix(name, FifoQueueSuffix)
+	if len(name) > MaxQueueNameLength || !namePattern.MatchString(base) {
+		return fmt.Errorf("%w: queue name %q must be 1 to %d alphanumeric characters, hyphens or underscores, optionally ending with %s",
+			ErrInvalidParameter, name, MaxQueueNameLength, FifoQueueSuffix)
+	}
+	return nil
+}
+
+// ValidateAccountId checks that an account id can be used in stream names and subjects.
+func ValidateAccountId(account string) error {
+	if len(account) > maxAccountIdLength || !namePattern.MatchString(account) {
+		return fmt.Errorf("%w: account id %q must be 1 to %d alphanumeric characters, hyphens or underscores",
+			ErrInvalidParameter, account, maxAccountIdLength)
+	}
+	return nil
+}
+
+// fifoStreamSuffix replaces FifoQueueSuffix in stream names and subjects, where
+// '.' is not allowed or separates tokens. '~' is not allowed in queue names, so
+// the mapping is reversible.
+const fifoStreamSuffix = "~fifo"
+
+// IsFifoQueue reports whether the queue name denotes a FIFO queue.
+func IsFifoQueue(name string) bool {
+	return strings.HasSuffix(name, FifoQueueSuffix)
+}
+
+// streamAccountSeparator joins the owner account and the queue name in a
+// stream name, so queues of different accounts never share a stream.
+const streamAccountSeparator = "~"
+
+// Stream metadata keys recording the owner of a queue.
+const (
+	MetadataOwnerAccount = "owner.account"
+	MetadataOwnerRegion  = "owner.region"
+)
+
+// OwnerMetadata returns the StreamConfig.Metadata entries of the queue owner.
+func OwnerMetadata(account, region string) map[string]string {
+	return map[string]string{MetadataOwnerAccount: account, MetadataOwnerRegion: region}
+}
+
+// IsOwnedBy reports whether the stream metadata names account and region as the queue owner.
+func IsOwnedBy(meta map[string]string, account, region string) bool {
+	return meta[MetadataOwnerAccount] == account && meta[MetadataOwnerRegion] == region
+}
+
+// queueToken maps a valid queue name to a single token usable in both stream
+// names and subjects.
+func queueToken(queue string) string {
+	if name, ok := strings.CutSuffix(queue, FifoQueueSuffix); ok {
+		return name + fifoStreamSuffix
+	}
+	return queue
+}
+
+// StreamName returns the JetStream stream backing a queue of account:
+// <account>~<queue>, with .fifo written as ~fifo.
+func StreamName(account, queue string) string {
+	return account + streamAccountSeparator + queueToken(queue)
+}
+
+// QueueName is the inverse of StreamName.
+func QueueName(stream string) (account, queue string) {
+	account, queue, _ = strings.Cut(stream, streamAccountSeparator)
+	if name, ok := strings.CutSuffix(queue, fifoStreamSuffix); ok {
+		queue = name + FifoQueueSuffix
+	}
+	return account, queue
+}
+
+// AccountSubjects is the subject filter matching every queue stream of account.
+func AccountSubjects(account string) string {
+	return account + ".>"
+}
+
+// StreamSubject returns the subject filter of the stream backing a queue:
+// <account>.<queue token>. FIFO queues publish every message group to its own subject.
+func StreamSubject(account, queue string) string {
+	if IsFifoQueue(queue) {
+		return account + "." + queueToken(queue) + ".*"
+	}
+	return account + "." + queueToken(queue)
+}
+
+// QueueSubject returns the subject a message is published to. The message
+// group is base64url encoded as it may contain characters not allowed in subjects.
+func QueueSubject(account, queue, messageGroupId string) string {
+	if !IsFifoQueue(queue) || messageGroupId == "" {
+		return account + "." + queueToken(queue)
+	}
+	return account + "." + queueToken(queue) + "." + base64.RawURLEncoding.EncodeToString([]byte(messageGroupId))
 }
@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// HeaderDeadLetterSource records the source queue of a message moved to a dead-letter queue.
+const HeaderDeadLetterSource = "Sqs-Dead-Letter-Source"
+
+// RedrivePolicy is the value of the RedrivePolicy queue attribute.
+type RedrivePolicy struct {
+	DeadLetterTargetArn string `json:"deadLetterTargetArn"`
+	MaxReceiveCount     int    `json:"maxReceiveCount"`
+}
+
+// UnmarshalJSON accepts maxReceiveCount as a number or a string, as SQS does.
+func (p *RedrivePolicy) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		DeadLetterTargetArn string          `json:"deadLetterTargetArn"`
+		MaxReceiveCount     json.RawMessage `json:"maxReceiveCount"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	count := string(raw.MaxReceiveCount)
+	if unquoted, err := strconv.Unquote(count); err == nil {
+		count = unquoted
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return err
+	}
+
+	p.DeadLetterTargetArn = raw.DeadLetterTargetArn
+	p.MaxReceiveCount = n
+	return nil
+}
+
+// Redrive permissions of the RedriveAllowPolicy queue attribute.
+const (
+	RedrivePermissionAllowAll = "allowAll"
+	RedrivePermissionDenyAll  = "denyAll"
+	RedrivePermissionByQueue  = "byQueue"
+)
+
+// RedriveAllowPolicy is the value of the RedriveAllowPolicy attribute on a dead-letter queue.
+type RedriveAllowPolicy struct {
+	RedrivePermission string   `json:"redrivePermission"`
+	SourceQueueArns   []string `json:"sourceQueueArns,omitempty"`
+}
+
+// Allows reports whether the source queue may use this queue as its dead-letter queue.
+func (p RedriveAllowPolicy) Allows(sourceArn string) bool {
+	switch p.RedrivePermission {
+	case RedrivePermissionAllowAll, "":
+		return true
+	case RedrivePermissionByQueue:
+		for _, arn := range p.SourceQueueArns {
+			if arn == sourceArn {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxTopicNameLength bounds a topic name so that its stream name,
+// ~topic~<account>~<topic>, fits the JetStream limit with the longest account
+// id. SNS itself allows 256 characters.
+const MaxTopicNameLength = maxStreamNameLength - len(topicStreamPrefix) - 2*len(streamAccountSeparator) - maxAccountIdLength
+
+// ProtocolSqs delivers the notifications of a subscription to a queue.
+const ProtocolSqs = "sqs"
+
+// SnsServicePrincipal is the principal a queue Policy names to accept
+// notifications of topics owned by other accounts.
+const SnsServicePrincipal = "sns.amazonaws.com"
+
+// topicStreamPrefix starts the stream name and subjects of every topic. No
+// account id contains '~', so topic streams never capture queue subjects.
+const topicStreamPrefix = "~topic"
+
+// Consumer metadata keys describing a subscription.
+const (
+	MetadataSubscriptionProtocol = "sub.protocol"
+	MetadataSubscriptionEndpoint = "sub.endpoint"
+	MetadataSubscriptionOwner    = "sub.owner"
+)
+
+// Headers of a message published to a topic. Message attributes use
+// HeaderMessageAttributePrefix like queue messages.
+const (
+	HeaderNotificationId        = "Sns-Message-Id"
+	HeaderNotificationSubject   = "Sns-Subject"
+	HeaderNotificationTimestamp = "Sns-Timestamp"
+)
+
+// NotificationTimestampFormat is the format of the Timestamp of an SNS notification.
+const NotificationTimestampFormat = "2006-01-02T15:04:05.000Z"
+
+type Topic struct {
+	TopicArn string `json:"TopicArn"`
+}
+
+type ListTopicsResult struct {
+	Topics    []Topic `json:"Topics"`
+	NextToken string  `json:"NextToken,omitempty"`
+}
+
+// Subscription delivers the messages published to a topic to an endpoint.
+type Subscription struct {
+	SubscriptionArn string `json:"SubscriptionArn"`
+	Owner           string `json:"Owner"`
+	Protocol        string `json:"Protocol"`
+	Endpoint        string `json:"Endpoint"`
+	TopicArn        string `json:"TopicArn"`
+}
+
+type ListSubscriptionsResult struct {
+	Subscriptions []Subscription `json:"Subscriptions"`
+	NextToken     string         `json:"NextToken,omitempty"`
+}
+
+// PublishOptions are the optional parts of a Publish request.
+type PublishOptions struct {
+	Subject           string
+	MessageAttributes map[string]MessageAttributeValue
+}
+
+// Notification is the SNS JSON envelope a queue receives as message body.
+// Notifications are not signed.
+type Notification struct {
+	Type              string                           `json:"Type"`
+	MessageId         string                           `json:"MessageId"`
+	TopicArn          string                           `json:"TopicArn"`
+	Subject           string                           `json:"Subject,omitempty"`
+	Message           string                           `json:"Message"`
+	Timestamp         string                           `json:"Timestamp"`
+	UnsubscribeURL    string                           `json:"UnsubscribeURL"`
+	MessageAttributes map[string]NotificationAttribute `json:"MessageAttributes,omitempty"`
+}
+
+// NotificationTypeNotification is the Type of a published message.
+const NotificationTypeNotification = "Notification"
+
+// NotificationAttribute is a message attribute in the envelope. Binary values are base64 encoded.
+type NotificationAttribute struct {
+	Type  string `json:"Type"`
+	Value string `json:"Value"`
+}
+
+// ValidateTopicName checks the SNS naming rules: 1 to MaxTopicNameLength
+// alphanumerics, hyphens and underscores. FIFO topics are not supported.
+func ValidateTopicName(name string) error {
+	if len(name) > MaxTopicNameLength || !namePattern.MatchString(name) {
+		return fmt.Errorf("%w: topic name %q must be 1 to %d alphanumeric characters, hyphens or underscores",
+			ErrInvalidParameter, name, MaxTopicNameLength)
+	}
+	return nil
+}
+
+// TopicStreamName returns the JetStream stream backing a topic of account: ~topic~<account>~<topic>.
+func TopicStreamName(account, topic string) string {
+	return topicStreamPrefix + streamAccountSeparator + account + streamAccountSeparator + topic
+}
+
+// TopicName is the inverse of TopicStreamName.
+func TopicName(stream string) (account, topic string) {
+	rest := strings.TrimPrefix(stream, topicStreamPrefix+streamAccountSeparator)
+	account, topic, _ = strings.Cut(rest, streamAccountSeparator)
+	return account, topic
+}
+
+// TopicSubject returns the subject messages are published to: ~topic.<account>.<topic>.
+func TopicSubject(account, topic string) string {
+	return topicStreamPrefix + "." + account + "." + topic
+}
+
+// AccountTopicSubjects is the subject filter matching every topic stream of account.
+func AccountTopicSubjects(account string) string {
+	return topicStreamPrefix + "." + account + ".*"
+}
+
+// AllTopicSubjects is the subject filter matching the topic streams of every account.
+const AllTopicSubjects = topicStreamPrefix + ".>"
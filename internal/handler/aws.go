@@ -0,0 +1,382 @@
+package handler
+
+import (
+	"fmt"
+	"nats/internal/entity"
+	imiddle "nats/internal/middleware"
+	"nats/internal/service"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AwsOperation is an AWS SQS API operation, independent of the protocol that
+// carries it. NewInput returns a pointer to the request shape to decode into.
+type AwsOperation struct {
+	NewInput func() any
+	Run      func(c echo.Context, input any) (any, error)
+}
+
+func awsOperation[In, Out any](run func(c echo.Context, in *In) (Out, error)) AwsOperation {
+	return AwsOperation{
+		NewInput: func() any { return new(In) },
+		Run: func(c echo.Context, input any) (any, error) {
+			return run(c, input.(*In))
+		},
+	}
+}
+
+// AwsHandler serves the AWS SQS operations on top of the same services as the ?Action= API.
+type AwsHandler struct {
+	queueSvc       service.QueueService
+	messageSvc     service.MessageService
+	moveTaskSvc    service.MoveTaskService
+	defaultAccount string
+}
+
+func NewAwsHandler(queueSvc service.QueueService, messageSvc service.MessageService, moveTaskSvc service.MoveTaskService, defaultAccount string) *AwsHandler {
+	return &AwsHandler{queueSvc: queueSvc, messageSvc: messageSvc, moveTaskSvc: moveTaskSvc, defaultAccount: defaultAccount}
+}
+
+// AwsQueueRequest addresses a queue by the QueueUrl returned by CreateQueue or GetQueueUrl.
+type AwsQueueRequest struct {
+	QueueUrl string `json:"QueueUrl" validate:"required"`
+}
+
+type AwsCreateQueueRequest struct {
+	QueueName  string            `json:"QueueName" validate:"required"`
+	Attributes map[string]string `json:"Attributes"`
+	Tags       map[string]string `json:"tags"`
+}
+
+type AwsQueueUrlResult struct {
+	QueueUrl string `json:"QueueUrl"`
+}
+
+type AwsListQueuesRequest struct {
+	QueueNamePrefix string `json:"QueueNamePrefix"`
+	MaxResults      int    `json:"MaxResults" validate:"omitempty,min=1,max=1000"`
+	NextToken       string `json:"NextToken"`
+}
+
+type AwsGetQueueAttributesRequest struct {
+	AwsQueueRequest
+	AttributeNames []string `json:"AttributeNames"`
+}
+
+type AwsSetQueueAttributesRequest struct {
+	AwsQueueRequest
+	Attributes map[string]string `json:"Attributes"`
+}
+
+type AwsTagQueueRequest struct {
+	AwsQueueRequest
+	Tags map[string]string `json:"Tags"`
+}
+
+// AwsAddPermissionRequest lists accounts and actions as AWSAccountId.N and
+// ActionName.N in the Query protocol.
+type AwsAddPermissionRequest struct {
+	AwsQueueRequest
+	Label         string   `json:"Label" validate:"required"`
+	AWSAccountIds []string `json:"AWSAccountIds" xml:"AWSAccountId"`
+	Actions       []string `json:"Actions" xml:"ActionName"`
+}
+
+type AwsRemovePermissionRequest struct {
+	AwsQueueRequest
+	Label string `json:"Label" validate:"required"`
+}
+
+type AwsUntagQueueRequest struct {
+	AwsQueueRequest
+	TagKeys []string `json:"TagKeys"`
+}
+
+type AwsSendMessageRequest struct {
+	AwsQueueRequest
+	MessageBody            string `json:"MessageBody" validate:"required"`
+	DelaySeconds           *int   `json:"DelaySeconds"`
+	MessageGroupId         string `json:"MessageGroupId"`
+	MessageDeduplicationId string `json:"MessageDeduplicationId"`
+
+	MessageAttributes       map[string]entity.MessageAttributeValue `json:"MessageAttributes"`
+	MessageSystemAttributes map[string]entity.MessageAttributeValue `json:"MessageSystemAttributes"`
+}
+
+type AwsSendMessageBatchRequest struct {
+	AwsQueueRequest
+	Entries []entity.SendMessageBatchRequestEntry `json:"Entries" validate:"dive"`
+}
+
+type AwsReceiveMessageRequest struct {
+	AwsQueueRequest
+	MaxNumberOfMessages int  `json:"MaxNumberOfMessages" validate:"omitempty,min=1,max=10"`
+	VisibilityTimeout   *int `json:"VisibilityTimeout" validate:"omitempty,min=0,max=43200"`
+	WaitTimeSeconds     *int `json:"WaitTimeSeconds" validate:"omitempty,min=0,max=20"`
+
+	AttributeNames              []string `json:"AttributeNames"`
+	MessageSystemAttributeNames []string `json:"MessageSystemAttributeNames"`
+	MessageAttributeNames       []string `json:"MessageAttributeNames"`
+}
+
+type AwsDeleteMessageRequest struct {
+	AwsQueueRequest
+	ReceiptHandle string `json:"ReceiptHandle" validate:"required"`
+}
+
+type AwsDeleteMessageBatchRequest struct {
+	AwsQueueRequest
+	Entries []entity.DeleteMessageBatchRequestEntry `json:"Entries" validate:"dive"`
+}
+
+type AwsChangeMessageVisibilityRequest struct {
+	AwsQueueRequest
+	ReceiptHandle     string `json:"ReceiptHandle" validate:"required"`
+	VisibilityTimeout *int   `json:"VisibilityTimeout" validate:"required,min=0,max=43200"`
+}
+
+type AwsChangeMessageVisibilityBatchRequest struct {
+	AwsQueueRequest
+	Entries []entity.ChangeMessageVisibilityBatchRequestEntry `json:"Entries" validate:"dive"`
+}
+
+// AwsEmptyResult is the result of the operations that only report success.
+type AwsEmptyResult struct{}
+
+// account returns the account the request acts as when it names no queue:
+// the signer's account, the :accountid of a Query request sent to /<account>,
+// otherwise the default account.
+func (h *AwsHandler) account(c echo.Context) string {
+	if account, ok := imiddle.SignerAccount(c); ok {
+		return account
+	}
+	if account := c.Param("accountid"); account != "" {
+		return account
+	}
+	return h.defaultAccount
+}
+
+// parseQueueUrl returns the account and queue name of a queue URL: the last
+// two path segments, as built by the queue service.
+func parseQueueUrl(queueUrl string) (string, string, error) {
+	u, err := url.Parse(queueUrl)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: QueueUrl %q is not a valid URL", entity.ErrInvalidParameter, queueUrl)
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 {
+		return "", "", fmt.Errorf("%w: QueueUrl %q does not name a queue", entity.ErrInvalidParameter, queueUrl)
+	}
+	account, name := segments[len(segments)-2], segments[len(segments)-1]
+	if err := entity.ValidateAccountId(account); err != nil {
+		return "", "", err
+	}
+	if err := entity.ValidateQueueName(name); err != nil {
+		return "", "", err
+	}
+	return account, name, nil
+}
+
+// queueOf resolves the QueueUrl of a request. A request signed by another
+// account than the queue owner needs the queue Policy to allow action.
+func (h *AwsHandler) queueOf(c echo.Context, queueUrl, action string) (string, string, error) {
+	account, name, err := parseQueueUrl(queueUrl)
+	if err != nil {
+		return "", "", err
+	}
+	if signer, ok := imiddle.SignerAccount(c); ok {
+		req := accessRequest(c, signer, action)
+		if err := h.queueSvc.AuthorizeQueueAction(c.Request().Context(), name, account, req); err != nil {
+			return "", "", err
+		}
+	}
+	return account, name, nil
+}
+
+func (h *AwsHandler) CreateQueue(c echo.Context, req *AwsCreateQueueRequest) (AwsQueueUrlResult, error) {
+	queue, err := h.queueSvc.CreateQueue(c.Request().Context(), req.QueueName, h.account(c), req.Attributes, req.Tags)
+	return AwsQueueUrlResult{QueueUrl: queue.QueueUrl}, err
+}
+
+func (h *AwsHandler) GetQueueUrl(c echo.Context, req *GetQueueUrlRequest) (AwsQueueUrlResult, error) {
+	account := h.account(c)
+	owner := account
+	if req.QueueOwnerAWSAccountId != "" {
+		owner = req.QueueOwnerAWSAccountId
+	}
+	queue, err := h.queueSvc.GetQueueUrl(c.Request().Context(), req.QueueName, owner, account)
+	return AwsQueueUrlResult{QueueUrl: queue.QueueUrl}, err
+}
+
+func (h *AwsHandler) ListQueues(c echo.Context, req *AwsListQueuesRequest) (entity.ListQueuesResult, error) {
+	opts := entity.ListQueuesOptions{
+		QueueNamePrefix: req.QueueNamePrefix,
+		MaxResults:      req.MaxResults,
+		NextToken:       req.NextToken,
+	}
+	return h.queueSvc.ListQueues(c.Request().Context(), h.account(c), opts)
+}
+
+func (h *AwsHandler) DeleteQueue(c echo.Context, req *AwsQueueRequest) (AwsEmptyResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "DeleteQueue")
+	if err != nil {
+		return AwsEmptyResult{}, err
+	}
+	return AwsEmptyResult{}, h.queueSvc.DeleteQueue(c.Request().Context(), name, account)
+}
+
+func (h *AwsHandler) PurgeQueue(c echo.Context, req *AwsQueueRequest) (AwsEmptyResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "PurgeQueue")
+	if err != nil {
+		return AwsEmptyResult{}, err
+	}
+	return AwsEmptyResult{}, h.queueSvc.PurgeQueue(c.Request().Context(), name, account)
+}
+
+func (h *AwsHandler) GetQueueAttributes(c echo.Context, req *AwsGetQueueAttributesRequest) (GetQueueAttributesResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "GetQueueAttributes")
+	if err != nil {
+		return GetQueueAttributesResult{}, err
+	}
+	attrs, err := h.queueSvc.GetQueueAttributes(c.Request().Context(), name, account, req.AttributeNames)
+	return GetQueueAttributesResult{Attributes: attrs}, err
+}
+
+func (h *AwsHandler) SetQueueAttributes(c echo.Context, req *AwsSetQueueAttributesRequest) (AwsEmptyResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "SetQueueAttributes")
+	if err != nil {
+		return AwsEmptyResult{}, err
+	}
+	return AwsEmptyResult{}, h.queueSvc.SetQueueAttributes(c.Request().Context(), name, account, req.Attributes)
+}
+
+func (h *AwsHandler) AddPermission(c echo.Context, req *AwsAddPermissionRequest) (AwsEmptyResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "AddPermission")
+	if err != nil {
+		return AwsEmptyResult{}, err
+	}
+	return AwsEmptyResult{}, h.queueSvc.AddPermission(c.Request().Context(), name, account, req.Label, req.AWSAccountIds, req.Actions)
+}
+
+func (h *AwsHandler) RemovePermission(c echo.Context, req *AwsRemovePermissionRequest) (AwsEmptyResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "RemovePermission")
+	if err != nil {
+		return AwsEmptyResult{}, err
+	}
+	return AwsEmptyResult{}, h.queueSvc.RemovePermission(c.Request().Context(), name, account, req.Label)
+}
+
+func (h *AwsHandler) TagQueue(c echo.Context, req *AwsTagQueueRequest) (AwsEmptyResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "TagQueue")
+	if err != nil {
+		return AwsEmptyResult{}, err
+	}
+	return AwsEmptyResult{}, h.queueSvc.TagQueue(c.Request().Context(), name, account, req.Tags)
+}
+
+func (h *AwsHandler) UntagQueue(c echo.Context, req *AwsUntagQueueRequest) (AwsEmptyResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "UntagQueue")
+	if err != nil {
+		return AwsEmptyResult{}, err
+	}
+	return AwsEmptyResult{}, h.queueSvc.UntagQueue(c.Request().Context(), name, account, req.TagKeys)
+}
+
+func (h *AwsHandler) ListQueueTags(c echo.Context, req *AwsQueueRequest) (ListQueueTagsResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "ListQueueTags")
+	if err != nil {
+		return ListQueueTagsResult{}, err
+	}
+	tags, err := h.queueSvc.ListQueueTags(c.Request().Context(), name, account)
+	return ListQueueTagsResult{Tags: tags}, err
+}
+
+func (h *AwsHandler) SendMessage(c echo.Context, req *AwsSendMessageRequest) (entity.SendMessageResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "SendMessage")
+	if err != nil {
+		return entity.SendMessageResult{}, err
+	}
+	opts := entity.SendOptions{
+		MessageGroupId:         req.MessageGroupId,
+		MessageDeduplicationId: req.MessageDeduplicationId,
+		DelaySeconds:           req.DelaySeconds,
+
+		MessageAttributes:       req.MessageAttributes,
+		MessageSystemAttributes: req.MessageSystemAttributes,
+	}
+	return h.messageSvc.SendMessage(c.Request().Context(), name, account, req.MessageBody, "", opts)
+}
+
+func (h *AwsHandler) SendMessageBatch(c echo.Context, req *AwsSendMessageBatchRequest) (entity.SendMessageBatchResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "SendMessage")
+	if err != nil {
+		return entity.SendMessageBatchResult{}, err
+	}
+	return h.messageSvc.SendMessageBatch(c.Request().Context(), name, account, req.Entries)
+}
+
+func (h *AwsHandler) ReceiveMessage(c echo.Context, req *AwsReceiveMessageRequest) (ReceiveMessageResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "ReceiveMessage")
+	if err != nil {
+		return ReceiveMessageResult{}, err
+	}
+	opts := entity.ReceiveOptions{
+		MaxNumberOfMessages: req.MaxNumberOfMessages,
+		VisibilityTimeout:   req.VisibilityTimeout,
+		WaitTimeSeconds:     req.WaitTimeSeconds,
+
+		AttributeNames:        append(req.AttributeNames, req.MessageSystemAttributeNames...),
+		MessageAttributeNames: req.MessageAttributeNames,
+	}
+	messages, err := h.messageSvc.ReceiveMessage(c.Request().Context(), name, account, opts)
+	return ReceiveMessageResult{Messages: messages}, err
+}
+
+func (h *AwsHandler) DeleteMessage(c echo.Context, req *AwsDeleteMessageRequest) (AwsEmptyResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "DeleteMessage")
+	if err != nil {
+		return AwsEmptyResult{}, err
+	}
+	return AwsEmptyResult{}, h.messageSvc.DeleteMessage(c.Request().Context(), name, account, req.ReceiptHandle)
+}
+
+func (h *AwsHandler) DeleteMessageBatch(c echo.Context, req *AwsDeleteMessageBatchRequest) (entity.DeleteMessageBatchResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "DeleteMessage")
+	if err != nil {
+		return entity.DeleteMessageBatchResult{}, err
+	}
+	return h.messageSvc.DeleteMessageBatch(c.Request().Context(), name, account, req.Entries)
+}
+
+func (h *AwsHandler) ChangeMessageVisibility(c echo.Context, req *AwsChangeMessageVisibilityRequest) (AwsEmptyResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "ChangeMessageVisibility")
+	if err != nil {
+		return AwsEmptyResult{}, err
+	}
+	return AwsEmptyResult{}, h.messageSvc.ChangeMessageVisibility(c.Request().Context(), name, account, req.ReceiptHandle, *req.VisibilityTimeout)
+}
+
+func (h *AwsHandler) ChangeMessageVisibilityBatch(c echo.Context, req *AwsChangeMessageVisibilityBatchRequest) (entity.ChangeMessageVisibilityBatchResult, error) {
+	account, name, err := h.queueOf(c, req.QueueUrl, "ChangeMessageVisibility")
+	if err != nil {
+		return entity.ChangeMessageVisibilityBatchResult{}, err
+	}
+	return h.messageSvc.ChangeMessageVisibilityBatch(c.Request().Context(), name, account, req.Entries)
+}
+
+func (h *AwsHandler) StartMessageMoveTask(c echo.Context, req *StartMessageMoveTaskRequest) (StartMessageMoveTaskResult, error) {
+	taskHandle, err := h.moveTaskSvc.StartMessageMoveTask(c.Request().Context(), h.account(c), req.SourceArn, req.DestinationArn, req.MaxNumberOfMessagesPerSecond)
+	return StartMessageMoveTaskResult{TaskHandle: taskHandle}, err
+}
+
+func (h *AwsHandler) ListMessageMoveTasks(c echo.Context, req *ListMessageMoveTasksRequest) (ListMessageMoveTasksResult, error) {
+	tasks, err := h.moveTaskSvc.ListMessageMoveTasks(c.Request().Context(), h.account(c), req.SourceArn, req.MaxResults)
+	return ListMessageMoveTasksResult{Results: tasks}, err
+}
+
+func (h *AwsHandler) CancelMessageMoveTask(c echo.Context, req *CancelMessageMoveTaskRequest) (CancelMessageMoveTaskResult, error) {
+	moved, err := h.moveTaskSvc.CancelMessageMoveTask(c.Request().Context(), h.account(c), req.TaskHandle)
+	return CancelMessageMoveTaskResult{ApproximateNumberOfMessagesMoved: moved}, err
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"nats/internal/context/logs"
+	"nats/internal/entity"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// AWS JSON 1.0 protocol, as spoken by aws-sdk-go-v2 and the AWS CLI.
+const (
+	AwsJsonContentType  = "application/x-amz-json-1.0"
+	HeaderAmzTarget     = "X-Amz-Target"
+	awsJsonTargetPrefix = "AmazonSQS."
+	awsErrorTypePrefix  = "com.amazonaws.sqs#"
+)
+
+// Response headers read by the AWS SDKs.
+const (
+	headerAmznRequestId  = "x-amzn-RequestId"
+	headerAmznQueryError = "x-amzn-query-error"
+)
+
+type awsJsonError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+// serveAwsJson decodes the JSON request of op, runs it and writes its JSON result.
+func serveAwsJson(c echo.Context, action string, op AwsOperation) error {
+	ctx := c.Request().Context()
+
+	input := op.NewInput()
+	if err := json.NewDecoder(c.Request().Body).Decode(input); err != nil && !errors.Is(err, io.EOF) {
+		logs.GetLogger(ctx).Error("Invalid AWS JSON request body", zap.String("action", action), zap.Error(err))
+		return writeAwsJsonError(c, fmt.Errorf("%w: request body is not valid JSON", entity.ErrInvalidParameter))
+	}
+
+	if err := c.Validate(input); err != nil {
+		logs.GetLogger(ctx).Error("Required parameter is missing", zap.String("action", action), zap.Error(err))
+		return writeAwsJsonError(c, fmt.Errorf("%w: %v", entity.ErrInvalidParameter, err))
+	}
+
+	output, err := op.Run(c, input)
+	if err != nil {
+		logs.GetLogger(ctx).Error("AWS JSON operation failed", zap.String("action", action), zap.Error(err))
+		return writeAwsJsonError(c, err)
+	}
+	return writeAwsJson(c, http.StatusOK, output)
+}
+
+func writeAwsJson(c echo.Context, code int, body any) error {
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set(headerAmznRequestId, c.Response().Header().Get(echo.HeaderXRequestID))
+	return c.Blob(code, AwsJsonContentType, bytes)
+}
+
+// writeAwsJsonError writes the __type error shape. x-amzn-query-error carries
+// the Query protocol code for clients in query compatible mode.
+func writeAwsJsonError(c echo.Context, err error) error {
+	awsErr, resp := entity.AwsErrorOf(err)
+	c.Response().Header().Set(headerAmznQueryError, awsErr.QueryCode+";"+resp.Error.Type)
+	return writeAwsJson(c, awsErr.HTTPCode, awsJsonError{
+		Type:    awsErrorTypePrefix + awsErr.Code,
+		Message: resp.Error.Message,
+	})
+}
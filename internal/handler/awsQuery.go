@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"nats/internal/context/logs"
+	"nats/internal/entity"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// AWS Query protocol: form encoded requests with an Action parameter and XML
+// responses, as spoken by boto2, older Java SDKs and shell scripts.
+const awsQueryNamespace = "http://queue.amazonaws.com/doc/2012-11-05/"
+
+type awsQueryError struct {
+	XMLName   xml.Name `xml:"ErrorResponse"`
+	Namespace string   `xml:"xmlns,attr"`
+	Error     struct {
+		Type    string `xml:"Type"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Error"`
+	RequestId string `xml:"RequestId"`
+}
+
+// serveAwsQuery decodes the form parameters of op, runs it and writes its XML
+// result. A request sent to a queue URL may leave QueueUrl out.
+func serveAwsQuery(c echo.Context, action string, op AwsOperation) error {
+	ctx := c.Request().Context()
+
+	form, err := c.FormParams()
+	if err != nil {
+		logs.GetLogger(ctx).Error("Invalid AWS Query request body", zap.String("action", action), zap.Error(err))
+		return writeAwsQueryError(c, fmt.Errorf("%w: request body is not form encoded", entity.ErrInvalidParameter))
+	}
+	if form.Get("QueueUrl") == "" && c.Param("queueid") != "" {
+		form.Set("QueueUrl", c.Request().URL.Path)
+	}
+
+	input := op.NewInput()
+	if err := decodeQuery(form, "", reflect.ValueOf(input).Elem()); err != nil {
+		logs.GetLogger(ctx).Error("Invalid AWS Query request parameter", zap.String("action", action), zap.Error(err))
+		return writeAwsQueryError(c, err)
+	}
+
+	if err := c.Validate(input); err != nil {
+		logs.GetLogger(ctx).Error("Required parameter is missing", zap.String("action", action), zap.Error(err))
+		return writeAwsQueryError(c, fmt.Errorf("%w: %v", entity.ErrInvalidParameter, err))
+	}
+
+	output, err := op.Run(c, input)
+	if err != nil {
+		logs.GetLogger(ctx).Error("AWS Query operation failed", zap.String("action", action), zap.Error(err))
+		return writeAwsQueryError(c, err)
+	}
+
+	body, err := encodeQueryResponse(action, output, c.Response().Header().Get(echo.HeaderXRequestID))
+	if err != nil {
+		return err
+	}
+	return c.Blob(http.StatusOK, echo.MIMETextXMLCharsetUTF8, body)
+}
+
+func writeAwsQueryError(c echo.Context, err error) error {
+	awsErr, resp := entity.AwsErrorOf(err)
+	body := awsQueryError{Namespace: awsQueryNamespace, RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+	body.Error.Type = resp.Error.Type
+	body.Error.Code = awsErr.QueryCode
+	body.Error.Message = resp.Error.Message
+	return c.XML(awsErr.HTTPCode, body)
+}
+
+// queryMember returns the Query protocol name of a field. Lists and maps are
+// flattened under a singular name (AttributeName.1, Attribute.1.Name); lists
+// of structures are named after the element type unless an xml tag says otherwise.
+func queryMember(field reflect.StructField) string {
+	if name := field.Tag.Get("xml"); name != "" {
+		return name
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		name = field.Name
+	}
+	name = strings.ToUpper(name[:1]) + name[1:]
+
+	t := field.Type
+	switch {
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct:
+		return t.Elem().Name()
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String, t.Kind() == reflect.Map:
+		return strings.TrimSuffix(name, "s")
+	}
+	return name
+}
+
+// queryMapKey is the name of the key of a flattened map entry: Tag.N.Key, otherwise Attribute.N.Name.
+func queryMapKey(member string) string {
+	if member == "Tag" {
+		return "Key"
+	}
+	return "Name"
+}
+
+func omitEmpty(field reflect.StructField) bool {
+	return strings.Contains(field.Tag.Get("json"), ",omitempty")
+}
+
+// hasQueryPrefix reports whether the form has the parameter name or any parameter below it.
+func hasQueryPrefix(form url.Values, name string) bool {
+	for key := range form {
+		if key == name || strings.HasPrefix(key, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeQuery fills the struct v from the form parameters below prefix.
+func decodeQuery(form url.Values, prefix string, v reflect.Value) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.Anonymous {
+			if err := decodeQuery(form, prefix, v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := decodeQueryValue(form, prefix+queryMember(field), v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeQueryValue(form url.Values, name string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(form.Get(name))
+
+	case reflect.Int, reflect.Int64:
+		if value := form.Get(name); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%w: %s must be an integer", entity.ErrInvalidParameter, name)
+			}
+			v.SetInt(n)
+		}
+
+	case reflect.Bool:
+		if value := form.Get(name); value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%w: %s must be true or false", entity.ErrInvalidParameter, name)
+			}
+			v.SetBool(b)
+		}
+
+	case reflect.Pointer:
+		if !hasQueryPrefix(form, name) {
+			return nil
+		}
+		elem := reflect.New(v.Type().Elem())
+		if err := decodeQueryValue(form, name, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+
+	case reflect.Struct:
+		return decodeQuery(form, name+".", v)
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if value := form.Get(name); value != "" {
+				b, err := base64.StdEncoding.DecodeString(value)
+				if err != nil {
+					return fmt.Errorf("%w: %s must be base64 encoded", entity.ErrInvalidParameter, name)
+				}
+				v.SetBytes(b)
+			}
+			return nil
+		}
+		for n := 1; hasQueryPrefix(form, name+"."+strconv.Itoa(n)); n++ {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeQueryValue(form, name+"."+strconv.Itoa(n), elem); err != nil {
+				return err
+			}
+			v.Set(reflect.Append(v, elem))
+		}
+
+	case reflect.Map:
+		member := name[strings.LastIndex(name, ".")+1:]
+		for n := 1; hasQueryPrefix(form, name+"."+strconv.Itoa(n)); n++ {
+			entry := name + "." + strconv.Itoa(n) + "."
+			key := form.Get(entry + queryMapKey(member))
+			if key == "" {
+				return fmt.Errorf("%w: %s%s is required", entity.ErrInvalidParameter, entry, queryMapKey(member))
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeQueryValue(form, entry+"Value", elem); err != nil {
+				return err
+			}
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			v.SetMapIndex(reflect.ValueOf(key), elem)
+		}
+	}
+	return nil
+}
+
+// encodeQueryResponse writes <ActionResponse><ActionResult>...</ActionResult><ResponseMetadata>.
+// Operations without output have no result element.
+func encodeQueryResponse(action string, output any, requestId string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+
+	response := xml.StartElement{
+		Name: xml.Name{Local: action + "Response"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: awsQueryNamespace}},
+	}
+	if err := enc.EncodeToken(response); err != nil {
+		return nil, err
+	}
+
+	v := reflect.ValueOf(output)
+	if v.Kind() == reflect.Struct && v.NumField() > 0 {
+		result := xml.StartElement{Name: xml.Name{Local: action + "Result"}}
+		if err := enc.EncodeToken(result); err != nil {
+			return nil, err
+		}
+		if err := encodeQueryStruct(enc, v); err != nil {
+			return nil, err
+		}
+		if err := enc.EncodeToken(result.End()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := enc.EncodeElement(entity.ResponseMetadata{RequestId: requestId}, xml.StartElement{Name: xml.Name{Local: "ResponseMetadata"}}); err != nil {
+		return nil, err
+	}
+	if err := enc.EncodeToken(response.End()); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeQueryStruct(enc *xml.Encoder, v reflect.Value) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.Anonymous {
+			if err := encodeQueryStruct(enc, v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if omitEmpty(field) && v.Field(i).IsZero() {
+			continue
+		}
+		if err := encodeQueryValue(enc, queryMember(field), v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeQueryValue(enc *xml.Encoder, name string, v reflect.Value) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return encodeQueryValue(enc, name, v.Elem())
+
+	case reflect.Struct:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		if err := encodeQueryStruct(enc, v); err != nil {
+			return err
+		}
+		return enc.EncodeToken(start.End())
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return enc.EncodeElement(base64.StdEncoding.EncodeToString(v.Bytes()), start)
+		}
+		for i := range v.Len() {
+			if err := encodeQueryValue(enc, name, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			if err := enc.EncodeToken(start); err != nil {
+				return err
+			}
+			if err := enc.EncodeElement(key, xml.StartElement{Name: xml.Name{Local: queryMapKey(name)}}); err != nil {
+				return err
+			}
+			if err := encodeQueryValue(enc, "Value", v.MapIndex(reflect.ValueOf(key))); err != nil {
+				return err
+			}
+			if err := enc.EncodeToken(start.End()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return enc.EncodeElement(v.Interface(), start)
+}
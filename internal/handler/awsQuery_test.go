@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nats/internal/entity"
+	imiddle "nats/internal/middleware"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// Form bodies as sent by the AWS CLI with the Query protocol.
+const (
+	cliSetQueueAttributes = "Action=SetQueueAttributes&Version=2012-11-05" +
+		"&QueueUrl=http%3A%2F%2Flocalhost%3A8080%2Fv1%2Faccountid%2Forders" +
+		"&Attribute.1.Name=VisibilityTimeout&Attribute.1.Value=45" +
+		"&Attribute.2.Name=RedrivePolicy" +
+		"&Attribute.2.Value=%7B%22deadLetterTargetArn%22%3A%22srn%3Ascp%3Asns%3Akr-west1%3Aaccountid%3Aorders-dlq%22%2C%22maxReceiveCount%22%3A%225%22%7D"
+
+	cliSendMessage = "Action=SendMessage&Version=2012-11-05" +
+		"&QueueUrl=http%3A%2F%2Flocalhost%3A8080%2Fv1%2Faccountid%2Forders" +
+		"&MessageBody=%7B%22id%22%3A+42%7D&DelaySeconds=5" +
+		"&MessageAttribute.1.Name=kind&MessageAttribute.1.Value.DataType=String&MessageAttribute.1.Value.StringValue=order" +
+		"&MessageAttribute.2.Name=image&MessageAttribute.2.Value.DataType=Binary&MessageAttribute.2.Value.BinaryValue=AQI%3D"
+
+	cliSendMessageBatch = "Action=SendMessageBatch&Version=2012-11-05" +
+		"&QueueUrl=http%3A%2F%2Flocalhost%3A8080%2Fv1%2Faccountid%2Forders.fifo" +
+		"&SendMessageBatchRequestEntry.1.Id=first&SendMessageBatchRequestEntry.1.MessageBody=one" +
+		"&SendMessageBatchRequestEntry.1.MessageGroupId=g1&SendMessageBatchRequestEntry.1.MessageDeduplicationId=d1" +
+		"&SendMessageBatchRequestEntry.1.MessageAttribute.1.Name=kind" +
+		"&SendMessageBatchRequestEntry.1.MessageAttribute.1.Value.DataType=Number" +
+		"&SendMessageBatchRequestEntry.1.MessageAttribute.1.Value.StringValue=7" +
+		"&SendMessageBatchRequestEntry.2.Id=second&SendMessageBatchRequestEntry.2.MessageBody=two" +
+		"&SendMessageBatchRequestEntry.2.MessageGroupId=g1&SendMessageBatchRequestEntry.2.DelaySeconds=0"
+
+	cliReceiveMessage = "Action=ReceiveMessage&Version=2012-11-05" +
+		"&QueueUrl=http%3A%2F%2Flocalhost%3A8080%2Fv1%2Faccountid%2Forders" +
+		"&AttributeName.1=ApproximateReceiveCount&AttributeName.2=SentTimestamp" +
+		"&MessageAttributeName.1=All&MaxNumberOfMessages=10&WaitTimeSeconds=20"
+)
+
+func decodeQueryForm(t *testing.T, body string, input any) error {
+	form, err := url.ParseQuery(body)
+	assert.NoError(t, err)
+	return decodeQuery(form, "", reflect.ValueOf(input).Elem())
+}
+
+func TestDecodeQuery(t *testing.T) {
+	var setAttributes AwsSetQueueAttributesRequest
+	assert.NoError(t, decodeQueryForm(t, cliSetQueueAttributes, &setAttributes))
+	assert.Equal(t, "http://localhost:8080/v1/accountid/orders", setAttributes.QueueUrl)
+	assert.Equal(t, map[string]string{
+		entity.AttrVisibilityTimeout: "45",
+		entity.AttrRedrivePolicy:     `{"deadLetterTargetArn":"srn:scp:sns:kr-west1:accountid:orders-dlq","maxReceiveCount":"5"}`,
+	}, setAttributes.Attributes)
+
+	var send AwsSendMessageRequest
+	assert.NoError(t, decodeQueryForm(t, cliSendMessage, &send))
+	assert.Equal(t, `{"id": 42}`, send.MessageBody)
+	if assert.NotNil(t, send.DelaySeconds) {
+		assert.Equal(t, 5, *send.DelaySeconds)
+	}
+	assert.Equal(t, map[string]entity.MessageAttributeValue{
+		"kind":  {DataType: entity.DataTypeString, StringValue: "order"},
+		"image": {DataType: entity.DataTypeBinary, BinaryValue: []byte{1, 2}},
+	}, send.MessageAttributes)
+	assert.Nil(t, send.MessageSystemAttributes)
+
+	var batch AwsSendMessageBatchRequest
+	assert.NoError(t, decodeQueryForm(t, cliSendMessageBatch, &batch))
+	zero := 0
+	assert.Equal(t, []entity.SendMessageBatchRequestEntry{
+		{
+			Id: "first", MessageBody: "one", MessageGroupId: "g1", MessageDeduplicationId: "d1",
+			MessageAttributes: map[string]entity.MessageAttributeValue{"kind": {DataType: "Number", StringValue: "7"}},
+		},
+		{Id: "second", MessageBody: "two", MessageGroupId: "g1", DelaySeconds: &zero},
+	}, batch.Entries)
+
+	var receive AwsReceiveMessageRequest
+	assert.NoError(t, decodeQueryForm(t, cliReceiveMessage, &receive))
+	assert.Equal(t, []string{entity.SystemAttrApproximateReceiveCount, "SentTimestamp"}, receive.AttributeNames)
+	assert.Equal(t, []string{"All"}, receive.MessageAttributeNames)
+	assert.Equal(t, 10, receive.MaxNumberOfMessages)
+	if assert.NotNil(t, receive.WaitTimeSeconds) {
+		assert.Equal(t, 20, *receive.WaitTimeSeconds)
+	}
+	assert.Nil(t, receive.VisibilityTimeout)
+}
+
+func TestDecodeQueryInvalid(t *testing.T) {
+	tests := []struct {
+		body  string
+		input any
+	}{
+		{"Attribute.1.Value=45", &AwsSetQueueAttributesRequest{}},
+		{"MessageBody=x&DelaySeconds=soon", &AwsSendMessageRequest{}},
+		{"MessageBody=x&MessageAttribute.1.Name=image&MessageAttribute.1.Value.DataType=Binary&MessageAttribute.1.Value.BinaryValue=%21%21", &AwsSendMessageRequest{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			assert.ErrorIs(t, decodeQueryForm(t, tt.body, tt.input), entity.ErrInvalidParameter)
+		})
+	}
+}
+
+func TestEncodeQueryResponse(t *testing.T) {
+	receive := ReceiveMessageResult{Messages: []entity.Message{{
+		MessageId:     "5fea7756-0ea4-451a-a703-a558b933e274",
+		ReceiptHandle: "MbZj6wDWli",
+		Body:          "hello & <bye>",
+		MD5OfBody:     "fafb00f5732ab283681e124bf8747ed1",
+		Attributes:    map[string]string{entity.SystemAttrApproximateReceiveCount: "1", "SentTimestamp": "1792230600000"},
+		MessageAttributes: map[string]entity.MessageAttributeValue{
+			"kind":  {DataType: entity.DataTypeString, StringValue: "order"},
+			"image": {DataType: entity.DataTypeBinary, BinaryValue: []byte{1, 2}},
+		},
+	}}}
+	body, err := encodeQueryResponse("ReceiveMessage", receive, "req-1")
+	assert.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<ReceiveMessageResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/"><ReceiveMessageResult>`+
+		`<Message><MessageId>5fea7756-0ea4-451a-a703-a558b933e274</MessageId><ReceiptHandle>MbZj6wDWli</ReceiptHandle>`+
+		`<Body>hello &amp; &lt;bye&gt;</Body><MD5OfBody>fafb00f5732ab283681e124bf8747ed1</MD5OfBody>`+
+		`<Attribute><Name>ApproximateReceiveCount</Name><Value>1</Value></Attribute>`+
+		`<Attribute><Name>SentTimestamp</Name><Value>1792230600000</Value></Attribute>`+
+		`<MessageAttribute><Name>image</Name><Value><DataType>Binary</DataType><BinaryValue>AQI=</BinaryValue></Value></MessageAttribute>`+
+		`<MessageAttribute><Name>kind</Name><Value><DataType>String</DataType><StringValue>order</StringValue></Value></MessageAttribute>`+
+		`</Message></ReceiveMessageResult><ResponseMetadata><RequestId>req-1</RequestId></ResponseMetadata></ReceiveMessageResponse>`,
+		string(body))
+
+	batch := entity.SendMessageBatchResult{
+		Successful: []entity.SendMessageBatchResultEntry{{Id: "first", MessageId: "m1", MD5OfMessageBody: "f97c5d29941bfb1b2fdab0874906ab82", SequenceNumber: "7"}},
+		Failed:     []entity.BatchResultErrorEntry{entity.NewBatchResultErrorEntry("second", entity.ErrInvalidParameter)},
+	}
+	body, err = encodeQueryResponse("SendMessageBatch", batch, "req-2")
+	assert.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<SendMessageBatchResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/"><SendMessageBatchResult>`+
+		`<SendMessageBatchResultEntry><Id>first</Id><MessageId>m1</MessageId><MD5OfMessageBody>f97c5d29941bfb1b2fdab0874906ab82</MD5OfMessageBody><SequenceNumber>7</SequenceNumber></SendMessageBatchResultEntry>`+
+		`<BatchResultErrorEntry><Id>second</Id><SenderFault>true</SenderFault><Code>InvalidParameter</Code><Message>`+entity.InvalidParameter.Error.Message+`</Message></BatchResultErrorEntry>`+
+		`</SendMessageBatchResult><ResponseMetadata><RequestId>req-2</RequestId></ResponseMetadata></SendMessageBatchResponse>`,
+		string(body))
+
+	body, err = encodeQueryResponse("DeleteMessage", AwsEmptyResult{}, "req-3")
+	assert.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<DeleteMessageResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/"><ResponseMetadata><RequestId>req-3</RequestId></ResponseMetadata></DeleteMessageResponse>`,
+		string(body))
+}
+
+// TestAwsQueryRoundTrip sends an AWS CLI body through the router and reads the
+// XML the CLI would parse.
+func TestAwsQueryRoundTrip(t *testing.T) {
+	var got AwsSendMessageRequest
+	operations := map[string]AwsOperation{
+		"SendMessage": awsOperation(func(c echo.Context, req *AwsSendMessageRequest) (entity.SendMessageResult, error) {
+			got = *req
+			return entity.SendMessageResult{MessageId: "m1", MD5OfMessageBody: "b6d1f3b4d3c5c6b1"}, nil
+		}),
+	}
+	e := echo.New()
+	e.Validator = imiddle.NewCustomValidator()
+	NewApiRouter(nil, nil, operations, nil).RegisterAwsProtocols(e)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(cliSendMessage))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "order", got.MessageAttributes["kind"].StringValue)
+	assert.Equal(t, []byte{1, 2}, got.MessageAttributes["image"].BinaryValue)
+	assert.Contains(t, rec.Body.String(), `<SendMessageResult><MessageId>m1</MessageId><MD5OfMessageBody>b6d1f3b4d3c5c6b1</MD5OfMessageBody></SendMessageResult>`)
+
+	// A missing MessageBody is reported in the Query error shape
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("Action=SendMessage&QueueUrl=http%3A%2F%2Flocalhost%3A8080%2Fv1%2Faccountid%2Forders"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<Code>InvalidParameterValue</Code>`)
+}
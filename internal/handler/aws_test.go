@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nats/internal/entity"
+	imiddle "nats/internal/middleware"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// newAwsTestServer serves the AWS protocols with a GetQueueUrl operation that
+// echoes the queue name, or fails with err when set.
+func newAwsTestServer(err error) *echo.Echo {
+	operations := map[string]AwsOperation{
+		"GetQueueUrl": awsOperation(func(c echo.Context, req *GetQueueUrlRequest) (AwsQueueUrlResult, error) {
+			return AwsQueueUrlResult{QueueUrl: "http://localhost:8080/v1/accountid/" + req.QueueName}, err
+		}),
+	}
+	e := echo.New()
+	e.Validator = imiddle.NewCustomValidator()
+	NewApiRouter(nil, nil, operations, nil).RegisterAwsProtocols(e)
+	return e
+}
+
+func serveAwsJsonRequest(e *echo.Echo, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, AwsJsonContentType)
+	if target != "" {
+		req.Header.Set(HeaderAmzTarget, target)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAwsJsonTarget(t *testing.T) {
+	tests := []struct {
+		target   string
+		body     string
+		wantCode int
+		wantType string
+	}{
+		{target: "AmazonSQS.GetQueueUrl", body: `{"QueueName":"orders"}`, wantCode: http.StatusOK},
+		{target: "AmazonSQS.GetQueueUrl", body: `{}`, wantCode: http.StatusBadRequest, wantType: "com.amazonaws.sqs#InvalidParameterValue"},
+		{target: "AmazonSQS.GetQueueUrl", body: `{"QueueName":`, wantCode: http.StatusBadRequest, wantType: "com.amazonaws.sqs#InvalidParameterValue"},
+		{target: "AmazonSQS.CreateTopic", body: `{}`, wantCode: http.StatusBadRequest, wantType: "com.amazonaws.sqs#UnknownOperationException"},
+		{target: "GetQueueUrl", body: `{"QueueName":"orders"}`, wantCode: http.StatusBadRequest, wantType: "com.amazonaws.sqs#UnknownOperationException"},
+		{target: "AmazonSNS.GetQueueUrl", body: `{"QueueName":"orders"}`, wantCode: http.StatusBadRequest, wantType: "com.amazonaws.sqs#UnknownOperationException"},
+		{target: "", body: `{"QueueName":"orders"}`, wantCode: http.StatusBadRequest, wantType: "com.amazonaws.sqs#UnknownOperationException"},
+	}
+	e := newAwsTestServer(nil)
+	for _, tt := range tests {
+		t.Run(tt.target+" "+tt.body, func(t *testing.T) {
+			rec := serveAwsJsonRequest(e, tt.target, tt.body)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, AwsJsonContentType, rec.Header().Get(echo.HeaderContentType))
+
+			var resp map[string]string
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			if tt.wantType == "" {
+				assert.Equal(t, "http://localhost:8080/v1/accountid/orders", resp["QueueUrl"])
+				return
+			}
+			assert.Equal(t, tt.wantType, resp["__type"])
+			assert.NotEmpty(t, resp["message"])
+		})
+	}
+}
+
+func TestAwsJsonErrorType(t *testing.T) {
+	tests := []struct {
+		err            error
+		wantCode       int
+		wantType       string
+		wantQueryError string
+	}{
+		{entity.ErrQueueNotFound, http.StatusBadRequest, "com.amazonaws.sqs#QueueDoesNotExist", "AWS.SimpleQueueService.NonExistentQueue;Sender"},
+		{entity.ErrAuthorization, http.StatusForbidden, "com.amazonaws.sqs#AccessDenied", "AccessDenied;Sender"},
+		{entity.ErrQueueAlreadyExists, http.StatusBadRequest, "com.amazonaws.sqs#QueueNameExists", "QueueAlreadyExists;Sender"},
+		{entity.ErrPurgeQueueInProgress, http.StatusForbidden, "com.amazonaws.sqs#PurgeQueueInProgress", "AWS.SimpleQueueService.PurgeQueueInProgress;Sender"},
+		{fmt.Errorf("%w: MaxResults", entity.ErrInvalidParameter), http.StatusBadRequest, "com.amazonaws.sqs#InvalidParameterValue", "InvalidParameterValue;Sender"},
+		{entity.ErrReceiptHandleInvalid, http.StatusBadRequest, "com.amazonaws.sqs#ReceiptHandleIsInvalid", "ReceiptHandleIsInvalid;Sender"},
+		{entity.ErrTooManyEntriesInBatch, http.StatusBadRequest, "com.amazonaws.sqs#TooManyEntriesInBatchRequest", "AWS.SimpleQueueService.TooManyEntriesInBatchRequest;Sender"},
+		{entity.ErrMoveTaskNotFound, http.StatusNotFound, "com.amazonaws.sqs#ResourceNotFoundException", "ResourceNotFoundException;Sender"},
+		{entity.ErrSignatureDoesNotMatch, http.StatusForbidden, "com.amazonaws.sqs#SignatureDoesNotMatch", "SignatureDoesNotMatch;Sender"},
+		{errors.New("nats: timeout"), http.StatusInternalServerError, "com.amazonaws.sqs#InternalError", "InternalError;Server"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			rec := serveAwsJsonRequest(newAwsTestServer(tt.err), "AmazonSQS.GetQueueUrl", `{"QueueName":"orders"}`)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantQueryError, rec.Header().Get(headerAmznQueryError))
+
+			var resp awsJsonError
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.wantType, resp.Type)
+			assert.NotEmpty(t, resp.Message)
+		})
+	}
+}
+
+func TestParseQueueUrl(t *testing.T) {
+	tests := []struct {
+		queueUrl    string
+		wantAccount string
+		wantName    string
+		wantErr     error
+	}{
+		{queueUrl: "http://localhost:8080/v1/accountid/orders", wantAccount: "accountid", wantName: "orders"},
+		{queueUrl: "http://localhost:8080/v1/accountid/orders.fifo/", wantAccount: "accountid", wantName: "orders.fifo"},
+		{queueUrl: "https://sqs.kr-west1.example.com/accountid/orders", wantAccount: "accountid", wantName: "orders"},
+		{queueUrl: "/accountid/orders", wantAccount: "accountid", wantName: "orders"},
+		{queueUrl: "http://localhost:8080/orders", wantErr: entity.ErrInvalidParameter},
+		{queueUrl: "http://localhost:8080/", wantErr: entity.ErrInvalidParameter},
+		{queueUrl: "orders", wantErr: entity.ErrInvalidParameter},
+		{queueUrl: "http://localhost:8080/v1/account~id/orders", wantErr: entity.ErrInvalidParameter},
+		{queueUrl: "http://localhost:8080/v1/accountid/orders*", wantErr: entity.ErrInvalidParameter},
+		{queueUrl: "http://[::1", wantErr: entity.ErrInvalidParameter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.queueUrl, func(t *testing.T) {
+			account, name, err := parseQueueUrl(tt.queueUrl)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantAccount, account)
+			assert.Equal(t, tt.wantName, name)
+		})
+	}
+}
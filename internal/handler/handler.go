@@ -1,17 +1,35 @@
 package handler
 
 import (
+	"fmt"
+	"nats/internal/entity"
 	"nats/internal/service"
 
 	"github.com/labstack/echo/v4"
 )
 
-func AccountBaseHandlers(queueSvc service.QueueService) map[string]func() echo.HandlerFunc {
+func AccountBaseHandlers(queueSvc service.QueueService, moveTaskSvc service.MoveTaskService, topicSvc service.TopicService) map[string]func() echo.HandlerFunc {
 	queueHandler := NewQueueHandler(queueSvc)
+	moveTaskHandler := NewMoveTaskHandler(moveTaskSvc)
+	topicHandler := NewTopicHandler(topicSvc)
 
 	return map[string]func() echo.HandlerFunc{
 		"createQueue": queueHandler.Create,
 		"listQueues":  queueHandler.List,
+		"getQueueUrl": queueHandler.GetUrl,
+
+		"startMessageMoveTask":  moveTaskHandler.Start,
+		"listMessageMoveTasks":  moveTaskHandler.List,
+		"cancelMessageMoveTask": moveTaskHandler.Cancel,
+
+		"createTopic": topicHandler.Create,
+		"deleteTopic": topicHandler.Delete,
+		"listTopics":  topicHandler.List,
+		"publish":     topicHandler.Publish,
+
+		"subscribe":                topicHandler.Subscribe,
+		"unsubscribe":              topicHandler.Unsubscribe,
+		"listSubscriptionsByTopic": topicHandler.ListSubscriptions,
 	}
 }
 
@@ -20,9 +38,112 @@ func AccountQueueBaseHandlers(queueSvc service.QueueService, messageSvc service.
 	messageHandler := NewMessageHandler(messageSvc)
 
 	return map[string]func() echo.HandlerFunc{
-		"deleteQueue":  queueHandler.Delete,
+		"deleteQueue": queueHandler.Delete,
+		"purgeQueue":  queueHandler.Purge,
+
+		"tagQueue":      queueHandler.Tag,
+		"untagQueue":    queueHandler.Untag,
+		"listQueueTags": queueHandler.ListTags,
+
+		"getQueueAttributes": queueHandler.GetAttributes,
+		"setQueueAttributes": queueHandler.SetAttributes,
+
+		"addPermission":    queueHandler.AddPermission,
+		"removePermission": queueHandler.RemovePermission,
+
 		"message":      messageHandler.Message,
 		"messageAsync": messageHandler.MessageAsync,
 		"messageCheck": messageHandler.CheckAckStatus,
+
+		"sendMessageBatch": messageHandler.SendMessageBatch,
+
+		"receiveMessage":     messageHandler.ReceiveMessage,
+		"deleteMessage":      messageHandler.DeleteMessage,
+		"deleteMessageBatch": messageHandler.DeleteMessageBatch,
+
+		"changeMessageVisibility":      messageHandler.ChangeMessageVisibility,
+		"changeMessageVisibilityBatch": messageHandler.ChangeMessageVisibilityBatch,
+	}
+}
+
+// queuePolicyActions names the SQS action a queue handler performs, as the
+// queue Policy refers to it.
+var queuePolicyActions = map[string]string{
+	"deleteQueue":        "DeleteQueue",
+	"purgeQueue":         "PurgeQueue",
+	"tagQueue":           "TagQueue",
+	"untagQueue":         "UntagQueue",
+	"listQueueTags":      "ListQueueTags",
+	"getQueueAttributes": "GetQueueAttributes",
+	"setQueueAttributes": "SetQueueAttributes",
+	"addPermission":      "AddPermission",
+	"removePermission":   "RemovePermission",
+
+	"message":          "SendMessage",
+	"messageAsync":     "SendMessage",
+	"messageCheck":     "SendMessage",
+	"sendMessageBatch": "SendMessage",
+
+	"receiveMessage":               "ReceiveMessage",
+	"deleteMessage":                "DeleteMessage",
+	"deleteMessageBatch":           "DeleteMessage",
+	"changeMessageVisibility":      "ChangeMessageVisibility",
+	"changeMessageVisibilityBatch": "ChangeMessageVisibility",
+}
+
+// accessRequest describes a request of account on a queue for its Policy.
+func accessRequest(c echo.Context, account, action string) entity.AccessRequest {
+	return entity.AccessRequest{
+		Account:    account,
+		Action:     action,
+		Conditions: map[string]string{entity.ConditionSourceIp: c.RealIP()},
+	}
+}
+
+// AwsOperations maps the AWS SQS operation names to their implementation.
+// defaultAccount is the account of requests that do not address a queue URL.
+func AwsOperations(queueSvc service.QueueService, messageSvc service.MessageService, moveTaskSvc service.MoveTaskService, defaultAccount string) map[string]AwsOperation {
+	h := NewAwsHandler(queueSvc, messageSvc, moveTaskSvc, defaultAccount)
+
+	return map[string]AwsOperation{
+		"CreateQueue":        awsOperation(h.CreateQueue),
+		"DeleteQueue":        awsOperation(h.DeleteQueue),
+		"GetQueueUrl":        awsOperation(h.GetQueueUrl),
+		"ListQueues":         awsOperation(h.ListQueues),
+		"PurgeQueue":         awsOperation(h.PurgeQueue),
+		"GetQueueAttributes": awsOperation(h.GetQueueAttributes),
+		"SetQueueAttributes": awsOperation(h.SetQueueAttributes),
+
+		"AddPermission":    awsOperation(h.AddPermission),
+		"RemovePermission": awsOperation(h.RemovePermission),
+
+		"TagQueue":      awsOperation(h.TagQueue),
+		"UntagQueue":    awsOperation(h.UntagQueue),
+		"ListQueueTags": awsOperation(h.ListQueueTags),
+
+		"SendMessage":      awsOperation(h.SendMessage),
+		"SendMessageBatch": awsOperation(h.SendMessageBatch),
+		"ReceiveMessage":   awsOperation(h.ReceiveMessage),
+
+		"DeleteMessage":      awsOperation(h.DeleteMessage),
+		"DeleteMessageBatch": awsOperation(h.DeleteMessageBatch),
+
+		"ChangeMessageVisibility":      awsOperation(h.ChangeMessageVisibility),
+		"ChangeMessageVisibilityBatch": awsOperation(h.ChangeMessageVisibilityBatch),
+
+		"StartMessageMoveTask":  awsOperation(h.StartMessageMoveTask),
+		"ListMessageMoveTasks":  awsOperation(h.ListMessageMoveTasks),
+		"CancelMessageMoveTask": awsOperation(h.CancelMessageMoveTask),
+	}
+}
+
+// resolveQueueName makes the :queueid path segment identify the queue. A
+// queueName in the body is still accepted as long as it names the same queue.
+func resolveQueueName(c echo.Context, name *string) error {
+	queueID := c.Param("queueid")
+	if *name != "" && *name != queueID {
+		return fmt.Errorf("%w: queueName %q does not match the queue URL", entity.ErrInvalidParameter, *name)
 	}
+	*name = queueID
+	return nil
 }
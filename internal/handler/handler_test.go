@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nats/internal/entity"
+	imiddle "nats/internal/middleware"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAccessRequestSourceIp checks that aws:SourceIp cannot be forged with
+// headers, and is only read from X-Forwarded-For of a trusted proxy.
+func TestAccessRequestSourceIp(t *testing.T) {
+	var policy entity.Policy
+	assert.NoError(t, json.Unmarshal([]byte(`{"Statement":[
+		{"Effect":"Allow","Principal":"*","Action":"sqs:SendMessage","Condition":{"IpAddress":{"aws:SourceIp":"10.0.0.0/8"}}}
+	]}`), &policy))
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		headers        map[string]string
+		want           entity.PolicyDecision
+	}{
+		{name: "direct", remoteAddr: "10.1.2.3:4000", want: entity.PolicyAllow},
+		{name: "direct outside", remoteAddr: "203.0.113.5:4000", want: entity.PolicyNoMatch},
+		{name: "forged forwarded for", remoteAddr: "203.0.113.5:4000",
+			headers: map[string]string{echo.HeaderXForwardedFor: "10.1.2.3"}, want: entity.PolicyNoMatch},
+		{name: "forged real ip", remoteAddr: "203.0.113.5:4000",
+			headers: map[string]string{echo.HeaderXRealIP: "10.1.2.3"}, want: entity.PolicyNoMatch},
+		{name: "forged through untrusted client", trustedProxies: []string{"192.0.2.0/24"}, remoteAddr: "203.0.113.5:4000",
+			headers: map[string]string{echo.HeaderXForwardedFor: "10.1.2.3"}, want: entity.PolicyNoMatch},
+		{name: "trusted proxy", trustedProxies: []string{"192.0.2.0/24"}, remoteAddr: "192.0.2.1:4000",
+			headers: map[string]string{echo.HeaderXForwardedFor: "10.1.2.3"}, want: entity.PolicyAllow},
+		{name: "trusted proxy forwarding forged", trustedProxies: []string{"192.0.2.0/24"}, remoteAddr: "192.0.2.1:4000",
+			headers: map[string]string{echo.HeaderXForwardedFor: "10.1.2.3, 203.0.113.5"}, want: entity.PolicyNoMatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extractor, err := imiddle.IPExtractor(tt.trustedProxies)
+			assert.NoError(t, err)
+			e := echo.New()
+			e.IPExtractor = extractor
+
+			var decision entity.PolicyDecision
+			e.POST("/", func(c echo.Context) error {
+				decision = policy.Evaluate(accessRequest(c, "partner", "SendMessage"))
+				return c.NoContent(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			e.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tt.want, decision)
+		})
+	}
+
+	_, err := imiddle.IPExtractor([]string{"10.0.0.1"})
+	assert.Error(t, err)
+}
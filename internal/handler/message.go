@@ -2,6 +2,7 @@ package handler
 
 import (
 	"nats/internal/context/logs"
+	"nats/internal/entity"
 	"nats/internal/service"
 	"net/http"
 
@@ -18,13 +19,100 @@ func NewMessageHandler(svc service.MessageService) *MessageHandler {
 }
 
 type MessageRequest struct {
-	QueueName string `json:"queueName"`
-	Message   string `json:"message"`
-	Subject   string `json:"subject"`
+	QueueName              string `json:"queueName"`
+	Message                string `json:"message"`
+	Subject                string `json:"subject"`
+	MessageGroupId         string `json:"MessageGroupId"`
+	MessageDeduplicationId string `json:"MessageDeduplicationId"`
+	DelaySeconds           *int   `json:"DelaySeconds"`
+
+	MessageAttributes       map[string]entity.MessageAttributeValue `json:"MessageAttributes"`
+	MessageSystemAttributes map[string]entity.MessageAttributeValue `json:"MessageSystemAttributes"`
+}
+
+func (r MessageRequest) sendOptions() entity.SendOptions {
+	return entity.SendOptions{
+		MessageGroupId:         r.MessageGroupId,
+		MessageDeduplicationId: r.MessageDeduplicationId,
+		DelaySeconds:           r.DelaySeconds,
+
+		MessageAttributes:       r.MessageAttributes,
+		MessageSystemAttributes: r.MessageSystemAttributes,
+	}
+}
+
+type SendMessageResponse struct {
+	SendMessageResult entity.SendMessageResult `json:"SendMessageResult"`
+	ResponseMetadata  entity.ResponseMetadata  `json:"ResponseMetadata"`
+}
+
+type SendMessageBatchRequest struct {
+	QueueName string                                `json:"queueName" validate:"required"`
+	Entries   []entity.SendMessageBatchRequestEntry `json:"Entries" validate:"dive"`
 }
 
-type MessageResponse struct {
-	MessageID string `json:"messageId"`
+type SendMessageBatchResponse struct {
+	SendMessageBatchResult entity.SendMessageBatchResult `json:"SendMessageBatchResult"`
+	ResponseMetadata       entity.ResponseMetadata       `json:"ResponseMetadata"`
+}
+
+type ReceiveMessageRequest struct {
+	QueueName           string `json:"queueName" validate:"required"`
+	MaxNumberOfMessages int    `json:"MaxNumberOfMessages" validate:"omitempty,min=1,max=10"`
+	VisibilityTimeout   *int   `json:"VisibilityTimeout" validate:"omitempty,min=0,max=43200"`
+	WaitTimeSeconds     *int   `json:"WaitTimeSeconds" validate:"omitempty,min=0,max=20"`
+
+	AttributeNames              []string `json:"AttributeNames"`
+	MessageSystemAttributeNames []string `json:"MessageSystemAttributeNames"`
+	MessageAttributeNames       []string `json:"MessageAttributeNames"`
+}
+
+type ReceiveMessageResult struct {
+	Messages []entity.Message `json:"Messages"`
+}
+
+type ReceiveMessageResponse struct {
+	ReceiveMessageResult ReceiveMessageResult    `json:"ReceiveMessageResult"`
+	ResponseMetadata     entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type DeleteMessageRequest struct {
+	QueueName     string `json:"queueName" validate:"required"`
+	ReceiptHandle string `json:"ReceiptHandle" validate:"required"`
+}
+
+type DeleteMessageResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type DeleteMessageBatchRequest struct {
+	QueueName string                                  `json:"queueName" validate:"required"`
+	Entries   []entity.DeleteMessageBatchRequestEntry `json:"Entries" validate:"dive"`
+}
+
+type DeleteMessageBatchResponse struct {
+	DeleteMessageBatchResult entity.DeleteMessageBatchResult `json:"DeleteMessageBatchResult"`
+	ResponseMetadata         entity.ResponseMetadata         `json:"ResponseMetadata"`
+}
+
+type ChangeMessageVisibilityRequest struct {
+	QueueName         string `json:"queueName" validate:"required"`
+	ReceiptHandle     string `json:"ReceiptHandle" validate:"required"`
+	VisibilityTimeout *int   `json:"VisibilityTimeout" validate:"required,min=0,max=43200"`
+}
+
+type ChangeMessageVisibilityResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type ChangeMessageVisibilityBatchRequest struct {
+	QueueName string                                            `json:"queueName" validate:"required"`
+	Entries   []entity.ChangeMessageVisibilityBatchRequestEntry `json:"Entries" validate:"dive"`
+}
+
+type ChangeMessageVisibilityBatchResponse struct {
+	ChangeMessageVisibilityBatchResult entity.ChangeMessageVisibilityBatchResult `json:"ChangeMessageVisibilityBatchResult"`
+	ResponseMetadata                   entity.ResponseMetadata                   `json:"ResponseMetadata"`
 }
 
 func (h *MessageHandler) Message() echo.HandlerFunc {
@@ -35,17 +123,24 @@ func (h *MessageHandler) Message() echo.HandlerFunc {
 		var req MessageRequest
 		if err := c.Bind(&req); err != nil {
 			logger.Warn("메시지 요청 파싱 실패", zap.Error(err))
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logger.Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
 		}
 
-		msgID, err := h.svc.SendMessage(ctx, req.QueueName, req.Message, req.Subject)
+		result, err := h.svc.SendMessage(ctx, req.QueueName, c.Param("accountid"), req.Message, req.Subject, req.sendOptions())
 		if err != nil {
 			logger.Error("메시지 발행 실패", zap.Error(err))
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
 		}
 
-		logger.Info("메시지 발행 성공", zap.String("messageId", msgID))
-		return c.JSON(http.StatusOK, MessageResponse{MessageID: msgID})
+		logger.Info("메시지 발행 성공", zap.String("messageId", result.MessageId), zap.String("seq", result.SequenceNumber))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, SendMessageResponse{SendMessageResult: result, ResponseMetadata: meta})
 	}
 }
 
@@ -57,17 +152,60 @@ func (h *MessageHandler) MessageAsync() echo.HandlerFunc {
 		var req MessageRequest
 		if err := c.Bind(&req); err != nil {
 			logger.Warn("메시지 요청 파싱 실패", zap.Error(err))
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logger.Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
 		}
 
-		msgID, err := h.svc.SendAsyncMessage(ctx, req.QueueName, req.Message, req.Subject)
+		result, err := h.svc.SendAsyncMessage(ctx, req.QueueName, c.Param("accountid"), req.Message, req.Subject, req.sendOptions())
 		if err != nil {
 			logger.Error("메시지 발행 실패", zap.Error(err))
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
 		}
 
-		logger.Info("메시지 발행 성공", zap.String("messageId", msgID))
-		return c.JSON(http.StatusOK, MessageResponse{MessageID: msgID})
+		logger.Info("메시지 발행 성공", zap.String("messageId", result.MessageId))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, SendMessageResponse{SendMessageResult: result, ResponseMetadata: meta})
+	}
+}
+
+func (h *MessageHandler) SendMessageBatch() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req SendMessageBatchRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid sendMessageBatch request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		result, err := h.svc.SendMessageBatch(ctx, req.QueueName, c.Param("accountid"), req.Entries)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to send message batch", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Message batch sending done", zap.String("queue", req.QueueName),
+			zap.Int("successful", len(result.Successful)), zap.Int("failed", len(result.Failed)))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, SendMessageBatchResponse{
+			SendMessageBatchResult: result, ResponseMetadata: meta,
+		})
 	}
 }
 
@@ -92,3 +230,182 @@ func (h *MessageHandler) CheckAckStatus() echo.HandlerFunc {
 		return c.JSON(http.StatusOK, map[string]string{"status": status})
 	}
 }
+
+func (h *MessageHandler) ReceiveMessage() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req ReceiveMessageRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid receiveMessage request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		opts := entity.ReceiveOptions{
+			MaxNumberOfMessages: req.MaxNumberOfMessages,
+			VisibilityTimeout:   req.VisibilityTimeout,
+			WaitTimeSeconds:     req.WaitTimeSeconds,
+
+			AttributeNames:        append(req.AttributeNames, req.MessageSystemAttributeNames...),
+			MessageAttributeNames: req.MessageAttributeNames,
+		}
+		messages, err := h.svc.ReceiveMessage(ctx, req.QueueName, c.Param("accountid"), opts)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to receive messages", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Messages received", zap.String("queue", req.QueueName), zap.Int("count", len(messages)))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ReceiveMessageResponse{
+			ReceiveMessageResult: ReceiveMessageResult{Messages: messages}, ResponseMetadata: meta,
+		})
+	}
+}
+
+func (h *MessageHandler) DeleteMessage() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req DeleteMessageRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid deleteMessage request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.DeleteMessage(ctx, req.QueueName, c.Param("accountid"), req.ReceiptHandle); err != nil {
+			logs.GetLogger(ctx).Error("Failed to delete message", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Message deletion success", zap.String("queue", req.QueueName))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, DeleteMessageResponse{ResponseMetadata: meta})
+	}
+}
+
+func (h *MessageHandler) DeleteMessageBatch() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req DeleteMessageBatchRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid deleteMessageBatch request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		result, err := h.svc.DeleteMessageBatch(ctx, req.QueueName, c.Param("accountid"), req.Entries)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to delete message batch", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Message batch deletion done", zap.String("queue", req.QueueName),
+			zap.Int("successful", len(result.Successful)), zap.Int("failed", len(result.Failed)))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, DeleteMessageBatchResponse{
+			DeleteMessageBatchResult: result, ResponseMetadata: meta,
+		})
+	}
+}
+
+func (h *MessageHandler) ChangeMessageVisibility() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req ChangeMessageVisibilityRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid changeMessageVisibility request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.ChangeMessageVisibility(ctx, req.QueueName, c.Param("accountid"), req.ReceiptHandle, *req.VisibilityTimeout); err != nil {
+			logs.GetLogger(ctx).Error("Failed to change message visibility", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Message visibility changed", zap.String("queue", req.QueueName), zap.Int("visibilityTimeout", *req.VisibilityTimeout))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ChangeMessageVisibilityResponse{ResponseMetadata: meta})
+	}
+}
+
+func (h *MessageHandler) ChangeMessageVisibilityBatch() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req ChangeMessageVisibilityBatchRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid changeMessageVisibilityBatch request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		result, err := h.svc.ChangeMessageVisibilityBatch(ctx, req.QueueName, c.Param("accountid"), req.Entries)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to change message visibility batch", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Message visibility batch change done", zap.String("queue", req.QueueName),
+			zap.Int("successful", len(result.Successful)), zap.Int("failed", len(result.Failed)))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ChangeMessageVisibilityBatchResponse{
+			ChangeMessageVisibilityBatchResult: result, ResponseMetadata: meta,
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"nats/internal/context/logs"
+	"nats/internal/entity"
+	"nats/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type MoveTaskHandler struct {
+	svc service.MoveTaskService
+}
+
+func NewMoveTaskHandler(svc service.MoveTaskService) *MoveTaskHandler {
+	return &MoveTaskHandler{svc: svc}
+}
+
+type StartMessageMoveTaskRequest struct {
+	SourceArn                    string `json:"SourceArn" validate:"required"`
+	DestinationArn               string `json:"DestinationArn"`
+	MaxNumberOfMessagesPerSecond int    `json:"MaxNumberOfMessagesPerSecond" validate:"omitempty,min=1,max=500"`
+}
+
+type StartMessageMoveTaskResult struct {
+	TaskHandle string `json:"TaskHandle"`
+}
+
+type StartMessageMoveTaskResponse struct {
+	StartMessageMoveTaskResult StartMessageMoveTaskResult `json:"StartMessageMoveTaskResult"`
+	ResponseMetadata           entity.ResponseMetadata    `json:"ResponseMetadata"`
+}
+
+type ListMessageMoveTasksRequest struct {
+	SourceArn  string `json:"SourceArn" validate:"required"`
+	MaxResults int    `json:"MaxResults" validate:"omitempty,min=1,max=10"`
+}
+
+type ListMessageMoveTasksResult struct {
+	Results []entity.MessageMoveTask `json:"Results" xml:"ListMessageMoveTasksResultEntry"`
+}
+
+type ListMessageMoveTasksResponse struct {
+	ListMessageMoveTasksResult ListMessageMoveTasksResult `json:"ListMessageMoveTasksResult"`
+	ResponseMetadata           entity.ResponseMetadata    `json:"ResponseMetadata"`
+}
+
+type CancelMessageMoveTaskRequest struct {
+	TaskHandle string `json:"TaskHandle" validate:"required"`
+}
+
+type CancelMessageMoveTaskResult struct {
+	ApproximateNumberOfMessagesMoved int64 `json:"ApproximateNumberOfMessagesMoved"`
+}
+
+type CancelMessageMoveTaskResponse struct {
+	CancelMessageMoveTaskResult CancelMessageMoveTaskResult `json:"CancelMessageMoveTaskResult"`
+	ResponseMetadata            entity.ResponseMetadata     `json:"ResponseMetadata"`
+}
+
+func (h *MoveTaskHandler) Start() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req StartMessageMoveTaskRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid startMessageMoveTask request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		taskHandle, err := h.svc.StartMessageMoveTask(ctx, c.Param("accountid"), req.SourceArn, req.DestinationArn, req.MaxNumberOfMessagesPerSecond)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to start message move task", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Message move task started", zap.String("source", req.SourceArn), zap.String("taskHandle", taskHandle))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, StartMessageMoveTaskResponse{
+			StartMessageMoveTaskResult: StartMessageMoveTaskResult{TaskHandle: taskHandle}, ResponseMetadata: meta,
+		})
+	}
+}
+
+func (h *MoveTaskHandler) List() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req ListMessageMoveTasksRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid listMessageMoveTasks request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		tasks, err := h.svc.ListMessageMoveTasks(ctx, c.Param("accountid"), req.SourceArn, req.MaxResults)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Message move task lookup failed", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Return message move tasks", zap.Int("count", len(tasks)))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ListMessageMoveTasksResponse{
+			ListMessageMoveTasksResult: ListMessageMoveTasksResult{Results: tasks}, ResponseMetadata: meta,
+		})
+	}
+}
+
+func (h *MoveTaskHandler) Cancel() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req CancelMessageMoveTaskRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid cancelMessageMoveTask request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		moved, err := h.svc.CancelMessageMoveTask(ctx, c.Param("accountid"), req.TaskHandle)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to cancel message move task", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Message move task cancelled", zap.String("taskHandle", req.TaskHandle))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, CancelMessageMoveTaskResponse{
+			CancelMessageMoveTaskResult: CancelMessageMoveTaskResult{ApproximateNumberOfMessagesMoved: moved}, ResponseMetadata: meta,
+		})
+	}
+}
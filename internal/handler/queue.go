@@ -5,7 +5,6 @@ import (
 	"nats/internal/entity"
 	"nats/internal/service"
 	"net/http"
-	"strings"
 
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
@@ -20,7 +19,9 @@ func NewQueueHandler(svc service.QueueService) *QueueHandler {
 }
 
 type CreateQueueRequest struct {
-	Name string `json:"Name" validate:"required"`
+	Name       string            `json:"Name" validate:"required"`
+	Attributes map[string]string `json:"Attributes"`
+	Tags       map[string]string `json:"tags"`
 }
 
 type CreateQueueResponse struct {
@@ -29,15 +30,108 @@ type CreateQueueResponse struct {
 }
 
 type DeleteQueueRequest struct {
-	QueueSrn string `json:"QueueSrn" validate:"required"`
+	QueueSrn string `json:"QueueSrn"`
 }
 
 type DeleteQueueResponse struct {
 	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
 }
 
+type GetQueueAttributesRequest struct {
+	QueueName      string   `json:"queueName" validate:"required"`
+	AttributeNames []string `json:"AttributeNames"`
+}
+
+type GetQueueAttributesResult struct {
+	Attributes map[string]string `json:"Attributes"`
+}
+
+type GetQueueAttributesResponse struct {
+	GetQueueAttributesResult GetQueueAttributesResult `json:"GetQueueAttributesResult"`
+	ResponseMetadata         entity.ResponseMetadata  `json:"ResponseMetadata"`
+}
+
+type SetQueueAttributesRequest struct {
+	QueueName  string            `json:"queueName" validate:"required"`
+	Attributes map[string]string `json:"Attributes"`
+}
+
+type SetQueueAttributesResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type PurgeQueueRequest struct {
+	QueueName string `json:"queueName"`
+}
+
+type PurgeQueueResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type GetQueueUrlRequest struct {
+	QueueName              string `json:"QueueName" validate:"required"`
+	QueueOwnerAWSAccountId string `json:"QueueOwnerAWSAccountId"`
+}
+
+type GetQueueUrlResponse struct {
+	GetQueueUrlResult entity.Queue            `json:"GetQueueUrlResult"`
+	ResponseMetadata  entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type ListQueuesRequest struct {
+	QueueNamePrefix string `query:"QueueNamePrefix"`
+	MaxResults      int    `query:"MaxResults" validate:"omitempty,min=1,max=1000"`
+	NextToken       string `query:"NextToken"`
+	TagKey          string `query:"TagKey"`
+	TagValue        string `query:"TagValue"`
+}
+
+type TagQueueRequest struct {
+	QueueName string            `json:"queueName"`
+	Tags      map[string]string `json:"Tags"`
+}
+
+type UntagQueueRequest struct {
+	QueueName string   `json:"queueName"`
+	TagKeys   []string `json:"TagKeys"`
+}
+
+type ListQueueTagsRequest struct {
+	QueueName string `json:"queueName"`
+}
+
+type TagQueueResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type ListQueueTagsResult struct {
+	Tags map[string]string `json:"Tags"`
+}
+
+type ListQueueTagsResponse struct {
+	ListQueueTagsResult ListQueueTagsResult     `json:"ListQueueTagsResult"`
+	ResponseMetadata    entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type AddPermissionRequest struct {
+	QueueName     string   `json:"queueName"`
+	Label         string   `json:"Label"`
+	AWSAccountIds []string `json:"AWSAccountIds"`
+	Actions       []string `json:"Actions"`
+}
+
+type RemovePermissionRequest struct {
+	QueueName string `json:"queueName"`
+	Label     string `json:"Label"`
+}
+
+type PermissionResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
 type ListQueuesResponse struct {
-	Queues []entity.Queue `json:"queues"`
+	ListQueuesResult entity.ListQueuesResult `json:"ListQueuesResult"`
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
 }
 
 func (h *QueueHandler) Create() echo.HandlerFunc {
@@ -55,10 +149,11 @@ func (h *QueueHandler) Create() echo.HandlerFunc {
 			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
 		}
 
-		result, err := h.svc.CreateQueue(ctx, req.Name, c.Param("accountid"))
+		result, err := h.svc.CreateQueue(ctx, req.Name, c.Param("accountid"), req.Attributes, req.Tags)
 		if err != nil {
 			logs.GetLogger(ctx).Error("Failed to create stream", zap.Error(err))
-			return c.JSON(entity.InternalError.HTTPCode, entity.InternalError.Error)
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
 		}
 		logs.GetLogger(ctx).Info("Stream creation success", zap.String("queue", req.Name))
 		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
@@ -83,16 +178,26 @@ func (h *QueueHandler) Delete() echo.HandlerFunc {
 			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
 		}
 
-		parts := strings.Split(req.QueueSrn, ":")
-		name := parts[len(parts)-1]
-
-		if name == "" {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "missing 'name' parameter"})
+		account := c.Param("accountid")
+		var name string
+		if req.QueueSrn != "" {
+			srnName, err := h.svc.ResolveQueueSrn(req.QueueSrn, account)
+			if err != nil {
+				logs.GetLogger(ctx).Error("QueueSrn is not a queue of the account", zap.Error(err))
+				errResp := entity.ErrorResponseOf(err)
+				return c.JSON(errResp.HTTPCode, errResp.Error)
+			}
+			name = srnName
+		}
+		if err := resolveQueueName(c, &name); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
 		}
 
-		if err := h.svc.DeleteQueue(ctx, name); err != nil {
+		if err := h.svc.DeleteQueue(ctx, name, account); err != nil {
 			logs.GetLogger(ctx).Error("Failed to delete stream", zap.Error(err))
-			return c.JSON(entity.InternalError.HTTPCode, entity.InternalError.Error)
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
 		}
 
 		logs.GetLogger(ctx).Info("Stream deletion success", zap.String("queue", name))
@@ -105,13 +210,290 @@ func (h *QueueHandler) List() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
 
-		queues, err := h.svc.ListQueues(ctx, c.Param("accountid"))
+		var req ListQueuesRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid listQueues request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid listQueues request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		opts := entity.ListQueuesOptions{
+			QueueNamePrefix: req.QueueNamePrefix,
+			MaxResults:      req.MaxResults,
+			NextToken:       req.NextToken,
+			TagKey:          req.TagKey,
+			TagValue:        req.TagValue,
+		}
+		result, err := h.svc.ListQueues(ctx, c.Param("accountid"), opts)
 		if err != nil {
 			logs.GetLogger(ctx).Error("Queue list lookup failed", zap.Error(err))
-			return c.JSON(entity.InternalError.HTTPCode, entity.InternalError.Error)
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Return queue list", zap.Int("count", len(result.QueueUrls)))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ListQueuesResponse{ListQueuesResult: result, ResponseMetadata: meta})
+	}
+}
+
+func (h *QueueHandler) GetAttributes() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req GetQueueAttributesRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid getQueueAttributes request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		attrs, err := h.svc.GetQueueAttributes(ctx, req.QueueName, c.Param("accountid"), req.AttributeNames)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to get queue attributes", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
 		}
 
-		logs.GetLogger(ctx).Info("Return queue list", zap.Int("count", len(queues)))
-		return c.JSON(http.StatusOK, ListQueuesResponse{Queues: queues})
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, GetQueueAttributesResponse{
+			GetQueueAttributesResult: GetQueueAttributesResult{Attributes: attrs}, ResponseMetadata: meta,
+		})
+	}
+}
+
+func (h *QueueHandler) SetAttributes() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req SetQueueAttributesRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid setQueueAttributes request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.SetQueueAttributes(ctx, req.QueueName, c.Param("accountid"), req.Attributes); err != nil {
+			logs.GetLogger(ctx).Error("Failed to set queue attributes", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Queue attributes updated", zap.String("queue", req.QueueName))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, SetQueueAttributesResponse{ResponseMetadata: meta})
+	}
+}
+
+func (h *QueueHandler) GetUrl() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req GetQueueUrlRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid getQueueUrl request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		owner := c.Param("accountid")
+		if req.QueueOwnerAWSAccountId != "" {
+			owner = req.QueueOwnerAWSAccountId
+		}
+		result, err := h.svc.GetQueueUrl(ctx, req.QueueName, owner, c.Param("accountid"))
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to get queue url", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, GetQueueUrlResponse{GetQueueUrlResult: result, ResponseMetadata: meta})
+	}
+}
+
+func (h *QueueHandler) Purge() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req PurgeQueueRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid purgeQueue request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.PurgeQueue(ctx, req.QueueName, c.Param("accountid")); err != nil {
+			logs.GetLogger(ctx).Error("Failed to purge queue", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Queue purge success", zap.String("queue", req.QueueName))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, PurgeQueueResponse{ResponseMetadata: meta})
+	}
+}
+
+func (h *QueueHandler) Tag() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req TagQueueRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid tagQueue request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.TagQueue(ctx, req.QueueName, c.Param("accountid"), req.Tags); err != nil {
+			logs.GetLogger(ctx).Error("Failed to tag queue", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, TagQueueResponse{ResponseMetadata: meta})
+	}
+}
+
+func (h *QueueHandler) Untag() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req UntagQueueRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid untagQueue request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.UntagQueue(ctx, req.QueueName, c.Param("accountid"), req.TagKeys); err != nil {
+			logs.GetLogger(ctx).Error("Failed to untag queue", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, TagQueueResponse{ResponseMetadata: meta})
+	}
+}
+
+func (h *QueueHandler) ListTags() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req ListQueueTagsRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid listQueueTags request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		tags, err := h.svc.ListQueueTags(ctx, req.QueueName, c.Param("accountid"))
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to list queue tags", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ListQueueTagsResponse{
+			ListQueueTagsResult: ListQueueTagsResult{Tags: tags}, ResponseMetadata: meta,
+		})
+	}
+}
+
+func (h *QueueHandler) AddPermission() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req AddPermissionRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid addPermission request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.AddPermission(ctx, req.QueueName, c.Param("accountid"), req.Label, req.AWSAccountIds, req.Actions); err != nil {
+			logs.GetLogger(ctx).Error("Failed to add permission", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, PermissionResponse{ResponseMetadata: meta})
+	}
+}
+
+func (h *QueueHandler) RemovePermission() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req RemovePermissionRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid removePermission request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := resolveQueueName(c, &req.QueueName); err != nil {
+			logs.GetLogger(ctx).Error("Queue name does not match the queue URL", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.RemovePermission(ctx, req.QueueName, c.Param("accountid"), req.Label); err != nil {
+			logs.GetLogger(ctx).Error("Failed to remove permission", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, PermissionResponse{ResponseMetadata: meta})
 	}
 }
@@ -1,26 +1,37 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
 
 	"nats/internal/context/logs"
 	"nats/internal/context/metrics"
+	"nats/internal/entity"
+	imiddle "nats/internal/middleware"
+	"nats/internal/service"
 )
 
 type ApiRouter interface {
 	Register(g *echo.Group)
+	RegisterAwsProtocols(e *echo.Echo, m ...echo.MiddlewareFunc)
 }
 
 type apiRouter struct {
 	accountBaseHandlers      map[string]func() echo.HandlerFunc
 	accountQueueBaseHandlers map[string]func() echo.HandlerFunc
+	awsOperations            map[string]AwsOperation
+	queueSvc                 service.QueueService
 }
 
-func NewApiRouter(accountBaseHandlers map[string]func() echo.HandlerFunc, accountQueueBaseHandlers map[string]func() echo.HandlerFunc) ApiRouter {
-	return &apiRouter{accountBaseHandlers: accountBaseHandlers, accountQueueBaseHandlers: accountQueueBaseHandlers}
+func NewApiRouter(accountBaseHandlers map[string]func() echo.HandlerFunc, accountQueueBaseHandlers map[string]func() echo.HandlerFunc,
+	awsOperations map[string]AwsOperation, queueSvc service.QueueService) ApiRouter {
+	return &apiRouter{accountBaseHandlers: accountBaseHandlers, accountQueueBaseHandlers: accountQueueBaseHandlers, awsOperations: awsOperations, queueSvc: queueSvc}
 }
 
 func (r *apiRouter) Register(g *echo.Group) {
@@ -28,15 +39,31 @@ func (r *apiRouter) Register(g *echo.Group) {
 	g.Any("/:accountid/:queueid", r.handleAccountQueueBase)
 }
 
+// RegisterAwsProtocols serves the AWS SQS protocols on /, the endpoint the
+// AWS SDKs and CLI send every operation to. JSON requests name the operation
+// in X-Amz-Target, Query requests in the Action parameter.
+func (r *apiRouter) RegisterAwsProtocols(e *echo.Echo, m ...echo.MiddlewareFunc) {
+	e.Match([]string{http.MethodGet, http.MethodPost}, "/", r.handleAwsProtocol, m...)
+}
+
 func (r *apiRouter) handleAccountBase(c echo.Context) error {
 	logs.GetLogger(c.Request().Context()).Info("handleAccountBase")
-	action := c.QueryParam("Action")
+	action := c.FormValue("Action")
+
+	if err := entity.ValidateAccountId(c.Param("accountid")); err != nil {
+		return r.invalidPath(c, action, err)
+	}
 
 	if handlerFunc, ok := r.accountBaseHandlers[action]; ok {
 		err := handlerFunc()(c)
 		metrics.ApiCallCounter.WithLabelValues(action, strconv.Itoa(c.Response().Status)).Inc()
 		return err
 	}
+	if op, ok := r.awsOperations[action]; ok {
+		err := serveAwsQuery(c, action, op)
+		metrics.ApiCallCounter.WithLabelValues(action, strconv.Itoa(c.Response().Status)).Inc()
+		return err
+	}
 
 	metrics.ApiCallCounter.WithLabelValues(action, "400").Inc()
 	return c.String(http.StatusBadRequest, "invalid Action")
@@ -44,14 +71,102 @@ func (r *apiRouter) handleAccountBase(c echo.Context) error {
 
 func (r *apiRouter) handleAccountQueueBase(c echo.Context) error {
 	logs.GetLogger(c.Request().Context()).Info("handleAccountQueueBase")
-	action := c.QueryParam("Action")
+	action := c.FormValue("Action")
+
+	if err := entity.ValidateAccountId(c.Param("accountid")); err != nil {
+		return r.invalidPath(c, action, err)
+	}
+	if err := entity.ValidateQueueName(c.Param("queueid")); err != nil {
+		return r.invalidPath(c, action, err)
+	}
 
 	if handlerFunc, ok := r.accountQueueBaseHandlers[action]; ok {
+		if err := r.authorizeQueueAction(c, action); err != nil {
+			logs.GetLogger(c.Request().Context()).Warn("Queue action denied", zap.String("action", action), zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			metrics.ApiCallCounter.WithLabelValues(action, strconv.Itoa(errResp.HTTPCode)).Inc()
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
 		err := handlerFunc()(c)
 		metrics.ApiCallCounter.WithLabelValues(action, strconv.Itoa(c.Response().Status)).Inc()
 		return err
 	}
+	if op, ok := r.awsOperations[action]; ok {
+		err := serveAwsQuery(c, action, op)
+		metrics.ApiCallCounter.WithLabelValues(action, strconv.Itoa(c.Response().Status)).Inc()
+		return err
+	}
 
 	metrics.ApiCallCounter.WithLabelValues(action, "400").Inc()
 	return c.String(http.StatusBadRequest, "invalid Action")
 }
+
+func (r *apiRouter) handleAwsProtocol(c echo.Context) error {
+	logs.GetLogger(c.Request().Context()).Info("handleAwsProtocol")
+	req := c.Request()
+
+	if strings.HasPrefix(req.Header.Get(echo.HeaderContentType), AwsJsonContentType) {
+		target := req.Header.Get(HeaderAmzTarget)
+		action, ok := strings.CutPrefix(target, awsJsonTargetPrefix)
+		op, found := r.awsOperations[action]
+		if !ok || !found {
+			err := writeAwsJsonError(c, fmt.Errorf("%w: unknown operation %q", entity.ErrInvalidAction, target))
+			metrics.ApiCallCounter.WithLabelValues(action, strconv.Itoa(c.Response().Status)).Inc()
+			return err
+		}
+
+		err := serveAwsJson(c, action, op)
+		metrics.ApiCallCounter.WithLabelValues(action, strconv.Itoa(c.Response().Status)).Inc()
+		return err
+	}
+
+	action := c.FormValue("Action")
+	op, ok := r.awsOperations[action]
+	if !ok {
+		err := writeAwsQueryError(c, fmt.Errorf("%w: unknown action %q", entity.ErrInvalidAction, action))
+		metrics.ApiCallCounter.WithLabelValues(action, strconv.Itoa(c.Response().Status)).Inc()
+		return err
+	}
+
+	err := serveAwsQuery(c, action, op)
+	metrics.ApiCallCounter.WithLabelValues(action, strconv.Itoa(c.Response().Status)).Inc()
+	return err
+}
+
+// authorizeQueueAction checks a request signed by another account than the
+// queue owner against the queue Policy. AWS operations check their QueueUrl.
+func (r *apiRouter) authorizeQueueAction(c echo.Context, action string) error {
+	signer, ok := imiddle.SignerAccount(c)
+	if !ok {
+		return nil
+	}
+	req := accessRequest(c, signer, queuePolicyActions[action])
+	return r.queueSvc.AuthorizeQueueAction(c.Request().Context(), c.Param("queueid"), c.Param("accountid"), req)
+}
+
+// invalidPath rejects an account id or queue name that cannot address a queue,
+// in XML when the action is a Query protocol one.
+func (r *apiRouter) invalidPath(c echo.Context, action string, err error) error {
+	logs.GetLogger(c.Request().Context()).Warn("Invalid queue path", zap.Error(err))
+	if _, ok := r.awsOperations[action]; ok {
+		err := writeAwsQueryError(c, err)
+		metrics.ApiCallCounter.WithLabelValues(action, strconv.Itoa(c.Response().Status)).Inc()
+		return err
+	}
+	errResp := entity.ErrorResponseOf(err)
+	metrics.ApiCallCounter.WithLabelValues(action, strconv.Itoa(errResp.HTTPCode)).Inc()
+	return c.JSON(errResp.HTTPCode, errResp.Error)
+}
+
+// WriteError writes err in the protocol of the request: AWS JSON, AWS Query for
+// an Action named after an AWS operation, otherwise the ?Action= API format.
+func WriteError(c echo.Context, err error) error {
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), AwsJsonContentType) {
+		return writeAwsJsonError(c, err)
+	}
+	if action := c.FormValue("Action"); action != "" && unicode.IsUpper(rune(action[0])) || c.Path() == "/" {
+		return writeAwsQueryError(c, err)
+	}
+	errResp := entity.ErrorResponseOf(err)
+	return c.JSON(errResp.HTTPCode, errResp.Error)
+}
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"nats/internal/context/logs"
+	"nats/internal/entity"
+	"nats/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type TopicHandler struct {
+	svc service.TopicService
+}
+
+func NewTopicHandler(svc service.TopicService) *TopicHandler {
+	return &TopicHandler{svc: svc}
+}
+
+type CreateTopicRequest struct {
+	Name string `json:"Name" validate:"required"`
+}
+
+type CreateTopicResponse struct {
+	CreateTopicResult entity.Topic            `json:"CreateTopicResult"`
+	ResponseMetadata  entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type TopicRequest struct {
+	TopicArn string `json:"TopicArn" validate:"required"`
+}
+
+type TopicResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type ListTopicsRequest struct {
+	NextToken string `query:"NextToken"`
+}
+
+type ListTopicsResponse struct {
+	ListTopicsResult entity.ListTopicsResult `json:"ListTopicsResult"`
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type SubscribeRequest struct {
+	TopicArn string `json:"TopicArn" validate:"required"`
+	Protocol string `json:"Protocol" validate:"required"`
+	Endpoint string `json:"Endpoint" validate:"required"`
+}
+
+type SubscribeResult struct {
+	SubscriptionArn string `json:"SubscriptionArn"`
+}
+
+type SubscribeResponse struct {
+	SubscribeResult  SubscribeResult         `json:"SubscribeResult"`
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+// UnsubscribeRequest also binds the query string of the UnsubscribeURL of a notification.
+type UnsubscribeRequest struct {
+	SubscriptionArn string `json:"SubscriptionArn" query:"SubscriptionArn" validate:"required"`
+}
+
+type ListSubscriptionsByTopicRequest struct {
+	TopicArn  string `json:"TopicArn" validate:"required"`
+	NextToken string `json:"NextToken"`
+}
+
+type ListSubscriptionsByTopicResponse struct {
+	ListSubscriptionsByTopicResult entity.ListSubscriptionsResult `json:"ListSubscriptionsByTopicResult"`
+	ResponseMetadata               entity.ResponseMetadata        `json:"ResponseMetadata"`
+}
+
+type PublishRequest struct {
+	TopicArn          string                                  `json:"TopicArn" validate:"required"`
+	Message           string                                  `json:"Message" validate:"required"`
+	Subject           string                                  `json:"Subject"`
+	MessageAttributes map[string]entity.MessageAttributeValue `json:"MessageAttributes"`
+}
+
+type PublishResult struct {
+	MessageId string `json:"MessageId"`
+}
+
+type PublishResponse struct {
+	PublishResult    PublishResult           `json:"PublishResult"`
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+func (h *TopicHandler) Create() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req CreateTopicRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid createTopic request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		result, err := h.svc.CreateTopic(ctx, req.Name, c.Param("accountid"))
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to create topic", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+		logs.GetLogger(ctx).Info("Topic creation success", zap.String("topic", req.Name))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, CreateTopicResponse{CreateTopicResult: result, ResponseMetadata: meta})
+	}
+}
+
+func (h *TopicHandler) Delete() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req TopicRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid deleteTopic request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.DeleteTopic(ctx, req.TopicArn, c.Param("accountid")); err != nil {
+			logs.GetLogger(ctx).Error("Failed to delete topic", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, TopicResponse{ResponseMetadata: meta})
+	}
+}
+
+func (h *TopicHandler) List() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req ListTopicsRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid listTopics request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		result, err := h.svc.ListTopics(ctx, c.Param("accountid"), req.NextToken)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Topic list lookup failed", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ListTopicsResponse{ListTopicsResult: result, ResponseMetadata: meta})
+	}
+}
+
+func (h *TopicHandler) Subscribe() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req SubscribeRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid subscribe request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		arn, err := h.svc.Subscribe(ctx, req.TopicArn, c.Param("accountid"), req.Protocol, req.Endpoint)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to subscribe", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, SubscribeResponse{SubscribeResult: SubscribeResult{SubscriptionArn: arn}, ResponseMetadata: meta})
+	}
+}
+
+func (h *TopicHandler) Unsubscribe() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req UnsubscribeRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid unsubscribe request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.Unsubscribe(ctx, req.SubscriptionArn, c.Param("accountid")); err != nil {
+			logs.GetLogger(ctx).Error("Failed to unsubscribe", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, TopicResponse{ResponseMetadata: meta})
+	}
+}
+
+func (h *TopicHandler) ListSubscriptions() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req ListSubscriptionsByTopicRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid listSubscriptionsByTopic request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		result, err := h.svc.ListSubscriptionsByTopic(ctx, req.TopicArn, c.Param("accountid"), req.NextToken)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Subscription list lookup failed", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ListSubscriptionsByTopicResponse{ListSubscriptionsByTopicResult: result, ResponseMetadata: meta})
+	}
+}
+
+func (h *TopicHandler) Publish() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req PublishRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid publish request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		opts := entity.PublishOptions{Subject: req.Subject, MessageAttributes: req.MessageAttributes}
+		id, err := h.svc.Publish(ctx, req.TopicArn, c.Param("accountid"), req.Message, opts)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to publish", zap.Error(err))
+			errResp := entity.ErrorResponseOf(err)
+			return c.JSON(errResp.HTTPCode, errResp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, PublishResponse{PublishResult: PublishResult{MessageId: id}, ResponseMetadata: meta})
+	}
+}
@@ -2,6 +2,7 @@ package valkey
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"nats/pkg/config"
@@ -13,6 +14,23 @@ type ValkeyClient interface {
 	Shutdown(ctx context.Context)
 	GetValue(ctx context.Context, key string) (string, error)
 	SetValueWithTTL(ctx context.Context, key string, value string, ttl time.Duration) error
+	SetValue(ctx context.Context, key string, value string) error
+	SetValueNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error)
+	DeleteValue(ctx context.Context, key string) error
+	IncrWithTTL(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	ListPush(ctx context.Context, key string, value string) error
+	ListRange(ctx context.Context, key string, start, stop int64) ([]string, error)
+	SetAdd(ctx context.Context, key string, member string) error
+	SetRemove(ctx context.Context, key string, member string) error
+	SetMembers(ctx context.Context, key string) ([]string, error)
+	SortedSetAdd(ctx context.Context, key string, member string, score int64) error
+	SortedSetCountFrom(ctx context.Context, key string, min int64) (int64, error)
+	EvalInt(ctx context.Context, script string, keys, args []string) (int64, error)
+}
+
+// IsNil reports whether err is the reply to a lookup of a missing key.
+func IsNil(err error) bool {
+	return valkey.IsValkeyNil(err)
 }
 
 type valkeyClient struct {
@@ -42,3 +60,76 @@ func (v *valkeyClient) GetValue(ctx context.Context, key string) (string, error)
 func (v *valkeyClient) SetValueWithTTL(ctx context.Context, key string, value string, ttl time.Duration) error {
 	return v.client.Do(ctx, v.client.B().Set().Key(key).Value(value).Ex(ttl).Build()).Error()
 }
+
+func (v *valkeyClient) SetValue(ctx context.Context, key string, value string) error {
+	return v.client.Do(ctx, v.client.B().Set().Key(key).Value(value).Build()).Error()
+}
+
+// SetValueNX stores the value only if the key does not exist yet. It reports whether the value was stored.
+func (v *valkeyClient) SetValueNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	err := v.client.Do(ctx, v.client.B().Set().Key(key).Value(value).Nx().Px(ttl).Build()).Error()
+	if valkey.IsValkeyNil(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (v *valkeyClient) DeleteValue(ctx context.Context, key string) error {
+	return v.client.Do(ctx, v.client.B().Del().Key(key).Build()).Error()
+}
+
+// IncrWithTTL increments a counter and (re)sets its expiry.
+func (v *valkeyClient) IncrWithTTL(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	resps := v.client.DoMulti(ctx,
+		v.client.B().Incr().Key(key).Build(),
+		v.client.B().Pexpire().Key(key).Milliseconds(ttl.Milliseconds()).Build(),
+	)
+	if err := resps[1].Error(); err != nil {
+		return 0, err
+	}
+	return resps[0].AsInt64()
+}
+
+func (v *valkeyClient) ListPush(ctx context.Context, key string, value string) error {
+	return v.client.Do(ctx, v.client.B().Lpush().Key(key).Element(value).Build()).Error()
+}
+
+func (v *valkeyClient) ListRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return v.client.Do(ctx, v.client.B().Lrange().Key(key).Start(start).Stop(stop).Build()).AsStrSlice()
+}
+
+func (v *valkeyClient) SetAdd(ctx context.Context, key string, member string) error {
+	return v.client.Do(ctx, v.client.B().Sadd().Key(key).Member(member).Build()).Error()
+}
+
+func (v *valkeyClient) SetRemove(ctx context.Context, key string, member string) error {
+	return v.client.Do(ctx, v.client.B().Srem().Key(key).Member(member).Build()).Error()
+}
+
+func (v *valkeyClient) SetMembers(ctx context.Context, key string) ([]string, error) {
+	return v.client.Do(ctx, v.client.B().Smembers().Key(key).Build()).AsStrSlice()
+}
+
+// SortedSetAdd adds or moves member to score.
+func (v *valkeyClient) SortedSetAdd(ctx context.Context, key string, member string, score int64) error {
+	return v.client.Do(ctx, v.client.B().Zadd().Key(key).ScoreMember().ScoreMember(float64(score), member).Build()).Error()
+}
+
+// SortedSetCountFrom drops the members scored below min and counts the rest.
+func (v *valkeyClient) SortedSetCountFrom(ctx context.Context, key string, min int64) (int64, error) {
+	bound := "(" + strconv.FormatInt(min, 10)
+	resps := v.client.DoMulti(ctx,
+		v.client.B().Zremrangebyscore().Key(key).Min("-inf").Max(bound).Build(),
+		v.client.B().Zcard().Key(key).Build(),
+	)
+	if err := resps[0].Error(); err != nil {
+		return 0, err
+	}
+	return resps[1].AsInt64()
+}
+
+// EvalInt runs a Lua script atomically and returns its integer reply.
+func (v *valkeyClient) EvalInt(ctx context.Context, script string, keys, args []string) (int64, error) {
+	cmd := v.client.B().Eval().Script(script).Numkeys(int64(len(keys))).Key(keys...).Arg(args...).Build()
+	return v.client.Do(ctx, cmd).AsInt64()
+}
@@ -0,0 +1,362 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"nats/internal/context/logs"
+	"nats/internal/entity"
+	"nats/pkg/config"
+)
+
+// AWS Signature Version 4 names.
+const (
+	sigV4Algorithm   = "AWS4-HMAC-SHA256"
+	sigV4Terminator  = "aws4_request"
+	sigV4Service     = "sqs"
+	sigV4DateFormat  = "20060102T150405Z"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+	headerAmzDate    = "X-Amz-Date"
+	headerAmzContent = "X-Amz-Content-Sha256"
+
+	// maxRequestBody bounds the body of the largest valid request: a batch of
+	// entity.MaxMessageSize bytes, percent-encoded in a Query body at up to three
+	// bytes per byte, with room for entry ids and attribute names.
+	maxRequestBody = 4 * entity.MaxMessageSize
+	// maxPresignExpires is the longest X-Amz-Expires a presigned request may carry.
+	maxPresignExpires = 7 * 24 * time.Hour
+	// DefaultMaxClockSkew is the allowed difference between X-Amz-Date and the server clock.
+	DefaultMaxClockSkew = 5 * time.Minute
+)
+
+// signerAccountKey holds the account of the verified signer in the echo context.
+const signerAccountKey = "sigv4.account"
+
+// KeyStore looks up access keys. It returns entity.ErrInvalidClientTokenId for an unknown key.
+type KeyStore interface {
+	GetCredential(ctx context.Context, accessKeyId string) (entity.Credential, error)
+}
+
+type configKeyStore map[string]entity.Credential
+
+// NewConfigKeyStore serves the access keys listed in the config, meant for development.
+func NewConfigKeyStore(keys []config.AccessKeyConfig) KeyStore {
+	store := make(configKeyStore, len(keys))
+	for _, key := range keys {
+		store[key.AccessKeyId] = entity.Credential{AccessKeyId: key.AccessKeyId, SecretAccessKey: key.SecretAccessKey, Account: key.Account}
+	}
+	return store
+}
+
+func (s configKeyStore) GetCredential(_ context.Context, accessKeyId string) (entity.Credential, error) {
+	credential, ok := s[accessKeyId]
+	if !ok {
+		return credential, entity.ErrInvalidClientTokenId
+	}
+	return credential, nil
+}
+
+type SigV4Config struct {
+	Store        KeyStore
+	Region       string
+	MaxClockSkew time.Duration
+	// ErrorHandler writes a rejected request in the protocol of the request.
+	ErrorHandler func(c echo.Context, err error) error
+}
+
+// SigV4 verifies the AWS Signature Version 4 of every request, signed in the
+// Authorization header or presigned in the query string. The signer's account
+// must match the :accountid path parameter of account routes, queue routes
+// leave other accounts to the queue Policy. Handlers read it through SignerAccount.
+func SigV4(cfg SigV4Config) echo.MiddlewareFunc {
+	if cfg.MaxClockSkew == 0 {
+		cfg.MaxClockSkew = DefaultMaxClockSkew
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			account, err := verifySigV4(c.Request(), cfg, time.Now())
+			if err != nil {
+				logs.GetLogger(c.Request().Context()).Warn("Request authentication failed", zap.Error(err))
+				return cfg.ErrorHandler(c, err)
+			}
+			if pathAccount := c.Param("accountid"); pathAccount != "" && pathAccount != account && c.Param("queueid") == "" {
+				err := fmt.Errorf("%w: signed by account %s, not %s", entity.ErrAuthorization, account, pathAccount)
+				logs.GetLogger(c.Request().Context()).Warn("Request authentication failed", zap.Error(err))
+				return cfg.ErrorHandler(c, err)
+			}
+			c.Set(signerAccountKey, account)
+			return next(c)
+		}
+	}
+}
+
+// SignerAccount returns the account of the verified signer of the request.
+// ok is false when authentication is disabled.
+func SignerAccount(c echo.Context) (account string, ok bool) {
+	account, ok = c.Get(signerAccountKey).(string)
+	return account, ok
+}
+
+// sigV4Request is the signature of a request and what it claims to cover.
+type sigV4Request struct {
+	accessKeyId   string
+	scope         string // <date>/<region>/<service>/aws4_request
+	date          string
+	region        string
+	service       string
+	amzDate       string
+	signedHeaders []string
+	signature     string
+	expires       time.Duration // presigned requests only
+	presigned     bool
+}
+
+func verifySigV4(r *http.Request, cfg SigV4Config, now time.Time) (string, error) {
+	sig, err := parseSigV4(r)
+	if err != nil {
+		return "", err
+	}
+	if sig.region != cfg.Region || sig.service != sigV4Service {
+		return "", fmt.Errorf("%w: credential scope %s must use region %s and service %s", entity.ErrIncompleteSignature, sig.scope, cfg.Region, sigV4Service)
+	}
+
+	signedAt, err := time.Parse(sigV4DateFormat, sig.amzDate)
+	if err != nil || sig.amzDate[:8] != sig.date {
+		return "", fmt.Errorf("%w: X-Amz-Date %q does not match the credential scope", entity.ErrIncompleteSignature, sig.amzDate)
+	}
+	if signedAt.After(now.Add(cfg.MaxClockSkew)) {
+		return "", fmt.Errorf("%w: signed at %s", entity.ErrRequestExpired, sig.amzDate)
+	}
+	if sig.presigned {
+		if now.After(signedAt.Add(sig.expires)) {
+			return "", fmt.Errorf("%w: presigned at %s for %s", entity.ErrRequestExpired, sig.amzDate, sig.expires)
+		}
+	} else if signedAt.Before(now.Add(-cfg.MaxClockSkew)) {
+		return "", fmt.Errorf("%w: signed at %s", entity.ErrRequestExpired, sig.amzDate)
+	}
+
+	credential, err := cfg.Store.GetCredential(r.Context(), sig.accessKeyId)
+	if err != nil {
+		return "", err
+	}
+
+	payloadHash, err := sigV4PayloadHash(r, sig.presigned)
+	if err != nil {
+		return "", err
+	}
+
+	stringToSign := sigV4StringToSign(sig.amzDate, sig.scope, canonicalRequest(r, sig.signedHeaders, payloadHash))
+	expected := sigV4Signature(credential.SecretAccessKey, sig.date, sig.region, sig.service, stringToSign)
+	if !hmac.Equal([]byte(expected), []byte(sig.signature)) {
+		return "", entity.ErrSignatureDoesNotMatch
+	}
+	return credential.Account, nil
+}
+
+// parseSigV4 reads the signature from the Authorization header or, for
+// presigned requests, from the X-Amz-* query parameters.
+func parseSigV4(r *http.Request) (sigV4Request, error) {
+	var sig sigV4Request
+
+	if auth := r.Header.Get(echo.HeaderAuthorization); auth != "" {
+		params, ok := strings.CutPrefix(auth, sigV4Algorithm+" ")
+		if !ok {
+			return sig, fmt.Errorf("%w: Authorization must use %s", entity.ErrIncompleteSignature, sigV4Algorithm)
+		}
+		var credential, signedHeaders string
+		for _, param := range strings.Split(params, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			switch key {
+			case "Credential":
+				credential = value
+			case "SignedHeaders":
+				signedHeaders = value
+			case "Signature":
+				sig.signature = value
+			}
+		}
+		sig.amzDate = r.Header.Get(headerAmzDate)
+		return sig, sig.parse(credential, signedHeaders)
+	}
+
+	query := r.URL.Query()
+	if query.Get("X-Amz-Algorithm") == "" {
+		return sig, entity.ErrMissingAuthenticationToken
+	}
+	if query.Get("X-Amz-Algorithm") != sigV4Algorithm {
+		return sig, fmt.Errorf("%w: X-Amz-Algorithm must be %s", entity.ErrIncompleteSignature, sigV4Algorithm)
+	}
+	seconds, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || seconds < 1 || time.Duration(seconds)*time.Second > maxPresignExpires {
+		return sig, fmt.Errorf("%w: X-Amz-Expires must be 1 to %d seconds", entity.ErrIncompleteSignature, int(maxPresignExpires.Seconds()))
+	}
+	sig.presigned = true
+	sig.expires = time.Duration(seconds) * time.Second
+	sig.signature = query.Get("X-Amz-Signature")
+	sig.amzDate = query.Get(headerAmzDate)
+	return sig, sig.parse(query.Get("X-Amz-Credential"), query.Get("X-Amz-SignedHeaders"))
+}
+
+func (sig *sigV4Request) parse(credential, signedHeaders string) error {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[4] != sigV4Terminator {
+		return fmt.Errorf("%w: Credential must be <key>/<date>/<region>/<service>/%s", entity.ErrIncompleteSignature, sigV4Terminator)
+	}
+	sig.accessKeyId, sig.date, sig.region, sig.service = parts[0], parts[1], parts[2], parts[3]
+	sig.scope = strings.Join(parts[1:], "/")
+
+	sig.signedHeaders = strings.Split(signedHeaders, ";")
+	if !slices.Contains(sig.signedHeaders, "host") {
+		return fmt.Errorf("%w: SignedHeaders must include host", entity.ErrIncompleteSignature)
+	}
+	if sig.signature == "" || len(sig.amzDate) != len(sigV4DateFormat) {
+		return fmt.Errorf("%w: Signature and X-Amz-Date are required", entity.ErrIncompleteSignature)
+	}
+	return nil
+}
+
+// sigV4PayloadHash hashes the body and leaves it readable for the handler.
+// X-Amz-Content-Sha256, when sent, must match the body. Only presigned URLs
+// may leave the payload unsigned: a header signature must cover the form body,
+// which carries the Action and parameters of Query requests. For the same
+// reason presigned URLs only work on GET and HEAD requests without a body,
+// whose parameters all come from the signed query string.
+func sigV4PayloadHash(r *http.Request, presigned bool) (string, error) {
+	r.Body = http.MaxBytesReader(nil, r.Body, maxRequestBody)
+
+	declared := r.Header.Get(headerAmzContent)
+	if declared == unsignedPayload && !presigned {
+		return "", fmt.Errorf("%w: %s %s is only accepted on presigned URLs", entity.ErrIncompleteSignature, headerAmzContent, unsignedPayload)
+	}
+	if presigned && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return "", fmt.Errorf("%w: presigned URLs only accept GET and HEAD requests", entity.ErrIncompleteSignature)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return "", fmt.Errorf("%w: request body is larger than %d bytes", entity.ErrInvalidParameter, tooLarge.Limit)
+	}
+	if err != nil {
+		return "", err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if presigned {
+		if len(body) > 0 {
+			return "", fmt.Errorf("%w: a presigned request cannot carry a body", entity.ErrIncompleteSignature)
+		}
+		if declared == "" || declared == unsignedPayload {
+			return unsignedPayload, nil
+		}
+	}
+
+	hash := hashHex(body)
+	if declared != "" && declared != hash {
+		return "", fmt.Errorf("%w: %s does not match the body", entity.ErrSignatureDoesNotMatch, headerAmzContent)
+	}
+	return hash, nil
+}
+
+func canonicalRequest(r *http.Request, signedHeaders []string, payloadHash string) string {
+	var sb strings.Builder
+	sb.WriteString(r.Method)
+	sb.WriteByte('\n')
+	sb.WriteString(uriEncode(r.URL.EscapedPath(), false))
+	sb.WriteByte('\n')
+	sb.WriteString(canonicalQuery(r.URL.Query()))
+	sb.WriteByte('\n')
+	for _, name := range signedHeaders {
+		sb.WriteString(name)
+		sb.WriteByte(':')
+		sb.WriteString(canonicalHeaderValue(r, name))
+		sb.WriteByte('\n')
+	}
+	sb.WriteByte('\n')
+	sb.WriteString(strings.Join(signedHeaders, ";"))
+	sb.WriteByte('\n')
+	sb.WriteString(payloadHash)
+	return sb.String()
+}
+
+func canonicalQuery(query url.Values) string {
+	query.Del("X-Amz-Signature")
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "&")
+}
+
+func canonicalHeaderValue(r *http.Request, name string) string {
+	var values []string
+	switch name {
+	case "host":
+		values = []string{r.Host}
+	case "content-length":
+		values = []string{strconv.FormatInt(r.ContentLength, 10)}
+	default:
+		values = r.Header.Values(name)
+	}
+	for i, value := range values {
+		values[i] = strings.Join(strings.Fields(value), " ")
+	}
+	return strings.Join(values, ",")
+}
+
+// uriEncode percent-encodes everything but the RFC 3986 unreserved characters,
+// and '/' unless encodeSlash.
+func uriEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', b == '/' && !encodeSlash:
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
+
+func sigV4StringToSign(amzDate, scope, canonicalRequest string) string {
+	return sigV4Algorithm + "\n" + amzDate + "\n" + scope + "\n" + hashHex([]byte(canonicalRequest))
+}
+
+func sigV4Signature(secret, date, region, service, stringToSign string) string {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, sigV4Terminator)
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
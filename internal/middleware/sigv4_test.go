@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"nats/internal/entity"
+	"nats/pkg/config"
+)
+
+// TestSigV4Vanilla checks the get-vanilla case of the AWS Signature Version 4 test suite.
+func TestSigV4Vanilla(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "http://example.amazonaws.com/", nil)
+	r.Header.Set(headerAmzDate, "20150830T123600Z")
+
+	canonical := canonicalRequest(r, []string{"host", "x-amz-date"}, hashHex(nil))
+	stringToSign := sigV4StringToSign("20150830T123600Z", "20150830/us-east-1/service/aws4_request", canonical)
+	signature := sigV4Signature("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20150830", "us-east-1", "service", stringToSign)
+	assert.Equal(t, "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31", signature)
+}
+
+var testSigV4Config = SigV4Config{
+	Store: NewConfigKeyStore([]config.AccessKeyConfig{
+		{AccessKeyId: "AKIDLOCALDEV", SecretAccessKey: "local-dev-secret", Account: "accountid"},
+	}),
+	Region:       "kr-west1",
+	MaxClockSkew: DefaultMaxClockSkew,
+}
+
+// signRequest signs r in the Authorization header like the AWS SDKs do.
+func signRequest(r *http.Request, body, secret string, at time.Time) {
+	amzDate := at.UTC().Format(sigV4DateFormat)
+	scope := amzDate[:8] + "/kr-west1/sqs/aws4_request"
+	r.Header.Set(headerAmzDate, amzDate)
+
+	signedHeaders := []string{"content-type", "host", "x-amz-date"}
+	canonical := canonicalRequest(r, signedHeaders, hashHex([]byte(body)))
+	signature := sigV4Signature(secret, amzDate[:8], "kr-west1", "sqs", sigV4StringToSign(amzDate, scope, canonical))
+	r.Header.Set("Authorization", sigV4Algorithm+" Credential=AKIDLOCALDEV/"+scope+", SignedHeaders="+strings.Join(signedHeaders, ";")+", Signature="+signature)
+}
+
+func newSignedRequest(body, secret string, at time.Time) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "http://localhost:8080/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	signRequest(r, body, secret, at)
+	return r
+}
+
+func TestVerifySigV4Header(t *testing.T) {
+	now := time.Now()
+	body := `{"QueueName":"orders"}`
+
+	account, err := verifySigV4(newSignedRequest(body, "local-dev-secret", now), testSigV4Config, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "accountid", account)
+
+	_, err = verifySigV4(newSignedRequest(body, "wrong-secret", now), testSigV4Config, now)
+	assert.ErrorIs(t, err, entity.ErrSignatureDoesNotMatch)
+
+	_, err = verifySigV4(newSignedRequest(body, "local-dev-secret", now.Add(-10*time.Minute)), testSigV4Config, now)
+	assert.ErrorIs(t, err, entity.ErrRequestExpired)
+
+	tampered := newSignedRequest(body, "local-dev-secret", now)
+	tampered.Body = http.NoBody
+	_, err = verifySigV4(tampered, testSigV4Config, now)
+	assert.ErrorIs(t, err, entity.ErrSignatureDoesNotMatch)
+
+	_, err = verifySigV4(httptest.NewRequest(http.MethodPost, "/", nil), testSigV4Config, now)
+	assert.ErrorIs(t, err, entity.ErrMissingAuthenticationToken)
+}
+
+func TestVerifySigV4UnsignedPayload(t *testing.T) {
+	now := time.Now()
+	body := "Action=SendMessage&QueueUrl=http%3A%2F%2Flocalhost%3A8080%2Fv1%2Faccountid%2Forders&MessageBody=hello"
+
+	// Signed over UNSIGNED-PAYLOAD, the signature would hold for any body
+	tampered := httptest.NewRequest(http.MethodPost, "http://localhost:8080/", strings.NewReader(body))
+	tampered.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tampered.Header.Set(headerAmzContent, unsignedPayload)
+	amzDate := now.UTC().Format(sigV4DateFormat)
+	scope := amzDate[:8] + "/kr-west1/sqs/aws4_request"
+	tampered.Header.Set(headerAmzDate, amzDate)
+	signedHeaders := []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	canonical := canonicalRequest(tampered, signedHeaders, unsignedPayload)
+	signature := sigV4Signature("local-dev-secret", amzDate[:8], "kr-west1", "sqs", sigV4StringToSign(amzDate, scope, canonical))
+	tampered.Header.Set("Authorization", sigV4Algorithm+" Credential=AKIDLOCALDEV/"+scope+", SignedHeaders="+strings.Join(signedHeaders, ";")+", Signature="+signature)
+	tampered.Body = io.NopCloser(strings.NewReader("Action=DeleteQueue&QueueUrl=http%3A%2F%2Flocalhost%3A8080%2Fv1%2Faccountid%2Forders"))
+
+	_, err := verifySigV4(tampered, testSigV4Config, now)
+	assert.ErrorIs(t, err, entity.ErrIncompleteSignature)
+}
+
+func TestVerifySigV4BodyLimit(t *testing.T) {
+	now := time.Now()
+	body := strings.Repeat("a", maxRequestBody+1)
+
+	_, err := verifySigV4(newSignedRequest(body, "local-dev-secret", now), testSigV4Config, now)
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+
+	// Unknown keys are rejected before the body is read
+	unknown := newSignedRequest(body, "local-dev-secret", now)
+	unknown.Header.Set("Authorization", strings.Replace(unknown.Header.Get("Authorization"), "AKIDLOCALDEV", "AKIDUNKNOWN", 1))
+	_, err = verifySigV4(unknown, testSigV4Config, now)
+	assert.ErrorIs(t, err, entity.ErrInvalidClientTokenId)
+}
+
+// presignRequest returns a request to a URL presigned for method with the query.
+func presignRequest(method string, query url.Values, body string, at time.Time) *http.Request {
+	amzDate := at.UTC().Format(sigV4DateFormat)
+	scope := amzDate[:8] + "/kr-west1/sqs/aws4_request"
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", "AKIDLOCALDEV/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", "60")
+	query.Set("X-Amz-SignedHeaders", "host")
+	r := httptest.NewRequest(method, "http://localhost:8080/?"+query.Encode(), nil)
+
+	canonical := canonicalRequest(r, []string{"host"}, unsignedPayload)
+	query.Set("X-Amz-Signature", sigV4Signature("local-dev-secret", amzDate[:8], "kr-west1", "sqs", sigV4StringToSign(amzDate, scope, canonical)))
+	r = httptest.NewRequest(method, "http://localhost:8080/?"+query.Encode(), strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return r
+}
+
+func TestVerifySigV4Presigned(t *testing.T) {
+	now := time.Now()
+	r := presignRequest(http.MethodGet, url.Values{"Action": {"ListQueues"}}, "", now)
+
+	account, err := verifySigV4(r, testSigV4Config, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "accountid", account)
+
+	_, err = verifySigV4(r, testSigV4Config, now.Add(2*time.Minute))
+	assert.ErrorIs(t, err, entity.ErrRequestExpired)
+}
+
+func TestVerifySigV4PresignedBody(t *testing.T) {
+	now := time.Now()
+	query := url.Values{"Action": {"ReceiveMessage"}, "QueueUrl": {"http://localhost:8080/v1/accountid/orders"}}
+	override := "Action=DeleteQueue&QueueUrl=http%3A%2F%2Flocalhost%3A8080%2Fv1%2Faccountid%2Forders"
+
+	// A form body would take priority over the signed query parameters
+	post := presignRequest(http.MethodPost, query, override, now)
+	_, err := verifySigV4(post, testSigV4Config, now)
+	assert.ErrorIs(t, err, entity.ErrIncompleteSignature)
+
+	get := presignRequest(http.MethodGet, query, override, now)
+	_, err = verifySigV4(get, testSigV4Config, now)
+	assert.ErrorIs(t, err, entity.ErrIncompleteSignature)
+
+	_, err = verifySigV4(presignRequest(http.MethodGet, query, "", now), testSigV4Config, now)
+	assert.NoError(t, err)
+}
@@ -2,20 +2,43 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
+	"nats/internal/entity"
 	"nats/internal/infra/nats"
 
+	gonats "github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 )
 
 type NatsRepo interface {
-	SendMessage(ctx context.Context, message, subject string) (*jetstream.PubAck, error)
-	SendAsyncMessage(ctx context.Context, message, subject string) (jetstream.PubAckFuture, error)
+	SendMessage(ctx context.Context, message, subject string, header gonats.Header) (*jetstream.PubAck, error)
+	SendAsyncMessage(ctx context.Context, message, subject string, header gonats.Header) (jetstream.PubAckFuture, error)
+	PublishBatch(ctx context.Context, msgs []*gonats.Msg) ([]*jetstream.PubAck, []error)
+	FetchMessages(ctx context.Context, cons jetstream.Consumer, batch int, wait time.Duration) ([]jetstream.Msg, error)
+	AckMessage(ctx context.Context, handle entity.ReceiptHandle, ackType string) error
+	NakMessage(ctx context.Context, handle entity.ReceiptHandle, delay time.Duration) error
+	GetMessage(ctx context.Context, stream string, seq uint64) (*jetstream.RawStreamMsg, error)
+	GetNextMessage(ctx context.Context, stream, subject string, seq uint64) (*jetstream.RawStreamMsg, error)
+	DeleteMessage(ctx context.Context, stream string, seq uint64) error
 
-	CreateStream(ctx context.Context, name string) (jetstream.Stream, error)
+	CreateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error)
+	UpdateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error)
+	GetStream(ctx context.Context, name string) (jetstream.Stream, error)
 	DeleteStream(ctx context.Context, name string) error
-	ListStreamNames(ctx context.Context) (<-chan string, error)
+	PurgeStream(ctx context.Context, name string) error
+	ListStreams(ctx context.Context, subject string, offset int) (<-chan *jetstream.StreamInfo, func() error, error)
+
+	GetOrCreateConsumer(ctx context.Context, stream, name string) (jetstream.Consumer, error)
+	GetConsumer(ctx context.Context, stream, name string) (jetstream.Consumer, error)
+	CreateConsumer(ctx context.Context, stream string, cfg jetstream.ConsumerConfig) (jetstream.Consumer, error)
+	ListConsumers(ctx context.Context, stream string) (<-chan *jetstream.ConsumerInfo, error)
+	UpdateConsumerAckWait(ctx context.Context, stream, name string, ackWait time.Duration) error
+	DeleteConsumer(ctx context.Context, stream, name string) error
 }
 
 type natsRepo struct {
@@ -26,26 +49,151 @@ func NewNatsRepo(jsClient nats.JetStreamPool) NatsRepo {
 	return &natsRepo{jsClient: jsClient}
 }
 
-func (s *natsRepo) SendMessage(ctx context.Context, message, subject string) (*jetstream.PubAck, error) {
+func (s *natsRepo) SendMessage(ctx context.Context, message, subject string, header gonats.Header) (*jetstream.PubAck, error) {
 	js, err := s.jsClient.GetJetStream(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return js.Publish(ctx, subject, []byte(message))
+	return js.PublishMsg(ctx, &gonats.Msg{Subject: subject, Data: []byte(message), Header: header})
 }
 
-func (s *natsRepo) SendAsyncMessage(ctx context.Context, message, subject string) (jetstream.PubAckFuture, error) {
+func (s *natsRepo) SendAsyncMessage(ctx context.Context, message, subject string, header gonats.Header) (jetstream.PubAckFuture, error) {
 	js, err := s.jsClient.GetJetStream(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return js.PublishAsync(subject, []byte(message))
+	return js.PublishMsgAsync(&gonats.Msg{Subject: subject, Data: []byte(message), Header: header})
+}
+
+// PublishBatch publishes the messages asynchronously on one pooled JetStream
+// context and waits for every ack. Publishing in order on a single connection
+// keeps the stream order of the batch. Results are reported per message.
+func (s *natsRepo) PublishBatch(ctx context.Context, msgs []*gonats.Msg) ([]*jetstream.PubAck, []error) {
+	acks := make([]*jetstream.PubAck, len(msgs))
+	errs := make([]error, len(msgs))
+
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return acks, errs
+	}
+
+	futures := make([]jetstream.PubAckFuture, len(msgs))
+	for i, msg := range msgs {
+		futures[i], errs[i] = js.PublishMsgAsync(msg)
+	}
+	for i, future := range futures {
+		if future == nil {
+			continue
+		}
+		select {
+		case ack := <-future.Ok():
+			acks[i] = ack
+		case err := <-future.Err():
+			errs[i] = err
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+		}
+	}
+	return acks, errs
+}
+
+// longPollInterval bounds a single pull request while long polling, so a
+// cancelled request never leaves a server-side pull waiting for long. Each pull
+// is a short-lived inbox subscription multiplexed on a shared pooled connection.
+const longPollInterval = time.Second
+
+// FetchMessages pulls up to batch messages from the durable consumer. With a
+// positive wait it long polls: it returns as soon as one message arrives, when
+// wait elapses, or when ctx is cancelled.
+func (s *natsRepo) FetchMessages(ctx context.Context, cons jetstream.Consumer, batch int, wait time.Duration) ([]jetstream.Msg, error) {
+	msgs, err := fetchNoWait(cons, batch)
+	if len(msgs) > 0 || err != nil || wait <= 0 {
+		return msgs, err
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		remaining := time.Until(deadline)
+		if remaining < 10*time.Millisecond {
+			return msgs, nil
+		}
+
+		// Wait for the first message only, then take whatever else is ready
+		msgBatch, err := cons.Fetch(1, jetstream.FetchMaxWait(min(remaining, longPollInterval)))
+		if err != nil {
+			return nil, err
+		}
+		for msg := range msgBatch.Messages() {
+			msgs = append(msgs, msg)
+		}
+		if err := msgBatch.Error(); err != nil {
+			return releaseMessages(msgs, err)
+		}
+		if len(msgs) == 0 {
+			continue
+		}
+
+		if batch > 1 {
+			rest, err := fetchNoWait(cons, batch-1)
+			msgs = append(msgs, rest...)
+			if err != nil {
+				return releaseMessages(msgs, err)
+			}
+		}
+		// The client went away while messages were in flight; hand them back
+		if err := ctx.Err(); err != nil {
+			return releaseMessages(msgs, err)
+		}
+		return msgs, nil
+	}
+}
+
+func fetchNoWait(cons jetstream.Consumer, batch int) ([]jetstream.Msg, error) {
+	msgBatch, err := cons.FetchNoWait(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]jetstream.Msg, 0, batch)
+	for msg := range msgBatch.Messages() {
+		msgs = append(msgs, msg)
+	}
+	return msgs, msgBatch.Error()
+}
+
+// releaseMessages NAKs messages that will not be returned to the caller so they become visible again.
+func releaseMessages(msgs []jetstream.Msg, err error) ([]jetstream.Msg, error) {
+	for _, msg := range msgs {
+		_ = msg.Nak()
+	}
+	return nil, err
 }
 
-func (s *natsRepo) CreateStream(ctx context.Context, name string) (jetstream.Stream, error) {
-	streamCfg := jetstream.StreamConfig{
+// FifoDeduplicationWindow is the SQS deduplication interval of a FIFO queue,
+// applied through Nats-Msg-Id.
+const FifoDeduplicationWindow = 5 * time.Minute
+
+// DefaultMaxAge is the retention of a new queue stream, matching the SQS
+// default.
+const DefaultMaxAge = 96 * time.Hour
+
+// MessageHeaderAllowance is the room a queue stream leaves above the
+// MaximumMessageSize of the queue for the headers carrying the message id and
+// the JSON-encoded message attributes.
+const MessageHeaderAllowance = entity.MaxMessageSize / 2
+
+// NewStreamConfig returns the configuration of a new queue stream. Queue
+// attributes are applied on top of it before CreateStream.
+func NewStreamConfig(name, subject string, metadata map[string]string) jetstream.StreamConfig {
+	return jetstream.StreamConfig{
 		Name:              name,
-		Subjects:          []string{name},
+		Subjects:          []string{subject},
 		Storage:           jetstream.FileStorage,
 		Replicas:          1,
 		Retention:         jetstream.LimitsPolicy,
@@ -53,19 +201,56 @@ func (s *natsRepo) CreateStream(ctx context.Context, name string) (jetstream.Str
 		MaxMsgs:           -1,
 		MaxMsgsPerSubject: -1,
 		MaxBytes:          -1,
-		MaxAge:            96 * time.Hour,
-		MaxMsgSize:        262144,
-		Duplicates:        0,
+		MaxAge:            DefaultMaxAge,
+		MaxMsgSize:        entity.MaxMessageSize + MessageHeaderAllowance,
 		AllowRollup:       false,
 		DenyDelete:        false,
 		DenyPurge:         false,
+		Metadata:          metadata,
+	}
+}
+
+// NewTopicStreamConfig returns the configuration of a new topic stream. A
+// message is kept until every subscription consumer acknowledged it, so a
+// message published without subscriptions is not stored at all.
+func NewTopicStreamConfig(name, subject string, metadata map[string]string) jetstream.StreamConfig {
+	return jetstream.StreamConfig{
+		Name:              name,
+		Subjects:          []string{subject},
+		Storage:           jetstream.FileStorage,
+		Replicas:          1,
+		Retention:         jetstream.InterestPolicy,
+		Discard:           jetstream.DiscardOld,
+		MaxMsgs:           -1,
+		MaxMsgsPerSubject: -1,
+		MaxBytes:          -1,
+		MaxAge:            DefaultMaxAge,
+		Metadata:          metadata,
+	}
+}
+
+func (s *natsRepo) CreateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
 	}
+	return js.CreateStream(ctx, cfg)
+}
 
+func (s *natsRepo) UpdateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
 	js, err := s.jsClient.GetJetStream(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return js.CreateStream(ctx, streamCfg)
+	return js.UpdateStream(ctx, cfg)
+}
+
+func (s *natsRepo) GetStream(ctx context.Context, name string) (jetstream.Stream, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return js.Stream(ctx, name)
 }
 
 func (s *natsRepo) DeleteStream(ctx context.Context, name string) error {
@@ -76,11 +261,274 @@ func (s *natsRepo) DeleteStream(ctx context.Context, name string) error {
 	return js.DeleteStream(ctx, name)
 }
 
-func (s *natsRepo) ListStreamNames(ctx context.Context) (<-chan string, error) {
+// PurgeStream removes every message of the stream and keeps its configuration.
+func (s *natsRepo) PurgeStream(ctx context.Context, name string) error {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return err
+	}
+	str, err := js.Stream(ctx, name)
+	if err != nil {
+		return err
+	}
+	return str.Purge(ctx)
+}
+
+// streamListRequest and streamListResponse are the paged STREAM.LIST API. It is
+// called directly because the jetstream lister always starts at the first stream.
+type streamListRequest struct {
+	Offset  int    `json:"offset"`
+	Subject string `json:"subject,omitempty"`
+}
+
+type streamListResponse struct {
+	Error   *jetstream.APIError     `json:"error,omitempty"`
+	Total   int                     `json:"total"`
+	Streams []*jetstream.StreamInfo `json:"streams"`
+}
+
+// ListStreams lists the streams capturing subject with their configuration, so
+// queues can be filtered on the attributes and tags kept in the stream metadata.
+// Streams arrive in name order, one JetStream page at a time, starting with the
+// stream at position offset; cancel ctx to stop early. Once the channel is
+// closed, the returned func reports the error that ended the listing early.
+func (s *natsRepo) ListStreams(ctx context.Context, subject string, offset int) (<-chan *jetstream.StreamInfo, func() error, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts := js.Options()
+	apiSubject := jetstream.DefaultAPIPrefix + "STREAM.LIST"
+	if opts.APIPrefix != "" {
+		apiSubject = opts.APIPrefix + "STREAM.LIST"
+	}
+
+	var listErr error
+	infoCh := make(chan *jetstream.StreamInfo)
+	go func() {
+		defer close(infoCh)
+		for {
+			page, err := s.listStreamPage(ctx, js, apiSubject, streamListRequest{Offset: offset, Subject: subject}, opts.DefaultTimeout)
+			if err != nil {
+				listErr = fmt.Errorf("list streams from %d: %w", offset, err)
+				return
+			}
+			if len(page.Streams) == 0 {
+				return
+			}
+			for _, info := range page.Streams {
+				select {
+				case infoCh <- info:
+				case <-ctx.Done():
+					listErr = ctx.Err()
+					return
+				}
+			}
+			offset += len(page.Streams)
+			if offset >= page.Total {
+				return
+			}
+		}
+	}()
+	return infoCh, func() error { return listErr }, nil
+}
+
+func (s *natsRepo) listStreamPage(ctx context.Context, js jetstream.JetStream, apiSubject string, req streamListRequest, timeout time.Duration) (streamListResponse, error) {
+	var resp streamListResponse
+	body, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+	if _, ok := ctx.Deadline(); !ok && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	msg, err := js.Conn().RequestWithContext(ctx, apiSubject, body)
+	if err != nil {
+		return resp, err
+	}
+	if err := json.Unmarshal(msg.Data, &resp); err != nil {
+		return resp, err
+	}
+	if resp.Error != nil {
+		return resp, resp.Error
+	}
+	return resp, nil
+}
+
+// JetStream ack payloads sent on the ack subject of a delivered message.
+const (
+	AckAck      = "+ACK"
+	AckNak      = "-NAK"
+	AckProgress = "+WPI"
+	AckTerm     = "+TERM"
+)
+
+// AckMessage sends an acknowledgement for the delivery described by the receipt handle.
+func (s *natsRepo) AckMessage(ctx context.Context, handle entity.ReceiptHandle, ackType string) error {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return err
+	}
+	return js.Conn().Publish(ackSubject(handle), []byte(ackType))
+}
+
+// NakMessage makes the delivery visible again after delay. The message stays
+// pending on the consumer until then, so the receipt handle can still ack it.
+func (s *natsRepo) NakMessage(ctx context.Context, handle entity.ReceiptHandle, delay time.Duration) error {
+	if delay <= 0 {
+		return s.AckMessage(ctx, handle, AckNak)
+	}
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("%s {\"delay\": %d}", AckNak, delay.Nanoseconds())
+	return js.Conn().Publish(ackSubject(handle), []byte(body))
+}
+
+// GetMessage reads a single message of the stream by sequence.
+func (s *natsRepo) GetMessage(ctx context.Context, stream string, seq uint64) (*jetstream.RawStreamMsg, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	str, err := js.Stream(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+	return str.GetMsg(ctx, seq)
+}
+
+// GetNextMessage reads the first message on subject whose sequence is at least seq.
+func (s *natsRepo) GetNextMessage(ctx context.Context, stream, subject string, seq uint64) (*jetstream.RawStreamMsg, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	str, err := js.Stream(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+	return str.GetMsg(ctx, seq, jetstream.WithGetMsgSubject(subject))
+}
+
+// DeleteMessage removes a message from the stream. It returns jetstream.ErrMsgDeleteUnsuccessful
+// when the sequence is no longer stored.
+func (s *natsRepo) DeleteMessage(ctx context.Context, stream string, seq uint64) error {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return err
+	}
+	str, err := js.Stream(ctx, stream)
+	if err != nil {
+		return err
+	}
+	return str.DeleteMsg(ctx, seq)
+}
+
+// ackSubject rebuilds the JetStream ack reply subject ($JS.ACK.<stream>.<consumer>.<delivered>.<sseq>.<cseq>.<ts>.<pending>).
+func ackSubject(handle entity.ReceiptHandle) string {
+	return fmt.Sprintf("$JS.ACK.%s.%s.%d.%d.%d.%d.0",
+		handle.Stream, handle.Consumer, handle.NumDelivered, handle.StreamSeq, handle.ConsumerSeq, handle.Timestamp)
+}
+
+// GetOrCreateConsumer looks up the durable pull consumer of a stream and creates it on first use.
+func (s *natsRepo) GetOrCreateConsumer(ctx context.Context, stream, name string) (jetstream.Consumer, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cons, err := js.Consumer(ctx, stream, name)
+	if !errors.Is(err, jetstream.ErrConsumerNotFound) {
+		return cons, err
+	}
+
+	str, err := js.Stream(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	// The queue VisibilityTimeout is the consumer AckWait
+	ackWait := entity.DefaultVisibilityTimeout
+	metaKey := entity.QueueMetadataKey(entity.AttrVisibilityTimeout)
+	if sec, err := strconv.Atoi(str.CachedInfo().Config.Metadata[metaKey]); err == nil {
+		ackWait = time.Duration(sec) * time.Second
+	}
+
+	consumerCfg := jetstream.ConsumerConfig{
+		Durable:       name,
+		DeliverPolicy: jetstream.DeliverAllPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       ackWait,
+		MaxDeliver:    -1,
+	}
+	return str.CreateOrUpdateConsumer(ctx, consumerCfg)
+}
+
+func (s *natsRepo) GetConsumer(ctx context.Context, stream, name string) (jetstream.Consumer, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return js.Consumer(ctx, stream, name)
+}
+
+// CreateConsumer creates a durable consumer, failing if one with the same name exists.
+func (s *natsRepo) CreateConsumer(ctx context.Context, stream string, cfg jetstream.ConsumerConfig) (jetstream.Consumer, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return js.CreateConsumer(ctx, stream, cfg)
+}
+
+// ListConsumers lists the consumers of a stream with their configuration.
+func (s *natsRepo) ListConsumers(ctx context.Context, stream string) (<-chan *jetstream.ConsumerInfo, error) {
 	js, err := s.jsClient.GetJetStream(ctx)
 	if err != nil {
 		return nil, err
 	}
-	lister := js.StreamNames(ctx)
-	return lister.Name(), nil
+	str, err := js.Stream(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+	return str.ListConsumers(ctx).Info(), nil
+}
+
+// UpdateConsumerAckWait applies a new queue VisibilityTimeout to an existing
+// consumer. A consumer that does not exist yet picks it up on creation.
+func (s *natsRepo) UpdateConsumerAckWait(ctx context.Context, stream, name string, ackWait time.Duration) error {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return err
+	}
+	cons, err := js.Consumer(ctx, stream, name)
+	if errors.Is(err, jetstream.ErrConsumerNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	consumerCfg := cons.CachedInfo().Config
+	consumerCfg.AckWait = ackWait
+	_, err = js.UpdateConsumer(ctx, stream, consumerCfg)
+	return err
+}
+
+// DeleteConsumer drops a consumer and its delivery state. A consumer that does
+// not exist is not an error.
+func (s *natsRepo) DeleteConsumer(ctx context.Context, stream, name string) error {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return err
+	}
+	err = js.DeleteConsumer(ctx, stream, name)
+	if errors.Is(err, jetstream.ErrConsumerNotFound) {
+		return nil
+	}
+	return err
 }
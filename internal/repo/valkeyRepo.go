@@ -3,9 +3,11 @@ package repo
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"nats/internal/context/logs"
 	"nats/internal/entity"
 	"nats/internal/infra/valkey"
+	"strconv"
 	"time"
 
 	"go.uber.org/zap"
@@ -14,6 +16,32 @@ import (
 type ValkeyRepo interface {
 	StoreAckResult(ctx context.Context, id string, result entity.AckResult) error
 	GetAckStatus(ctx context.Context, id string) (string, error)
+
+	AddMoveTask(ctx context.Context, task entity.MessageMoveTaskRecord) error
+	StoreMoveTask(ctx context.Context, task entity.MessageMoveTaskRecord) error
+	CheckpointMoveTask(ctx context.Context, task entity.MessageMoveTaskRecord) (bool, error)
+	CancelMoveTask(ctx context.Context, taskHandle string) (entity.MessageMoveTaskRecord, error)
+	GetMoveTask(ctx context.Context, taskHandle string) (entity.MessageMoveTaskRecord, error)
+	ListMoveTasks(ctx context.Context, sourceArn string, max int) ([]entity.MessageMoveTaskRecord, error)
+	ListRunningMoveTasks(ctx context.Context) ([]string, error)
+	FinishMoveTask(ctx context.Context, task entity.MessageMoveTaskRecord) error
+	AcquireMoveTaskLease(ctx context.Context, taskHandle, owner string, ttl time.Duration) (bool, error)
+	ReleaseMoveTaskLease(ctx context.Context, taskHandle string) error
+
+	AddReceive(ctx context.Context, stream string, seq uint64, ttl time.Duration) (uint64, error)
+	SetCurrentDelivery(ctx context.Context, stream string, seq, consumerSeq uint64, ttl time.Duration) error
+	GetCurrentDelivery(ctx context.Context, stream string, seq uint64) (uint64, error)
+	ClearCurrentDelivery(ctx context.Context, stream string, seq uint64) error
+	AddDelayedMessage(ctx context.Context, stream string, seq uint64, visibleAt time.Time) error
+	CountDelayedMessages(ctx context.Context, stream string) (int64, error)
+	ClearDelayedMessages(ctx context.Context, stream string) error
+
+	AcquirePurgeLock(ctx context.Context, stream string, ttl time.Duration) (bool, error)
+	ReleasePurgeLock(ctx context.Context, stream string) error
+	AcquireQueueLock(ctx context.Context, stream, owner string, ttl time.Duration) (bool, error)
+	ReleaseQueueLock(ctx context.Context, stream, owner string) error
+
+	GetCredential(ctx context.Context, accessKeyId string) (entity.Credential, error)
 }
 
 type valkeyRepo struct {
@@ -40,3 +68,273 @@ func (s *valkeyRepo) StoreAckResult(ctx context.Context, id string, result entit
 func (s *valkeyRepo) GetAckStatus(ctx context.Context, id string) (string, error) {
 	return s.valkeyClient.GetValue(ctx, id)
 }
+
+// Message move task keys. Task records have no TTL so a redrive survives API restarts.
+const (
+	moveTaskKeyPrefix    = "movetask:"
+	moveTaskSourcePrefix = "movetask:source:"
+	moveTaskLeasePrefix  = "movetask:lease:"
+	moveTaskRunningKey   = "movetask:running"
+)
+
+// AddMoveTask stores a new task and indexes it by source queue and as running.
+func (s *valkeyRepo) AddMoveTask(ctx context.Context, task entity.MessageMoveTaskRecord) error {
+	if err := s.StoreMoveTask(ctx, task); err != nil {
+		return err
+	}
+	if err := s.valkeyClient.ListPush(ctx, moveTaskSourcePrefix+task.SourceArn, task.TaskHandle); err != nil {
+		return err
+	}
+	return s.valkeyClient.SetAdd(ctx, moveTaskRunningKey, task.TaskHandle)
+}
+
+func (s *valkeyRepo) StoreMoveTask(ctx context.Context, task entity.MessageMoveTaskRecord) error {
+	bytes, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	return s.valkeyClient.SetValue(ctx, moveTaskKeyPrefix+task.TaskHandle, string(bytes))
+}
+
+// storeUnlessStatusScript stores ARGV[1] unless the stored task has the status ARGV[2].
+const storeUnlessStatusScript = `
+local stored = redis.call('GET', KEYS[1])
+if stored and cjson.decode(stored)['Status'] == ARGV[2] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1])
+return 1`
+
+// CheckpointMoveTask stores the progress of a running task. It reports false,
+// and stores nothing, once a cancellation was requested through any instance.
+func (s *valkeyRepo) CheckpointMoveTask(ctx context.Context, task entity.MessageMoveTaskRecord) (bool, error) {
+	bytes, err := json.Marshal(task)
+	if err != nil {
+		return false, err
+	}
+	stored, err := s.valkeyClient.EvalInt(ctx, storeUnlessStatusScript,
+		[]string{moveTaskKeyPrefix + task.TaskHandle}, []string{string(bytes), entity.MoveTaskCancelling})
+	return stored == 1, err
+}
+
+// compareAndSetScript replaces the value ARGV[1] of the key with ARGV[2].
+const compareAndSetScript = `
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2])
+return 1`
+
+// moveTaskCancelAttempts bounds the retries of a cancellation racing the checkpoints of the task.
+const moveTaskCancelAttempts = 5
+
+// CancelMoveTask marks a running task CANCELLING and returns it. The owning
+// instance stops at its next checkpoint. A task that is not running fails
+// with entity.ErrMoveTaskNotRunning.
+func (s *valkeyRepo) CancelMoveTask(ctx context.Context, taskHandle string) (entity.MessageMoveTaskRecord, error) {
+	key := moveTaskKeyPrefix + taskHandle
+	for range moveTaskCancelAttempts {
+		var task entity.MessageMoveTaskRecord
+		stored, err := s.valkeyClient.GetValue(ctx, key)
+		if err != nil {
+			return task, err
+		}
+		if err := json.Unmarshal([]byte(stored), &task); err != nil {
+			return task, err
+		}
+		if task.Status != entity.MoveTaskRunning {
+			return task, entity.ErrMoveTaskNotRunning
+		}
+
+		task.Status = entity.MoveTaskCancelling
+		bytes, err := json.Marshal(task)
+		if err != nil {
+			return task, err
+		}
+		// A checkpoint changed the task in between; read it again
+		swapped, err := s.valkeyClient.EvalInt(ctx, compareAndSetScript, []string{key}, []string{stored, string(bytes)})
+		if err != nil || swapped == 1 {
+			return task, err
+		}
+	}
+	return entity.MessageMoveTaskRecord{}, fmt.Errorf("cancel message move task %s: too many concurrent updates", taskHandle)
+}
+
+func (s *valkeyRepo) GetMoveTask(ctx context.Context, taskHandle string) (entity.MessageMoveTaskRecord, error) {
+	var task entity.MessageMoveTaskRecord
+	jsonStr, err := s.valkeyClient.GetValue(ctx, moveTaskKeyPrefix+taskHandle)
+	if err != nil {
+		return task, err
+	}
+	err = json.Unmarshal([]byte(jsonStr), &task)
+	return task, err
+}
+
+// ListMoveTasks returns the most recent tasks of a source queue, newest first.
+func (s *valkeyRepo) ListMoveTasks(ctx context.Context, sourceArn string, max int) ([]entity.MessageMoveTaskRecord, error) {
+	handles, err := s.valkeyClient.ListRange(ctx, moveTaskSourcePrefix+sourceArn, 0, int64(max-1))
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]entity.MessageMoveTaskRecord, 0, len(handles))
+	for _, handle := range handles {
+		task, err := s.GetMoveTask(ctx, handle)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Failed to load message move task", zap.String("taskHandle", handle), zap.Error(err))
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func (s *valkeyRepo) ListRunningMoveTasks(ctx context.Context) ([]string, error) {
+	return s.valkeyClient.SetMembers(ctx, moveTaskRunningKey)
+}
+
+// FinishMoveTask stores the final state and drops the task from the running set.
+func (s *valkeyRepo) FinishMoveTask(ctx context.Context, task entity.MessageMoveTaskRecord) error {
+	if err := s.StoreMoveTask(ctx, task); err != nil {
+		return err
+	}
+	return s.valkeyClient.SetRemove(ctx, moveTaskRunningKey, task.TaskHandle)
+}
+
+// claimScript stores ARGV[1] for ARGV[2] milliseconds unless the key holds another value.
+const claimScript = `
+local current = redis.call('GET', KEYS[1])
+if current and current ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1`
+
+// AcquireMoveTaskLease makes owner the only instance running the task until the lease expires.
+// The current owner calls it again to extend the lease.
+func (s *valkeyRepo) AcquireMoveTaskLease(ctx context.Context, taskHandle, owner string, ttl time.Duration) (bool, error) {
+	acquired, err := s.valkeyClient.EvalInt(ctx, claimScript,
+		[]string{moveTaskLeasePrefix + taskHandle}, []string{owner, strconv.FormatInt(ttl.Milliseconds(), 10)})
+	return acquired == 1, err
+}
+
+func (s *valkeyRepo) ReleaseMoveTaskLease(ctx context.Context, taskHandle string) error {
+	return s.valkeyClient.DeleteValue(ctx, moveTaskLeasePrefix+taskHandle)
+}
+
+// receivedPrefix counts how often a message was handed out to a client.
+// JetStream also redelivers messages that never reach a client: still delayed,
+// an older message of the FIFO group in flight, or a visibility change.
+const receivedPrefix = "received:"
+
+func receivedKey(stream string, seq uint64) string {
+	return receivedPrefix + stream + ":" + strconv.FormatUint(seq, 10)
+}
+
+// AddReceive counts a receive of the message and returns its receive count.
+func (s *valkeyRepo) AddReceive(ctx context.Context, stream string, seq uint64, ttl time.Duration) (uint64, error) {
+	count, err := s.valkeyClient.IncrWithTTL(ctx, receivedKey(stream, seq), ttl)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(max(count, 0)), nil
+}
+
+// deliveryPrefix keeps the consumer sequence of the latest delivery of a
+// message handed to a client. Receipt handles of earlier deliveries are stale.
+const deliveryPrefix = "delivery:"
+
+func deliveryKey(stream string, seq uint64) string {
+	return deliveryPrefix + stream + ":" + strconv.FormatUint(seq, 10)
+}
+
+func (s *valkeyRepo) SetCurrentDelivery(ctx context.Context, stream string, seq, consumerSeq uint64, ttl time.Duration) error {
+	return s.valkeyClient.SetValueWithTTL(ctx, deliveryKey(stream, seq), strconv.FormatUint(consumerSeq, 10), ttl)
+}
+
+// GetCurrentDelivery returns 0 when no delivery of the message is recorded.
+func (s *valkeyRepo) GetCurrentDelivery(ctx context.Context, stream string, seq uint64) (uint64, error) {
+	value, err := s.valkeyClient.GetValue(ctx, deliveryKey(stream, seq))
+	if valkey.IsNil(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+func (s *valkeyRepo) ClearCurrentDelivery(ctx context.Context, stream string, seq uint64) error {
+	return s.valkeyClient.DeleteValue(ctx, deliveryKey(stream, seq))
+}
+
+// delayedPrefix indexes the messages handed back until their delay elapses,
+// scored by the time they become visible.
+const delayedPrefix = "delayed:"
+
+func (s *valkeyRepo) AddDelayedMessage(ctx context.Context, stream string, seq uint64, visibleAt time.Time) error {
+	return s.valkeyClient.SortedSetAdd(ctx, delayedPrefix+stream, strconv.FormatUint(seq, 10), visibleAt.UnixMilli())
+}
+
+// CountDelayedMessages counts the messages of the stream still delayed.
+func (s *valkeyRepo) CountDelayedMessages(ctx context.Context, stream string) (int64, error) {
+	return s.valkeyClient.SortedSetCountFrom(ctx, delayedPrefix+stream, time.Now().UnixMilli())
+}
+
+func (s *valkeyRepo) ClearDelayedMessages(ctx context.Context, stream string) error {
+	return s.valkeyClient.DeleteValue(ctx, delayedPrefix+stream)
+}
+
+// purgePrefix marks a queue purged within the last ttl.
+const purgePrefix = "purge:"
+
+// AcquirePurgeLock reports whether the stream may be purged now. The lock of a
+// successful purge is never released; it expires after ttl so purges are spaced
+// at least ttl apart.
+func (s *valkeyRepo) AcquirePurgeLock(ctx context.Context, stream string, ttl time.Duration) (bool, error) {
+	return s.valkeyClient.SetValueNX(ctx, purgePrefix+stream, strconv.FormatInt(time.Now().Unix(), 10), ttl)
+}
+
+// ReleasePurgeLock lets a failed purge be retried right away.
+func (s *valkeyRepo) ReleasePurgeLock(ctx context.Context, stream string) error {
+	return s.valkeyClient.DeleteValue(ctx, purgePrefix+stream)
+}
+
+// queueLockPrefix serializes the read-modify-write updates of a queue's stream configuration.
+const queueLockPrefix = "queuelock:"
+
+// AcquireQueueLock makes owner the only one updating the stream configuration
+// until it releases the lock or ttl elapses.
+func (s *valkeyRepo) AcquireQueueLock(ctx context.Context, stream, owner string, ttl time.Duration) (bool, error) {
+	return s.valkeyClient.SetValueNX(ctx, queueLockPrefix+stream, owner, ttl)
+}
+
+// deleteIfScript deletes the key if it still holds ARGV[1].
+const deleteIfScript = `
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+return redis.call('DEL', KEYS[1])`
+
+// ReleaseQueueLock leaves a lock that expired and was taken by another owner alone.
+func (s *valkeyRepo) ReleaseQueueLock(ctx context.Context, stream, owner string) error {
+	_, err := s.valkeyClient.EvalInt(ctx, deleteIfScript, []string{queueLockPrefix + stream}, []string{owner})
+	return err
+}
+
+// accessKeyPrefix stores the access keys checked by the SigV4 middleware as JSON
+// encoded entity.Credential, provisioned outside this service.
+const accessKeyPrefix = "accesskey:"
+
+func (s *valkeyRepo) GetCredential(ctx context.Context, accessKeyId string) (entity.Credential, error) {
+	var credential entity.Credential
+	jsonStr, err := s.valkeyClient.GetValue(ctx, accessKeyPrefix+accessKeyId)
+	if valkey.IsNil(err) {
+		return credential, entity.ErrInvalidClientTokenId
+	}
+	if err != nil {
+		return credential, err
+	}
+	err = json.Unmarshal([]byte(jsonStr), &credential)
+	return credential, err
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"nats/internal/entity"
+	"nats/internal/repo"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// attributeValidators lists the queue attributes a client may set and how each value is checked.
+var attributeValidators = map[string]func(string) error{
+	entity.AttrVisibilityTimeout:             intAttribute(0, entity.MaxVisibilityTimeout),
+	entity.AttrReceiveMessageWaitTimeSeconds: intAttribute(0, entity.MaxWaitTimeSeconds),
+	entity.AttrDelaySeconds:                  intAttribute(0, entity.MaxDelaySeconds),
+	entity.AttrRedrivePolicy:                 validateRedrivePolicy,
+	entity.AttrRedriveAllowPolicy:            validateRedriveAllowPolicy,
+	entity.AttrFifoQueue:                     boolAttribute,
+	entity.AttrContentBasedDeduplication:     boolAttribute,
+	entity.AttrMessageRetentionPeriod:        intAttribute(entity.MinMessageRetentionPeriod, entity.MaxMessageRetentionPeriod),
+	entity.AttrMaximumMessageSize:            intAttribute(entity.MinMaximumMessageSize, entity.MaxMessageSize),
+	entity.AttrPolicy:                        validatePolicy,
+}
+
+// readOnlyAttributes are returned by GetQueueAttributes but cannot be set.
+var readOnlyAttributes = map[string]struct{}{
+	entity.AttrApproximateNumberOfMessages:           {},
+	entity.AttrApproximateNumberOfMessagesNotVisible: {},
+	entity.AttrApproximateNumberOfMessagesDelayed:    {},
+	entity.AttrCreatedTimestamp:                      {},
+	entity.AttrLastModifiedTimestamp:                 {},
+	entity.AttrQueueArn:                              {},
+}
+
+// removableAttributes are cleared by setting them to an empty string.
+var removableAttributes = map[string]struct{}{
+	entity.AttrRedrivePolicy:      {},
+	entity.AttrRedriveAllowPolicy: {},
+	entity.AttrPolicy:             {},
+}
+
+// validateQueueAttributes rejects unknown attribute names and out-of-range values.
+func validateQueueAttributes(attrs map[string]string) error {
+	for name, value := range attrs {
+		if _, ok := removableAttributes[name]; ok && value == "" {
+			continue
+		}
+		validate, ok := attributeValidators[name]
+		if !ok {
+			return fmt.Errorf("%w: %s", entity.ErrInvalidAttributeName, name)
+		}
+		if err := validate(value); err != nil {
+			return fmt.Errorf("%w: %s: %v", entity.ErrInvalidAttributeValue, name, err)
+		}
+	}
+	return nil
+}
+
+// validateAttributeNames checks the names requested from GetQueueAttributes.
+func validateAttributeNames(names []string) error {
+	for _, name := range names {
+		if name == entity.AttrAll {
+			continue
+		}
+		_, settable := attributeValidators[name]
+		_, readOnly := readOnlyAttributes[name]
+		if !settable && !readOnly {
+			return fmt.Errorf("%w: %s", entity.ErrInvalidAttributeName, name)
+		}
+	}
+	return nil
+}
+
+// applyQueueAttributes writes queue attributes into the stream configuration.
+// MessageRetentionPeriod and MaximumMessageSize are stream limits, every other
+// attribute is kept in the stream metadata. MaximumMessageSize is kept there
+// too, as the stream limit also covers the headers. An empty value removes the
+// attribute.
+func applyQueueAttributes(cfg *jetstream.StreamConfig, attrs map[string]string) {
+	if cfg.Metadata == nil {
+		cfg.Metadata = make(map[string]string, len(attrs))
+	}
+	for name, value := range attrs {
+		switch name {
+		case entity.AttrMessageRetentionPeriod:
+			sec, _ := strconv.Atoi(value)
+			cfg.MaxAge = time.Duration(sec) * time.Second
+			if cfg.Duplicates > 0 {
+				// Only FIFO queues deduplicate; the window follows the retention
+				cfg.Duplicates = min(repo.FifoDeduplicationWindow, cfg.MaxAge)
+			}
+		case entity.AttrMaximumMessageSize:
+			size, _ := strconv.Atoi(value)
+			cfg.MaxMsgSize = int32(size + repo.MessageHeaderAllowance)
+			cfg.Metadata[entity.QueueMetadataKey(name)] = value
+		default:
+			if value == "" {
+				delete(cfg.Metadata, entity.QueueMetadataKey(name))
+				continue
+			}
+			cfg.Metadata[entity.QueueMetadataKey(name)] = value
+		}
+	}
+	cfg.Metadata[entity.QueueMetadataKey(entity.AttrLastModifiedTimestamp)] = strconv.FormatInt(time.Now().Unix(), 10)
+}
+
+// configuredAttributes returns the attributes stored in the stream configuration,
+// with the SQS defaults for the ones never set.
+func configuredAttributes(cfg jetstream.StreamConfig) map[string]string {
+	attrs := map[string]string{
+		entity.AttrVisibilityTimeout:             strconv.Itoa(int(entity.DefaultVisibilityTimeout / time.Second)),
+		entity.AttrDelaySeconds:                  "0",
+		entity.AttrReceiveMessageWaitTimeSeconds: "0",
+	}
+	for name, value := range entity.QueueAttributes(cfg.Metadata) {
+		attrs[name] = value
+	}
+	attrs[entity.AttrMessageRetentionPeriod] = strconv.Itoa(int(cfg.MaxAge / time.Second))
+	attrs[entity.AttrMaximumMessageSize] = strconv.Itoa(maximumMessageSize(cfg))
+	return attrs
+}
+
+// maximumMessageSize returns the MaximumMessageSize of the queue. Streams
+// created before the attribute was kept in the metadata only have MaxMsgSize.
+func maximumMessageSize(cfg jetstream.StreamConfig) int {
+	if size, err := strconv.Atoi(cfg.Metadata[entity.QueueMetadataKey(entity.AttrMaximumMessageSize)]); err == nil {
+		return size
+	}
+	return min(int(cfg.MaxMsgSize), entity.MaxMessageSize)
+}
+
+func intAttribute(minValue, maxValue int) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if n < minValue || n > maxValue {
+			return fmt.Errorf("must be between %d and %d", minValue, maxValue)
+		}
+		return nil
+	}
+}
+
+func boolAttribute(value string) error {
+	if value != "true" && value != "false" {
+		return fmt.Errorf("must be true or false")
+	}
+	return nil
+}
+
+// queueIntAttribute reads an integer attribute, falling back to def when unset or malformed.
+func queueIntAttribute(attrs map[string]string, name string, def int) int {
+	n, err := strconv.Atoi(attrs[name])
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+func parseRedrivePolicy(value string) (entity.RedrivePolicy, error) {
+	var policy entity.RedrivePolicy
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		return policy, err
+	}
+	if _, _, _, err := parseQueueSrn(policy.DeadLetterTargetArn); err != nil {
+		return policy, fmt.Errorf("deadLetterTargetArn: %w", err)
+	}
+	if policy.MaxReceiveCount < 1 || policy.MaxReceiveCount > 1000 {
+		return policy, fmt.Errorf("maxReceiveCount must be between 1 and 1000")
+	}
+	return policy, nil
+}
+
+func validateRedrivePolicy(value string) error {
+	_, err := parseRedrivePolicy(value)
+	return err
+}
+
+func parseRedriveAllowPolicy(value string) (entity.RedriveAllowPolicy, error) {
+	var policy entity.RedriveAllowPolicy
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		return policy, err
+	}
+	switch policy.RedrivePermission {
+	case entity.RedrivePermissionAllowAll, entity.RedrivePermissionDenyAll:
+		if len(policy.SourceQueueArns) > 0 {
+			return policy, fmt.Errorf("sourceQueueArns is only allowed with %s", entity.RedrivePermissionByQueue)
+		}
+	case entity.RedrivePermissionByQueue:
+		if len(policy.SourceQueueArns) == 0 || len(policy.SourceQueueArns) > 10 {
+			return policy, fmt.Errorf("sourceQueueArns must list 1 to 10 queues")
+		}
+	default:
+		return policy, fmt.Errorf("unknown redrivePermission %q", policy.RedrivePermission)
+	}
+	return policy, nil
+}
+
+func validateRedriveAllowPolicy(value string) error {
+	_, err := parseRedriveAllowPolicy(value)
+	return err
+}
+
+func parsePolicy(value string) (entity.Policy, error) {
+	var policy entity.Policy
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		return policy, err
+	}
+	return policy, policy.Validate()
+}
+
+func validatePolicy(value string) error {
+	_, err := parsePolicy(value)
+	return err
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"nats/internal/entity"
+	"nats/internal/repo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRedrivePolicy(t *testing.T) {
+	const dlq = "srn:scp:sns:kr-west1:accountid:orders-dlq"
+
+	for _, value := range []string{
+		`{"deadLetterTargetArn":"` + dlq + `","maxReceiveCount":"5"}`,
+		`{"deadLetterTargetArn":"` + dlq + `","maxReceiveCount":5}`,
+	} {
+		policy, err := parseRedrivePolicy(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, entity.RedrivePolicy{DeadLetterTargetArn: dlq, MaxReceiveCount: 5}, policy)
+	}
+
+	for _, value := range []string{
+		`{"deadLetterTargetArn":"` + dlq + `","maxReceiveCount":0}`,
+		`{"deadLetterTargetArn":"arn:aws:sqs:us-east-1:1:q","maxReceiveCount":3}`,
+		`{"maxReceiveCount":3}`,
+		`not json`,
+	} {
+		_, err := parseRedrivePolicy(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestRedriveAllowPolicy(t *testing.T) {
+	const source = "srn:scp:sns:kr-west1:accountid:orders"
+
+	policy, err := parseRedriveAllowPolicy(`{"redrivePermission":"byQueue","sourceQueueArns":["` + source + `"]}`)
+	assert.NoError(t, err)
+	assert.True(t, policy.Allows(source))
+	assert.False(t, policy.Allows("srn:scp:sns:kr-west1:accountid:other"))
+
+	policy, err = parseRedriveAllowPolicy(`{"redrivePermission":"denyAll"}`)
+	assert.NoError(t, err)
+	assert.False(t, policy.Allows(source))
+
+	_, err = parseRedriveAllowPolicy(`{"redrivePermission":"byQueue"}`)
+	assert.Error(t, err)
+}
+
+func TestValidateQueueAttributes(t *testing.T) {
+	assert.NoError(t, validateQueueAttributes(map[string]string{entity.AttrVisibilityTimeout: "60"}))
+	assert.ErrorIs(t, validateQueueAttributes(map[string]string{entity.AttrVisibilityTimeout: "43201"}), entity.ErrInvalidAttributeValue)
+	assert.ErrorIs(t, validateQueueAttributes(map[string]string{"Unknown": "1"}), entity.ErrInvalidAttributeName)
+}
+
+func TestApplyQueueAttributes(t *testing.T) {
+	cfg := repo.NewStreamConfig("orders", "orders", nil)
+	applyQueueAttributes(&cfg, map[string]string{
+		entity.AttrMessageRetentionPeriod: "86400",
+		entity.AttrMaximumMessageSize:     "2048",
+		entity.AttrVisibilityTimeout:      "45",
+		entity.AttrRedrivePolicy:          `{"deadLetterTargetArn":"srn:scp:sns:kr-west1:accountid:orders-dlq","maxReceiveCount":3}`,
+	})
+	assert.Equal(t, 24*time.Hour, cfg.MaxAge)
+	assert.Equal(t, int32(2048+repo.MessageHeaderAllowance), cfg.MaxMsgSize)
+
+	attrs := configuredAttributes(cfg)
+	assert.Equal(t, "86400", attrs[entity.AttrMessageRetentionPeriod])
+	assert.Equal(t, "2048", attrs[entity.AttrMaximumMessageSize])
+	assert.Equal(t, "45", attrs[entity.AttrVisibilityTimeout])
+	assert.Equal(t, "0", attrs[entity.AttrDelaySeconds])
+	assert.NotEmpty(t, attrs[entity.AttrLastModifiedTimestamp])
+
+	applyQueueAttributes(&cfg, map[string]string{entity.AttrRedrivePolicy: ""})
+	assert.NotContains(t, configuredAttributes(cfg), entity.AttrRedrivePolicy)
+}
+
+func TestValidateAttributeNames(t *testing.T) {
+	assert.NoError(t, validateAttributeNames([]string{entity.AttrAll}))
+	assert.NoError(t, validateAttributeNames([]string{entity.AttrQueueArn, entity.AttrDelaySeconds}))
+	assert.ErrorIs(t, validateAttributeNames([]string{"Unknown"}), entity.ErrInvalidAttributeName)
+}
+
+func TestSameQueueAttributes(t *testing.T) {
+	existing := repo.NewStreamConfig("orders", "orders", nil)
+	applyQueueAttributes(&existing, map[string]string{entity.AttrVisibilityTimeout: "30"})
+	existing.Metadata[entity.QueueMetadataKey(entity.AttrLastModifiedTimestamp)] = "1"
+
+	requested := repo.NewStreamConfig("orders", "orders", nil)
+	applyQueueAttributes(&requested, nil)
+	assert.True(t, sameQueueAttributes(existing, requested), "default VisibilityTimeout equals an explicit 30")
+
+	applyQueueAttributes(&requested, map[string]string{entity.AttrDelaySeconds: "5"})
+	assert.False(t, sameQueueAttributes(existing, requested))
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"regexp"
+
+	"nats/internal/entity"
+)
+
+// maxBatchEntries is the SQS limit on entries in a single batch request.
+const maxBatchEntries = 10
+
+var batchEntryIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,80}$`)
+
+// validateBatchIds applies the SQS batch request rules shared by every *Batch action.
+func validateBatchIds(ids []string) error {
+	if len(ids) == 0 {
+		return entity.ErrEmptyBatchRequest
+	}
+	if len(ids) > maxBatchEntries {
+		return entity.ErrTooManyEntriesInBatch
+	}
+
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if !batchEntryIdPattern.MatchString(id) {
+			return entity.ErrInvalidBatchEntryId
+		}
+		if _, ok := seen[id]; ok {
+			return entity.ErrBatchEntryIdsNotDistinct
+		}
+		seen[id] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"nats/internal/context/logs"
+	"nats/internal/entity"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// receiveRetention keeps the receive state of the messages of queues without MaxAge.
+const receiveRetention = 14 * 24 * time.Hour
+
+func validateDelaySeconds(sec int) error {
+	if sec < 0 || sec > entity.MaxDelaySeconds {
+		return fmt.Errorf("%w: DelaySeconds must be between 0 and %d", entity.ErrInvalidParameter, entity.MaxDelaySeconds)
+	}
+	return nil
+}
+
+// deliveryDelay returns how long the message must stay invisible. The delay
+// counts from the time the stream stored the message, so it survives restarts
+// without any state besides the message. A DelaySeconds header set at send
+// time wins over the queue DelaySeconds attribute.
+func deliveryDelay(header nats.Header, meta *jetstream.MsgMetadata, queueDelay int) time.Duration {
+	sec := queueDelay
+	if value := header.Get(entity.HeaderDelaySeconds); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			sec = n
+		}
+	}
+	if sec <= 0 {
+		return 0
+	}
+	return time.Until(meta.Timestamp.Add(time.Duration(sec) * time.Second))
+}
+
+// deferMessage hands a message back without delivering it to the client.
+func (s *messageService) deferMessage(ctx context.Context, handle entity.ReceiptHandle, delay time.Duration) {
+	if err := s.natsRepo.NakMessage(ctx, handle, delay); err != nil {
+		logs.GetLogger(ctx).Warn("Failed to defer message", logs.WithTraceFields(ctx, zap.Error(err))...)
+	}
+}
+
+// delayMessage defers a message until its delay elapses and indexes it for
+// ApproximateNumberOfMessagesDelayed.
+func (s *messageService) delayMessage(ctx context.Context, handle entity.ReceiptHandle, delay time.Duration) {
+	if err := s.valkeyRepo.AddDelayedMessage(ctx, handle.Stream, handle.StreamSeq, time.Now().Add(delay)); err != nil {
+		logs.GetLogger(ctx).Warn("Failed to index delayed message", logs.WithTraceFields(ctx, zap.Error(err))...)
+	}
+	s.deferMessage(ctx, handle, delay)
+}
+
+// countReceive counts the delivery of a message to a client and returns its
+// receive count, which maxReceiveCount applies to. Deferrals and visibility
+// changes are redeliveries on JetStream but not receives. Should valkey fail,
+// the JetStream delivery count stands in.
+func (s *messageService) countReceive(ctx context.Context, meta *jetstream.MsgMetadata, retention time.Duration) uint64 {
+	count, err := s.valkeyRepo.AddReceive(ctx, meta.Stream, meta.Sequence.Stream, receiveStateRetention(retention))
+	if err != nil || count == 0 {
+		logs.GetLogger(ctx).Warn("Failed to count message receive", logs.WithTraceFields(ctx, zap.Error(err))...)
+		return meta.NumDelivered
+	}
+	return count
+}
+
+// receiveStateRetention keeps the receive state of a message as long as the queue keeps the message.
+func receiveStateRetention(maxAge time.Duration) time.Duration {
+	if maxAge <= 0 {
+		return receiveRetention
+	}
+	return maxAge
+}
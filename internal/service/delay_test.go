@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"nats/internal/entity"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliveryDelay(t *testing.T) {
+	meta := &jetstream.MsgMetadata{Timestamp: time.Now()}
+	header := nats.Header{}
+
+	assert.LessOrEqual(t, deliveryDelay(header, meta, 0), time.Duration(0))
+	assert.InDelta(t, 60*time.Second, deliveryDelay(header, meta, 60), float64(time.Second))
+
+	// The per-message value wins over the queue attribute, including 0
+	header.Set(entity.HeaderDelaySeconds, "0")
+	assert.LessOrEqual(t, deliveryDelay(header, meta, 60), time.Duration(0))
+	header.Set(entity.HeaderDelaySeconds, "10")
+	assert.InDelta(t, 10*time.Second, deliveryDelay(header, meta, 60), float64(time.Second))
+
+	// The delay counts from the stored timestamp
+	old := &jetstream.MsgMetadata{Timestamp: time.Now().Add(-time.Minute)}
+	assert.LessOrEqual(t, deliveryDelay(header, old, 0), time.Duration(0))
+}
+
+func TestSendTargetDelaySeconds(t *testing.T) {
+	delay := 30
+	_, header, err := sendTarget("orders", "accountid", "", "id-1", "body", entity.MaxMessageSize, entity.SendOptions{DelaySeconds: &delay})
+	assert.NoError(t, err)
+	assert.Equal(t, "30", header.Get(entity.HeaderDelaySeconds))
+
+	tooLong := entity.MaxDelaySeconds + 1
+	_, _, err = sendTarget("orders", "accountid", "", "id-1", "body", entity.MaxMessageSize, entity.SendOptions{DelaySeconds: &tooLong})
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+
+	_, _, err = sendTarget("orders.fifo", "accountid", "", "id-1", "body", entity.MaxMessageSize, entity.SendOptions{MessageGroupId: "g", MessageDeduplicationId: "d", DelaySeconds: &delay})
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+}
@@ -85,7 +85,7 @@ func (d *ackDispatcher) process(task *entity.AckTask) {
 		if ack != nil {
 			logger.Info("ACK received successfully", logs.WithTraceFields(ctx, zap.String("id", task.ID), zap.Uint64("seq", ack.Sequence))...)
 			span.SetStatus(codes.Ok, "ACK received successfully")
-			_ = d.valkeyRepo.StoreAckResult(ctx, task.ID, entity.AckResult{Status: "ACK", Sequence: ack.Sequence})
+			_ = d.valkeyRepo.StoreAckResult(ctx, task.ID, entity.AckResult{Status: "ACK", Sequence: ack.Sequence, Duplicate: ack.Duplicate})
 		} else {
 			logger.Error("ACK reception failure", logs.WithTraceFields(ctx, zap.String("id", task.ID))...)
 			span.SetStatus(codes.Error, "ACK reception failure")
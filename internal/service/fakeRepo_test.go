@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"nats/internal/entity"
+	"nats/internal/repo"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// fakeNatsRepo keeps streams in memory and records the calls the services
+// make. Methods a test does not expect are left to the embedded nil interface
+// and panic.
+type fakeNatsRepo struct {
+	repo.NatsRepo
+
+	mu         sync.Mutex
+	streams    map[string]*jetstream.StreamInfo
+	fetches    [][]jetstream.Msg
+	fetchCalls int
+	sent       []*nats.Msg
+	acks       []string
+	naks       []time.Duration
+	deleted    []uint64
+	purgeErr   error
+
+	listOffsets   []int
+	listFailAfter int
+}
+
+func newFakeNatsRepo() *fakeNatsRepo {
+	return &fakeNatsRepo{streams: make(map[string]*jetstream.StreamInfo)}
+}
+
+// addStream stores a stream created with cfg.
+func (r *fakeNatsRepo) addStream(cfg jetstream.StreamConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.streams[cfg.Name] = &jetstream.StreamInfo{Config: cfg, Created: time.Now()}
+}
+
+func (r *fakeNatsRepo) GetStream(ctx context.Context, name string) (jetstream.Stream, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info, ok := r.streams[name]
+	if !ok {
+		return nil, jetstream.ErrStreamNotFound
+	}
+	return fakeStream{info: info}, nil
+}
+
+func (r *fakeNatsRepo) CreateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.streams[cfg.Name]; ok {
+		return nil, jetstream.ErrStreamNameAlreadyInUse
+	}
+	info := &jetstream.StreamInfo{Config: cfg, Created: time.Now()}
+	r.streams[cfg.Name] = info
+	return fakeStream{info: info}, nil
+}
+
+// UpdateStream replaces the configuration of a stream. Writing it back lets
+// other goroutines run in between, as a round trip to the server would.
+func (r *fakeNatsRepo) UpdateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	runtime.Gosched()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info, ok := r.streams[cfg.Name]
+	if !ok {
+		return nil, jetstream.ErrStreamNotFound
+	}
+	info = &jetstream.StreamInfo{Config: cfg, Created: info.Created}
+	r.streams[cfg.Name] = info
+	return fakeStream{info: info}, nil
+}
+
+// ListStreams lists every stream in name order, whatever the subject. With
+// listFailAfter set, the listing fails after that many streams.
+func (r *fakeNatsRepo) ListStreams(ctx context.Context, subject string, offset int) (<-chan *jetstream.StreamInfo, func() error, error) {
+	r.mu.Lock()
+	r.listOffsets = append(r.listOffsets, offset)
+	names := slices.Sorted(maps.Keys(r.streams))
+	infos := make([]*jetstream.StreamInfo, 0, len(names))
+	for _, name := range names[min(offset, len(names)):] {
+		infos = append(infos, r.streams[name])
+	}
+	failAfter := r.listFailAfter
+	r.mu.Unlock()
+
+	var listErr error
+	infoCh := make(chan *jetstream.StreamInfo)
+	go func() {
+		defer close(infoCh)
+		for i, info := range infos {
+			if failAfter > 0 && i == failAfter {
+				listErr = nats.ErrTimeout
+				return
+			}
+			select {
+			case infoCh <- info:
+			case <-ctx.Done():
+				listErr = ctx.Err()
+				return
+			}
+		}
+	}()
+	return infoCh, func() error { return listErr }, nil
+}
+
+func (r *fakeNatsRepo) GetOrCreateConsumer(ctx context.Context, stream, name string) (jetstream.Consumer, error) {
+	if _, err := r.GetStream(ctx, stream); err != nil {
+		return nil, err
+	}
+	return fakeConsumer{info: &jetstream.ConsumerInfo{
+		Stream: stream,
+		Name:   name,
+		Config: jetstream.ConsumerConfig{Durable: name, AckWait: entity.DefaultVisibilityTimeout},
+	}}, nil
+}
+
+// FetchMessages hands out the next batch queued in fetches. Without one it
+// long polls until wait elapses.
+func (r *fakeNatsRepo) FetchMessages(ctx context.Context, cons jetstream.Consumer, batch int, wait time.Duration) ([]jetstream.Msg, error) {
+	r.mu.Lock()
+	r.fetchCalls++
+	if len(r.fetches) == 0 {
+		r.mu.Unlock()
+		select {
+		case <-time.After(max(wait, 0)):
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	defer r.mu.Unlock()
+	msgs := r.fetches[0]
+	r.fetches = r.fetches[1:]
+	return msgs, nil
+}
+
+func (r *fakeNatsRepo) SendMessage(ctx context.Context, message, subject string, header nats.Header) (*jetstream.PubAck, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, &nats.Msg{Subject: subject, Data: []byte(message), Header: header})
+	return &jetstream.PubAck{Sequence: uint64(len(r.sent))}, nil
+}
+
+func (r *fakeNatsRepo) PublishBatch(ctx context.Context, msgs []*nats.Msg) ([]*jetstream.PubAck, []error) {
+	acks := make([]*jetstream.PubAck, len(msgs))
+	errs := make([]error, len(msgs))
+	for i, msg := range msgs {
+		acks[i], errs[i] = r.SendMessage(ctx, string(msg.Data), msg.Subject, msg.Header)
+	}
+	return acks, errs
+}
+
+func (r *fakeNatsRepo) GetMessage(ctx context.Context, stream string, seq uint64) (*jetstream.RawStreamMsg, error) {
+	if _, err := r.GetStream(ctx, stream); err != nil {
+		return nil, err
+	}
+	return &jetstream.RawStreamMsg{Sequence: seq}, nil
+}
+
+func (r *fakeNatsRepo) AckMessage(ctx context.Context, handle entity.ReceiptHandle, ackType string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.acks = append(r.acks, ackType)
+	return nil
+}
+
+func (r *fakeNatsRepo) NakMessage(ctx context.Context, handle entity.ReceiptHandle, delay time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.naks = append(r.naks, delay)
+	return nil
+}
+
+func (r *fakeNatsRepo) DeleteMessage(ctx context.Context, stream string, seq uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleted = append(r.deleted, seq)
+	return nil
+}
+
+func (r *fakeNatsRepo) PurgeStream(ctx context.Context, name string) error {
+	return r.purgeErr
+}
+
+func (r *fakeNatsRepo) DeleteConsumer(ctx context.Context, stream, name string) error {
+	return nil
+}
+
+type fakeStream struct {
+	jetstream.Stream
+	info *jetstream.StreamInfo
+}
+
+func (s fakeStream) CachedInfo() *jetstream.StreamInfo { return s.info }
+
+type fakeConsumer struct {
+	jetstream.Consumer
+	info *jetstream.ConsumerInfo
+}
+
+func (c fakeConsumer) CachedInfo() *jetstream.ConsumerInfo { return c.info }
+
+// testMsg is a message delivered by a consumer. Acknowledgements are recorded in acks.
+type testMsg struct {
+	jetstream.Msg
+	subject string
+	header  nats.Header
+	data    []byte
+	meta    *jetstream.MsgMetadata
+	acks    *[]string
+}
+
+func (m testMsg) Subject() string      { return m.subject }
+func (m testMsg) Headers() nats.Header { return m.header }
+func (m testMsg) Data() []byte         { return m.data }
+
+func (m testMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	if m.meta == nil {
+		return nil, jetstream.ErrNotJSMessage
+	}
+	return m.meta, nil
+}
+
+func (m testMsg) Ack() error {
+	*m.acks = append(*m.acks, repo.AckAck)
+	return nil
+}
+
+func (m testMsg) Term() error {
+	*m.acks = append(*m.acks, repo.AckTerm)
+	return nil
+}
+
+func (m testMsg) NakWithDelay(delay time.Duration) error {
+	*m.acks = append(*m.acks, repo.AckNak)
+	return nil
+}
+
+// fakeValkeyRepo keeps the valkey state of the services in memory.
+type fakeValkeyRepo struct {
+	repo.ValkeyRepo
+
+	mu         sync.Mutex
+	deliveries map[uint64]uint64
+	receives   map[uint64]uint64
+	purgeLocks map[string]bool
+	queueLocks map[string]string
+	leaseErr   error
+}
+
+func newFakeValkeyRepo() *fakeValkeyRepo {
+	return &fakeValkeyRepo{
+		deliveries: make(map[uint64]uint64),
+		receives:   make(map[uint64]uint64),
+		purgeLocks: make(map[string]bool),
+		queueLocks: make(map[string]string),
+	}
+}
+
+func (r *fakeValkeyRepo) StoreAckResult(ctx context.Context, id string, result entity.AckResult) error {
+	return nil
+}
+
+func (r *fakeValkeyRepo) AcquireMoveTaskLease(ctx context.Context, taskHandle, owner string, ttl time.Duration) (bool, error) {
+	return r.leaseErr == nil, r.leaseErr
+}
+
+func (r *fakeValkeyRepo) CheckpointMoveTask(ctx context.Context, task entity.MessageMoveTaskRecord) (bool, error) {
+	return true, nil
+}
+
+func (r *fakeValkeyRepo) AcquirePurgeLock(ctx context.Context, stream string, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.purgeLocks[stream] {
+		return false, nil
+	}
+	r.purgeLocks[stream] = true
+	return true, nil
+}
+
+func (r *fakeValkeyRepo) ReleasePurgeLock(ctx context.Context, stream string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.purgeLocks, stream)
+	return nil
+}
+
+func (r *fakeValkeyRepo) AcquireQueueLock(ctx context.Context, stream, owner string, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.queueLocks[stream]; ok {
+		return false, nil
+	}
+	r.queueLocks[stream] = owner
+	return true, nil
+}
+
+func (r *fakeValkeyRepo) ReleaseQueueLock(ctx context.Context, stream, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.queueLocks[stream] == owner {
+		delete(r.queueLocks, stream)
+	}
+	return nil
+}
+
+func (r *fakeValkeyRepo) ClearDelayedMessages(ctx context.Context, stream string) error {
+	return nil
+}
+
+func (r *fakeValkeyRepo) AddReceive(ctx context.Context, stream string, seq uint64, ttl time.Duration) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.receives[seq]++
+	return r.receives[seq], nil
+}
+
+func (r *fakeValkeyRepo) AddDelayedMessage(ctx context.Context, stream string, seq uint64, visibleAt time.Time) error {
+	return nil
+}
+
+func (r *fakeValkeyRepo) SetCurrentDelivery(ctx context.Context, stream string, seq, consumerSeq uint64, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries[seq] = consumerSeq
+	return nil
+}
+
+func (r *fakeValkeyRepo) GetCurrentDelivery(ctx context.Context, stream string, seq uint64) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deliveries[seq], nil
+}
+
+func (r *fakeValkeyRepo) ClearCurrentDelivery(ctx context.Context, stream string, seq uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.deliveries, seq)
+	return nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"time"
+
+	"nats/internal/entity"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// fifoIdPattern matches MessageGroupId and MessageDeduplicationId: up to 128
+// alphanumeric or punctuation characters.
+var fifoIdPattern = regexp.MustCompile(`^[\x21-\x7e]{1,128}$`)
+
+// fifoDeferDelay is how long a FIFO message waits before it is offered again
+// while an older message of its group is still in flight.
+const fifoDeferDelay = time.Second
+
+// contentDeduplication derives the MessageDeduplicationId from the body when
+// a FIFO send omits it and the queue enables ContentBasedDeduplication.
+func (s *messageService) contentDeduplication(ctx context.Context, queueName, account, message string, opts entity.SendOptions) (entity.SendOptions, error) {
+	if !entity.IsFifoQueue(queueName) || opts.MessageDeduplicationId != "" {
+		return opts, nil
+	}
+	enabled, err := s.contentBasedDeduplication(ctx, queueName, account)
+	if err != nil {
+		return opts, err
+	}
+	if enabled {
+		opts.MessageDeduplicationId = contentDeduplicationId(message)
+	}
+	return opts, nil
+}
+
+// contentBasedDeduplication reads the ContentBasedDeduplication attribute of the queue.
+func (s *messageService) contentBasedDeduplication(ctx context.Context, queueName, account string) (bool, error) {
+	stream, err := s.natsRepo.GetStream(ctx, entity.StreamName(account, queueName))
+	if err != nil {
+		return false, streamError(err)
+	}
+	attrs := entity.QueueAttributes(stream.CachedInfo().Config.Metadata)
+	return attrs[entity.AttrContentBasedDeduplication] == "true", nil
+}
+
+// contentDeduplicationId is the hex SHA-256 of the message body.
+func contentDeduplicationId(message string) string {
+	sum := sha256.Sum256([]byte(message))
+	return hex.EncodeToString(sum[:])
+}
+
+// isGroupHead reports whether msg is the oldest message of its group stored
+// after the sequence already handed out in this batch. Messages are deleted
+// from the stream once processed, so only the group head (and the messages
+// following it in the same batch) may be in flight at any time.
+func (s *messageService) isGroupHead(ctx context.Context, stream string, msg jetstream.Msg, meta *jetstream.MsgMetadata, after uint64) (bool, error) {
+	head, err := s.natsRepo.GetNextMessage(ctx, stream, msg.Subject(), after+1)
+	if errors.Is(err, jetstream.ErrMsgNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return head.Sequence == meta.Sequence.Stream, nil
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"testing"
+
+	"nats/internal/entity"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendTargetFifoQueue(t *testing.T) {
+	opts := entity.SendOptions{MessageGroupId: "customer.42", MessageDeduplicationId: "order-1"}
+
+	subject, header, err := sendTarget("billing.fifo", "accountid", "", "id-1", "body", entity.MaxMessageSize, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.QueueSubject("accountid", "billing.fifo", "customer.42"), subject)
+	assert.Equal(t, "customer.42", header.Get(entity.HeaderMessageGroupId))
+	assert.Equal(t, "order-1", header.Get(jetstream.MsgIDHeader))
+	assert.Equal(t, "id-1", header.Get(entity.HeaderMessageId))
+
+	_, _, err = sendTarget("billing.fifo", "accountid", "", "id-1", "body", entity.MaxMessageSize, entity.SendOptions{MessageDeduplicationId: "order-1"})
+	assert.ErrorIs(t, err, entity.ErrMissingParameter)
+
+	_, _, err = sendTarget("billing.fifo", "accountid", "", "id-1", "body", entity.MaxMessageSize, entity.SendOptions{MessageGroupId: "customer 42", MessageDeduplicationId: "order-1"})
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+
+	_, _, err = sendTarget("billing", "accountid", "", "id-1", "body", entity.MaxMessageSize, opts)
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+}
+
+func TestFifoStreamNameRoundTrip(t *testing.T) {
+	for _, name := range []string{"billing", "billing.fifo", "billing-fifo", "billing_fifo"} {
+		stream := entity.StreamName("accountid", name)
+		assert.NotContains(t, stream, ".")
+		account, queue := entity.QueueName(stream)
+		assert.Equal(t, "accountid", account)
+		assert.Equal(t, name, queue)
+	}
+	assert.NotEqual(t, entity.StreamName("accountid", "billing.fifo"), entity.StreamName("accountid", "billing_fifo"))
+}
+
+func TestStreamNamePerAccount(t *testing.T) {
+	assert.NotEqual(t, entity.StreamName("account-a", "billing"), entity.StreamName("account-b", "billing"))
+	assert.NotEqual(t, entity.StreamSubject("account-a", "billing"), entity.StreamSubject("account-b", "billing"))
+	assert.Equal(t, "account-a.billing", entity.QueueSubject("account-a", "billing", ""))
+}
+
+func TestFifoSubjectIsSingleToken(t *testing.T) {
+	assert.Equal(t, "accountid.billing~fifo.*", entity.StreamSubject("accountid", "billing.fifo"))
+	assert.Equal(t, "accountid.billing~fifo", entity.QueueSubject("accountid", "billing.fifo", ""))
+	assert.NotEqual(t, entity.StreamSubject("accountid", "billing.fifo"), entity.StreamSubject("accountid", "billing"))
+}
+
+func TestContentDeduplicationId(t *testing.T) {
+	id := contentDeduplicationId("hello")
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", id)
+	assert.Regexp(t, fifoIdPattern, id)
+	assert.NotEqual(t, id, contentDeduplicationId("hello "))
+}
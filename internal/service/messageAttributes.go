@@ -0,0 +1,277 @@
+package service
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"nats/internal/entity"
+	"nats/internal/repo"
+
+	"github.com/nats-io/nats.go"
+)
+
+var (
+	messageAttributeNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,256}$`)
+	attributeDataTypePattern    = regexp.MustCompile(`^(String|Number|Binary)(\.[A-Za-z0-9_.-]+)?$`)
+	numberValuePattern          = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+)
+
+// maxNumberDigits is the SQS precision limit of Number attributes.
+const maxNumberDigits = 38
+
+// Transport type bytes of the SQS message attribute MD5 algorithm.
+const (
+	md5StringTransport byte = 1
+	md5BinaryTransport byte = 2
+)
+
+// validateMessageAttributes applies the SQS naming and value rules to user message attributes.
+func validateMessageAttributes(attrs map[string]entity.MessageAttributeValue) error {
+	if len(attrs) > entity.MaxMessageAttributes {
+		return fmt.Errorf("%w: a message can have at most %d MessageAttributes", entity.ErrInvalidParameter, entity.MaxMessageAttributes)
+	}
+	for name, value := range attrs {
+		if err := validateMessageAttributeName(name); err != nil {
+			return fmt.Errorf("%w: MessageAttribute %q: %v", entity.ErrInvalidParameter, name, err)
+		}
+		if err := validateMessageAttributeValue(value); err != nil {
+			return fmt.Errorf("%w: MessageAttribute %q: %v", entity.ErrInvalidParameter, name, err)
+		}
+	}
+	return nil
+}
+
+// validateMessageSystemAttributes only accepts AWSTraceHeader of type String.
+func validateMessageSystemAttributes(attrs map[string]entity.MessageAttributeValue) error {
+	for name, value := range attrs {
+		if name != entity.MessageSystemAttributeAWSTraceHeader {
+			return fmt.Errorf("%w: unsupported MessageSystemAttribute %q", entity.ErrInvalidParameter, name)
+		}
+		if value.DataType != entity.DataTypeString || value.StringValue == "" {
+			return fmt.Errorf("%w: MessageSystemAttribute %q must be a non-empty String", entity.ErrInvalidParameter, name)
+		}
+	}
+	return nil
+}
+
+func validateMessageAttributeName(name string) error {
+	if !messageAttributeNamePattern.MatchString(name) {
+		return fmt.Errorf("name must be 1 to 256 alphanumeric, '-', '_' or '.' characters")
+	}
+	lower := strings.ToLower(name)
+	if strings.HasPrefix(lower, "aws.") || strings.HasPrefix(lower, "amazon.") {
+		return fmt.Errorf("name prefix is reserved")
+	}
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") || strings.Contains(name, "..") {
+		return fmt.Errorf("name cannot start or end with a period or contain successive periods")
+	}
+	return nil
+}
+
+func validateMessageAttributeValue(value entity.MessageAttributeValue) error {
+	if len(value.DataType) > 256 || !attributeDataTypePattern.MatchString(value.DataType) {
+		return fmt.Errorf("invalid DataType %q", value.DataType)
+	}
+
+	baseType, _, _ := strings.Cut(value.DataType, ".")
+	switch baseType {
+	case entity.DataTypeBinary:
+		if len(value.BinaryValue) == 0 || value.StringValue != "" {
+			return fmt.Errorf("a Binary attribute requires a BinaryValue only")
+		}
+	case entity.DataTypeNumber:
+		if value.StringValue == "" || len(value.BinaryValue) > 0 {
+			return fmt.Errorf("a Number attribute requires a StringValue only")
+		}
+		if !numberValuePattern.MatchString(value.StringValue) || numberDigits(value.StringValue) > maxNumberDigits {
+			return fmt.Errorf("invalid Number value %q", value.StringValue)
+		}
+	default:
+		if value.StringValue == "" || len(value.BinaryValue) > 0 {
+			return fmt.Errorf("a String attribute requires a StringValue only")
+		}
+		if !validMessageChars(value.StringValue) {
+			return fmt.Errorf("StringValue contains characters outside the allowed set")
+		}
+	}
+	return nil
+}
+
+// numberDigits counts the significant digits of a number literal.
+func numberDigits(value string) int {
+	mantissa, _, _ := strings.Cut(strings.ToLower(value), "e")
+	digits := strings.TrimLeft(strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, mantissa), "0")
+	return len(digits)
+}
+
+// validMessageChars reports whether s only holds the characters SQS accepts:
+// #x9 | #xA | #xD | #x20 to #xD7FF | #xE000 to #xFFFD | #x10000 to #x10FFFF.
+func validMessageChars(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r == 0x9 || r == 0xA || r == 0xD:
+		case r >= 0x20 && r <= 0xD7FF:
+		case r >= 0xE000 && r <= 0xFFFD:
+		case r >= 0x10000 && r <= 0x10FFFF:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// setAttributeHeaders stores each attribute as a JSON header, which keeps
+// line breaks in string values out of the NATS header block.
+func setAttributeHeaders(header nats.Header, prefix string, attrs map[string]entity.MessageAttributeValue) error {
+	for name, value := range attrs {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		header.Set(prefix+name, string(encoded))
+	}
+	return nil
+}
+
+// attributesFromHeader reads back the attributes stored by setAttributeHeaders.
+func attributesFromHeader(header nats.Header, prefix string) map[string]entity.MessageAttributeValue {
+	var attrs map[string]entity.MessageAttributeValue
+	for key, values := range header {
+		name, ok := strings.CutPrefix(key, prefix)
+		if !ok || len(values) == 0 {
+			continue
+		}
+		var value entity.MessageAttributeValue
+		if err := json.Unmarshal([]byte(values[0]), &value); err != nil {
+			continue
+		}
+		if attrs == nil {
+			attrs = make(map[string]entity.MessageAttributeValue)
+		}
+		attrs[name] = value
+	}
+	return attrs
+}
+
+// selectMessageAttributes returns the attributes matching the requested
+// names: "All" or ".*" for every attribute, "prefix.*" for a prefix, or exact names.
+func selectMessageAttributes(attrs map[string]entity.MessageAttributeValue, names []string) map[string]entity.MessageAttributeValue {
+	if len(attrs) == 0 || len(names) == 0 {
+		return nil
+	}
+
+	selected := make(map[string]entity.MessageAttributeValue)
+	for _, requested := range names {
+		if requested == entity.SystemAttrAll || requested == ".*" {
+			return attrs
+		}
+		if prefix, ok := strings.CutSuffix(requested, ".*"); ok {
+			for name, value := range attrs {
+				if strings.HasPrefix(name, prefix+".") {
+					selected[name] = value
+				}
+			}
+			continue
+		}
+		if value, ok := attrs[requested]; ok {
+			selected[requested] = value
+		}
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+	return selected
+}
+
+// md5OfMessageAttributes implements the SQS checksum of message attributes:
+// for each attribute in name order, the length-prefixed name and data type,
+// a transport type byte and the length-prefixed value. Lengths are 4-byte big endian.
+func md5OfMessageAttributes(attrs map[string]entity.MessageAttributeValue) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := md5.New()
+	writeLengthPrefixed := func(b []byte) {
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(b)))
+		hash.Write(length[:])
+		hash.Write(b)
+	}
+	for _, name := range names {
+		value := attrs[name]
+		writeLengthPrefixed([]byte(name))
+		writeLengthPrefixed([]byte(value.DataType))
+		if len(value.BinaryValue) > 0 {
+			hash.Write([]byte{md5BinaryTransport})
+			writeLengthPrefixed(value.BinaryValue)
+		} else {
+			hash.Write([]byte{md5StringTransport})
+			writeLengthPrefixed([]byte(value.StringValue))
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// messageSize counts the body and every attribute name, data type and value
+// the way SQS does against MaxMessageSize.
+func messageSize(message string, attrs map[string]entity.MessageAttributeValue) int {
+	size := len(message)
+	for name, value := range attrs {
+		size += len(name) + len(value.DataType) + len(value.StringValue) + len(value.BinaryValue)
+	}
+	return size
+}
+
+// validateMessageSize checks the message against maxSize, the
+// MaximumMessageSize of the queue.
+func validateMessageSize(message string, attrs map[string]entity.MessageAttributeValue, maxSize int) error {
+	if messageSize(message, attrs) > maxSize {
+		return fmt.Errorf("%w: message must be shorter than %d bytes", entity.ErrInvalidParameter, maxSize)
+	}
+	return nil
+}
+
+// validateEncodedSize checks that the message still fits the stream once its
+// attributes are JSON-encoded into headers. JetStream counts the headers
+// against MaxMsgSize, which leaves repo.MessageHeaderAllowance above maxSize
+// for them.
+func validateEncodedSize(message string, header nats.Header, maxSize int) error {
+	size := len(message) + len("NATS/1.0\r\n\r\n")
+	for key, values := range header {
+		for _, value := range values {
+			size += len(key) + len(": \r\n") + len(value)
+		}
+	}
+	if size > maxSize+repo.MessageHeaderAllowance {
+		return fmt.Errorf("%w: message attributes are too large once encoded", entity.ErrInvalidParameter)
+	}
+	return nil
+}
+
+// md5Hex is the MD5OfBody checksum.
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
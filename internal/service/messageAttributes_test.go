@@ -0,0 +1,108 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"nats/internal/entity"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMD5OfMessageAttributes(t *testing.T) {
+	// Reference value of the SQS checksum algorithm
+	attrs := map[string]entity.MessageAttributeValue{
+		"timestamp": {DataType: "Number", StringValue: "1493147359900"},
+	}
+	assert.Equal(t, "235c5c510d26fb653d073faed50ae77c", md5OfMessageAttributes(attrs))
+
+	attrs["blob"] = entity.MessageAttributeValue{DataType: "Binary.gzip", BinaryValue: []byte{0x00, 0x01, 0xff}}
+	attrs["Name"] = entity.MessageAttributeValue{DataType: "String", StringValue: "héllo\n"}
+	assert.Equal(t, "6d7bf698848d5fbe81bea7f01c0e9de2", md5OfMessageAttributes(attrs))
+
+	assert.Empty(t, md5OfMessageAttributes(nil))
+}
+
+func TestValidateMessageAttributes(t *testing.T) {
+	valid := map[string]entity.MessageAttributeValue{
+		"customer-id":  {DataType: "String", StringValue: "42"},
+		"price":        {DataType: "Number.float", StringValue: "-1.5e3"},
+		"payload.data": {DataType: "Binary", BinaryValue: []byte("x")},
+	}
+	assert.NoError(t, validateMessageAttributes(valid))
+
+	invalid := []map[string]entity.MessageAttributeValue{
+		{"AWS.trace": {DataType: "String", StringValue: "x"}},
+		{"a..b": {DataType: "String", StringValue: "x"}},
+		{".a": {DataType: "String", StringValue: "x"}},
+		{"a": {DataType: "Text", StringValue: "x"}},
+		{"a": {DataType: "String"}},
+		{"a": {DataType: "Number", StringValue: "12abc"}},
+		{"a": {DataType: "Number", StringValue: "123456789012345678901234567890123456789"}},
+		{"a": {DataType: "Binary", StringValue: "x"}},
+		{"a": {DataType: "String", StringValue: "bell\x07"}},
+	}
+	for _, attrs := range invalid {
+		assert.ErrorIs(t, validateMessageAttributes(attrs), entity.ErrInvalidParameter, attrs)
+	}
+
+	tooMany := make(map[string]entity.MessageAttributeValue)
+	for i := 0; i <= entity.MaxMessageAttributes; i++ {
+		tooMany[fmt.Sprintf("a%d", i)] = entity.MessageAttributeValue{DataType: "String", StringValue: "x"}
+	}
+	assert.ErrorIs(t, validateMessageAttributes(tooMany), entity.ErrInvalidParameter)
+}
+
+func TestMessageAttributeHeadersRoundTrip(t *testing.T) {
+	attrs := map[string]entity.MessageAttributeValue{
+		"Name":       {DataType: "String", StringValue: "line1\nline2"},
+		"order.blob": {DataType: "Binary", BinaryValue: []byte{0, 1, 2}},
+		"order.size": {DataType: "Number", StringValue: "3"},
+	}
+	header := nats.Header{}
+	assert.NoError(t, setAttributeHeaders(header, entity.HeaderMessageAttributePrefix, attrs))
+	assert.Equal(t, attrs, attributesFromHeader(header, entity.HeaderMessageAttributePrefix))
+
+	assert.Equal(t, attrs, selectMessageAttributes(attrs, []string{"All"}))
+	assert.Len(t, selectMessageAttributes(attrs, []string{"order.*"}), 2)
+	assert.Len(t, selectMessageAttributes(attrs, []string{"Name", "missing"}), 1)
+	assert.Nil(t, selectMessageAttributes(attrs, nil))
+}
+
+func TestSendResult(t *testing.T) {
+	opts := entity.SendOptions{MessageAttributes: map[string]entity.MessageAttributeValue{
+		"timestamp": {DataType: "Number", StringValue: "1493147359900"},
+	}}
+	result := sendResult("id-1", "hello", opts)
+	assert.Equal(t, "id-1", result.MessageId)
+	assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", result.MD5OfMessageBody)
+	assert.Equal(t, "235c5c510d26fb653d073faed50ae77c", result.MD5OfMessageAttributes)
+	assert.Empty(t, result.MD5OfMessageSystemAttributes)
+	assert.Nil(t, result.Duplicate)
+}
+
+func TestMessageSize(t *testing.T) {
+	attrs := map[string]entity.MessageAttributeValue{
+		"a":    {DataType: "String", StringValue: "xyz"},
+		"blob": {DataType: "Binary", BinaryValue: []byte{1, 2}},
+	}
+	assert.Equal(t, 5+(1+6+3)+(4+6+2), messageSize("hello", attrs))
+
+	body := string(make([]byte, entity.MaxMessageSize))
+	assert.NoError(t, validateMessageSize(body, nil, entity.MaxMessageSize))
+	assert.ErrorIs(t, validateMessageSize(body, attrs, entity.MaxMessageSize), entity.ErrInvalidParameter)
+}
+
+func TestValidateEncodedSize(t *testing.T) {
+	header := messageHeader("id-1")
+	body := strings.Repeat("a", entity.MaxMessageSize)
+	assert.NoError(t, validateEncodedSize(body, header, entity.MaxMessageSize))
+
+	// JSON escapes < as \u003c, so the header grows six times over
+	attrs := map[string]entity.MessageAttributeValue{"html": {DataType: "String", StringValue: strings.Repeat("<", 64*1024)}}
+	assert.NoError(t, setAttributeHeaders(header, entity.HeaderMessageAttributePrefix, attrs))
+	assert.NoError(t, validateMessageSize("body", attrs, entity.MaxMessageSize))
+	assert.ErrorIs(t, validateEncodedSize("body", header, entity.MaxMessageSize), entity.ErrInvalidParameter)
+}
@@ -4,21 +4,35 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"nats/internal/context/logs"
+	"nats/internal/context/traces"
 	"nats/internal/entity"
 	"nats/internal/repo"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
+// receiverConsumer is the durable pull consumer shared by every ReceiveMessage caller of a queue.
+const receiverConsumer = "sqs-receiver"
+
 type MessageService interface {
-	SendMessage(ctx context.Context, queueName, message, subject string) (string, error)
-	SendAsyncMessage(ctx context.Context, queueName, message, subject string) (string, error)
+	SendMessage(ctx context.Context, queueName, account, message, subject string, opts entity.SendOptions) (entity.SendMessageResult, error)
+	SendAsyncMessage(ctx context.Context, queueName, account, message, subject string, opts entity.SendOptions) (entity.SendMessageResult, error)
 	CheckAckStatus(ctx context.Context, id string) (string, error)
+	ReceiveMessage(ctx context.Context, queueName, account string, opts entity.ReceiveOptions) ([]entity.Message, error)
+	DeleteMessage(ctx context.Context, queueName, account, receiptHandle string) error
+	DeleteMessageBatch(ctx context.Context, queueName, account string, entries []entity.DeleteMessageBatchRequestEntry) (entity.DeleteMessageBatchResult, error)
+	ChangeMessageVisibility(ctx context.Context, queueName, account, receiptHandle string, visibilityTimeout int) error
+	ChangeMessageVisibilityBatch(ctx context.Context, queueName, account string, entries []entity.ChangeMessageVisibilityBatchRequestEntry) (entity.ChangeMessageVisibilityBatchResult, error)
+	SendMessageBatch(ctx context.Context, queueName, account string, entries []entity.SendMessageBatchRequestEntry) (entity.SendMessageBatchResult, error)
 }
 
 type messageService struct {
@@ -26,14 +40,16 @@ type messageService struct {
 	timeout    time.Duration
 	natsRepo   repo.NatsRepo
 	valkeyRepo repo.ValkeyRepo
+	receipts   ReceiptCodec
 }
 
-func NewMessageService(dispatcher AckDispatcher, timeout time.Duration, natsRepo repo.NatsRepo, valkeyRepo repo.ValkeyRepo) MessageService {
+func NewMessageService(dispatcher AckDispatcher, timeout time.Duration, natsRepo repo.NatsRepo, valkeyRepo repo.ValkeyRepo, receipts ReceiptCodec) MessageService {
 	return &messageService{
 		dispatcher: dispatcher,
 		timeout:    timeout,
 		natsRepo:   natsRepo,
 		valkeyRepo: valkeyRepo,
+		receipts:   receipts,
 	}
 }
 
@@ -47,42 +63,172 @@ func newAckTask(parentCtx context.Context, id string, future jetstream.PubAckFut
 	}
 }
 
-func (s *messageService) SendMessage(ctx context.Context, queueName, message, subject string) (string, error) {
+func (s *messageService) SendMessage(ctx context.Context, queueName, account, message, subject string, opts entity.SendOptions) (entity.SendMessageResult, error) {
 	logger := logs.GetLogger(ctx)
 	logger.Debug("SendMessage", logs.WithTraceFields(ctx)...)
 
 	if queueName == "" || message == "" {
-		return "", errors.New("missing required fields")
+		return entity.SendMessageResult{}, fmt.Errorf("%w: missing required fields", entity.ErrMissingParameter)
+	}
+	opts, err := s.contentDeduplication(ctx, queueName, account, message, opts)
+	if err != nil {
+		return entity.SendMessageResult{}, err
 	}
-	if subject == "" {
-		subject = queueName
+	maxSize, err := s.queueMaximumMessageSize(ctx, queueName, account, messageSize(message, opts.MessageAttributes))
+	if err != nil {
+		return entity.SendMessageResult{}, err
 	}
 	id := uuid.NewString()
+	subject, header, err := sendTarget(queueName, account, subject, id, message, maxSize, opts)
+	if err != nil {
+		return entity.SendMessageResult{}, err
+	}
 
-	ack, err := s.natsRepo.SendMessage(ctx, message, subject)
+	ack, err := s.natsRepo.SendMessage(ctx, message, subject, header)
 	if err != nil {
 		_ = s.valkeyRepo.StoreAckResult(ctx, id, entity.AckResult{Status: "FAILED"})
-		return "", err
+		return entity.SendMessageResult{}, publishError(err)
+	}
+	id = s.originalMessageId(ctx, ack, id)
+
+	_ = s.valkeyRepo.StoreAckResult(ctx, id, entity.AckResult{Status: "ACK", Sequence: ack.Sequence, Duplicate: ack.Duplicate})
+	result := sendResult(id, message, opts)
+	result.SequenceNumber = strconv.FormatUint(ack.Sequence, 10)
+	if entity.IsFifoQueue(queueName) {
+		result.Duplicate = &ack.Duplicate
 	}
+	return result, nil
+}
 
-	_ = s.valkeyRepo.StoreAckResult(ctx, id, entity.AckResult{Status: "ACK", Sequence: ack.Sequence})
-	return id, nil
+// originalMessageId returns the MessageId of the message stored first when the
+// deduplication window dropped this one.
+func (s *messageService) originalMessageId(ctx context.Context, ack *jetstream.PubAck, id string) string {
+	if !ack.Duplicate {
+		return id
+	}
+	raw, err := s.natsRepo.GetMessage(ctx, ack.Stream, ack.Sequence)
+	if err != nil {
+		return id
+	}
+	return raw.Header.Get(entity.HeaderMessageId)
 }
 
-func (s *messageService) SendAsyncMessage(ctx context.Context, queueName, message, subject string) (string, error) {
+func (s *messageService) SendMessageBatch(ctx context.Context, queueName, account string, entries []entity.SendMessageBatchRequestEntry) (entity.SendMessageBatchResult, error) {
+	ctx, span := traces.StartSpan(ctx, "sendMessageBatch")
+	defer span.End()
+
+	result := entity.SendMessageBatchResult{
+		Successful: []entity.SendMessageBatchResultEntry{},
+		Failed:     []entity.BatchResultErrorEntry{},
+	}
+
+	ids := make([]string, len(entries))
+	totalSize, largest := 0, 0
+	contentDedup := false
+	for i, entry := range entries {
+		ids[i] = entry.Id
+		size := messageSize(entry.MessageBody, entry.MessageAttributes)
+		totalSize += size
+		largest = max(largest, size)
+		contentDedup = contentDedup || entry.MessageDeduplicationId == ""
+	}
+	contentDedup = contentDedup && entity.IsFifoQueue(queueName)
+	if err := validateBatchIds(ids); err != nil {
+		return result, err
+	}
+	if totalSize > entity.MaxMessageSize {
+		return result, fmt.Errorf("%w: %d bytes", entity.ErrBatchRequestTooLong, totalSize)
+	}
+	if contentDedup {
+		enabled, err := s.contentBasedDeduplication(ctx, queueName, account)
+		if err != nil {
+			return result, err
+		}
+		contentDedup = enabled
+	}
+	maxSize, err := s.queueMaximumMessageSize(ctx, queueName, account, largest)
+	if err != nil {
+		return result, err
+	}
+
+	// Invalid entries fail on their own; the rest is published in one go
+	var (
+		msgs    []*nats.Msg
+		pending []entity.SendMessageBatchRequestEntry
+		msgIds  []string
+	)
+	for _, entry := range entries {
+		opts := entry.SendOptions()
+		if contentDedup && opts.MessageDeduplicationId == "" {
+			opts.MessageDeduplicationId = contentDeduplicationId(entry.MessageBody)
+		}
+		id := uuid.NewString()
+		subject, header, err := sendTarget(queueName, account, "", id, entry.MessageBody, maxSize, opts)
+		if err != nil {
+			result.Failed = append(result.Failed, entity.NewBatchResultErrorEntry(entry.Id, err))
+			continue
+		}
+		msgs = append(msgs, &nats.Msg{Subject: subject, Data: []byte(entry.MessageBody), Header: header})
+		pending = append(pending, entry)
+		msgIds = append(msgIds, id)
+	}
+	if len(msgs) == 0 {
+		return result, nil
+	}
+
+	acks, errs := s.natsRepo.PublishBatch(ctx, msgs)
+	for i, entry := range pending {
+		if errs[i] != nil {
+			logs.GetLogger(ctx).Warn("Batch entry publish failed", logs.WithTraceFields(ctx, zap.String("id", entry.Id), zap.Error(errs[i]))...)
+			result.Failed = append(result.Failed, entity.NewBatchResultErrorEntry(entry.Id, publishError(errs[i])))
+			continue
+		}
+		sent := sendResult(s.originalMessageId(ctx, acks[i], msgIds[i]), entry.MessageBody, entry.SendOptions())
+		result.Successful = append(result.Successful, entity.SendMessageBatchResultEntry{
+			Id:                           entry.Id,
+			MessageId:                    sent.MessageId,
+			MD5OfMessageBody:             sent.MD5OfMessageBody,
+			MD5OfMessageAttributes:       sent.MD5OfMessageAttributes,
+			MD5OfMessageSystemAttributes: sent.MD5OfMessageSystemAttributes,
+			SequenceNumber:               strconv.FormatUint(acks[i].Sequence, 10),
+		})
+	}
+	return result, nil
+}
+
+func sendResult(id, message string, opts entity.SendOptions) entity.SendMessageResult {
+	return entity.SendMessageResult{
+		MessageId:                    id,
+		MD5OfMessageBody:             md5Hex([]byte(message)),
+		MD5OfMessageAttributes:       md5OfMessageAttributes(opts.MessageAttributes),
+		MD5OfMessageSystemAttributes: md5OfMessageAttributes(opts.MessageSystemAttributes),
+	}
+}
+
+func (s *messageService) SendAsyncMessage(ctx context.Context, queueName, account, message, subject string, opts entity.SendOptions) (entity.SendMessageResult, error) {
 	logger := logs.GetLogger(ctx)
 	logger.Debug("SendAsyncMessage", logs.WithTraceFields(ctx)...)
 
 	if queueName == "" || message == "" {
-		return "", errors.New("missing required fields")
+		return entity.SendMessageResult{}, fmt.Errorf("%w: missing required fields", entity.ErrMissingParameter)
 	}
-	if subject == "" {
-		subject = queueName
+	opts, err := s.contentDeduplication(ctx, queueName, account, message, opts)
+	if err != nil {
+		return entity.SendMessageResult{}, err
 	}
 
-	ackFuture, err := s.natsRepo.SendAsyncMessage(ctx, message, subject)
+	maxSize, err := s.queueMaximumMessageSize(ctx, queueName, account, messageSize(message, opts.MessageAttributes))
 	if err != nil {
-		return "", err
+		return entity.SendMessageResult{}, err
+	}
+	id := uuid.NewString()
+	subject, header, err := sendTarget(queueName, account, subject, id, message, maxSize, opts)
+	if err != nil {
+		return entity.SendMessageResult{}, err
+	}
+	ackFuture, err := s.natsRepo.SendAsyncMessage(ctx, message, subject, header)
+	if err != nil {
+		return entity.SendMessageResult{}, err
 	}
 
 	// taskCtx is for goroutine context. So, make new context (without cancel, include span and logger)
@@ -92,13 +238,12 @@ func (s *messageService) SendAsyncMessage(ctx context.Context, queueName, messag
 		taskCtx = trace.ContextWithSpanContext(taskCtx, spanCtx)
 	}
 	taskCtx = logs.WithLogger(taskCtx, logger)
-	id := uuid.NewString()
 	_ = s.valkeyRepo.StoreAckResult(taskCtx, id, entity.AckResult{Status: "PENDING"})
 
 	task := newAckTask(taskCtx, id, ackFuture, s.timeout)
 	s.dispatcher.Enqueue(task)
 
-	return id, nil
+	return sendResult(id, message, opts), nil
 }
 
 func (s *messageService) CheckAckStatus(ctx context.Context, id string) (string, error) {
@@ -117,6 +262,9 @@ func (s *messageService) CheckAckStatus(ctx context.Context, id string) (string,
 	case "PENDING":
 		return "PENDING", nil
 	case "ACK":
+		if result.Duplicate {
+			return "ACK " + strconv.FormatUint(result.Sequence, 10) + " DUPLICATE", nil
+		}
 		return "ACK " + strconv.FormatUint(result.Sequence, 10), nil
 	case "FAILED":
 		return "FAILED", nil
@@ -124,3 +272,538 @@ func (s *messageService) CheckAckStatus(ctx context.Context, id string) (string,
 		return "", errors.New("unknown status")
 	}
 }
+
+func (s *messageService) ReceiveMessage(ctx context.Context, queueName, account string, opts entity.ReceiveOptions) ([]entity.Message, error) {
+	ctx, span := traces.StartSpan(ctx, "receiveMessage")
+	defer span.End()
+
+	if queueName == "" {
+		return nil, fmt.Errorf("%w: missing queue name", entity.ErrInvalidParameter)
+	}
+	maxMessages := opts.MaxNumberOfMessages
+	if maxMessages == 0 {
+		maxMessages = 1
+	}
+	if maxMessages < 1 || maxMessages > 10 {
+		return nil, fmt.Errorf("%w: MaxNumberOfMessages must be between 1 and 10", entity.ErrInvalidParameter)
+	}
+	if opts.VisibilityTimeout != nil {
+		if err := validateVisibilityTimeout(*opts.VisibilityTimeout); err != nil {
+			return nil, err
+		}
+	}
+
+	streamName := entity.StreamName(account, queueName)
+	stream, err := s.natsRepo.GetStream(ctx, streamName)
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		return nil, entity.ErrQueueNotFound
+	}
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "natsRepo.GetStream error", err)
+		return nil, err
+	}
+	streamCfg := stream.CachedInfo().Config
+	attrs := entity.QueueAttributes(streamCfg.Metadata)
+	fifo := entity.IsFifoQueue(queueName)
+
+	wait, err := receiveWaitTime(attrs, opts.WaitTimeSeconds)
+	if err != nil {
+		return nil, err
+	}
+	redrive, hasRedrive := queueRedrivePolicy(attrs)
+	queueDelay := queueIntAttribute(attrs, entity.AttrDelaySeconds, 0)
+
+	cons, err := s.natsRepo.GetOrCreateConsumer(ctx, streamName, receiverConsumer)
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		return nil, entity.ErrQueueNotFound
+	}
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "natsRepo.GetOrCreateConsumer error", err)
+		return nil, err
+	}
+	queueVisibility := cons.CachedInfo().Config.AckWait
+
+	// Long polling goes on until a message can be handed out: messages still
+	// delayed or held back by their FIFO group do not end it early
+	deadline := time.Now().Add(wait)
+	messages := make([]entity.Message, 0, maxMessages)
+	for {
+		msgs, err := s.natsRepo.FetchMessages(ctx, cons, maxMessages, time.Until(deadline))
+		if errors.Is(err, context.Canceled) {
+			logs.GetLogger(ctx).Info("Receive cancelled by client", logs.WithTraceFields(ctx)...)
+			return nil, err
+		}
+		if err != nil {
+			traces.RecordSpanError(ctx, span, "natsRepo.FetchMessages error", err)
+			return nil, err
+		}
+
+		// FIFO: last sequence handed out per message group subject in this batch
+		groupHeads := make(map[string]uint64)
+
+		for _, msg := range msgs {
+			meta, err := msg.Metadata()
+			if err != nil {
+				logs.GetLogger(ctx).Warn("Skip message without JetStream metadata", logs.WithTraceFields(ctx)...)
+				continue
+			}
+			handle := entity.ReceiptHandle{
+				Stream:       meta.Stream,
+				Consumer:     meta.Consumer,
+				StreamSeq:    meta.Sequence.Stream,
+				ConsumerSeq:  meta.Sequence.Consumer,
+				NumDelivered: meta.NumDelivered,
+				Timestamp:    meta.Timestamp.UnixNano(),
+			}
+			if delay := deliveryDelay(msg.Headers(), meta, queueDelay); delay > 0 {
+				s.delayMessage(ctx, handle, delay)
+				continue
+			}
+			if fifo {
+				head, err := s.isGroupHead(ctx, streamName, msg, meta, groupHeads[msg.Subject()])
+				if err != nil || !head {
+					s.deferMessage(ctx, handle, fifoDeferDelay)
+					continue
+				}
+				groupHeads[msg.Subject()] = meta.Sequence.Stream
+			}
+			receiveCount := s.countReceive(ctx, meta, streamCfg.MaxAge)
+			if hasRedrive && receiveCount > uint64(redrive.MaxReceiveCount) {
+				err := s.moveToDeadLetter(ctx, msg, meta, redrive)
+				if err == nil {
+					continue
+				}
+				// Deliver it as usual rather than lose it
+				logs.GetLogger(ctx).Warn("Failed to move message to dead-letter queue",
+					logs.WithTraceFields(ctx, zap.Uint64("seq", meta.Sequence.Stream), zap.Error(err))...)
+			}
+			if opts.VisibilityTimeout != nil {
+				// Per-receive override: the fetch already started the queue default AckWait
+				visibility := time.Duration(*opts.VisibilityTimeout) * time.Second
+				if visibility != queueVisibility {
+					if err := s.natsRepo.NakMessage(ctx, handle, visibility); err != nil {
+						logs.GetLogger(ctx).Warn("Failed to apply receive visibility timeout", logs.WithTraceFields(ctx, zap.Error(err))...)
+					}
+				}
+			}
+			s.recordDelivery(ctx, handle, streamCfg.MaxAge)
+			receipt, err := s.receipts.Encode(handle)
+			if err != nil {
+				traces.RecordSpanError(ctx, span, "receipt handle encode error", err)
+				return nil, err
+			}
+			message := entity.Message{
+				MessageId:     messageID(msg.Headers(), meta),
+				ReceiptHandle: receipt,
+				Body:          string(msg.Data()),
+				MD5OfBody:     md5Hex(msg.Data()),
+				Attributes:    messageSystemAttributes(msg.Headers(), meta, receiveCount, opts.AttributeNames),
+			}
+			attrs := attributesFromHeader(msg.Headers(), entity.HeaderMessageAttributePrefix)
+			if selected := selectMessageAttributes(attrs, opts.MessageAttributeNames); len(selected) > 0 {
+				message.MessageAttributes = selected
+				message.MD5OfMessageAttributes = md5OfMessageAttributes(selected)
+			}
+			messages = append(messages, message)
+		}
+		if len(messages) > 0 || time.Until(deadline) <= 0 {
+			return messages, nil
+		}
+	}
+}
+
+func (s *messageService) DeleteMessage(ctx context.Context, queueName, account, receiptHandle string) error {
+	ctx, span := traces.StartSpan(ctx, "deleteMessage")
+	defer span.End()
+
+	if err := s.deleteMessage(ctx, queueName, account, receiptHandle); err != nil {
+		traces.RecordSpanError(ctx, span, "deleteMessage error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *messageService) DeleteMessageBatch(ctx context.Context, queueName, account string, entries []entity.DeleteMessageBatchRequestEntry) (entity.DeleteMessageBatchResult, error) {
+	ctx, span := traces.StartSpan(ctx, "deleteMessageBatch")
+	defer span.End()
+
+	result := entity.DeleteMessageBatchResult{
+		Successful: []entity.DeleteMessageBatchResultEntry{},
+		Failed:     []entity.BatchResultErrorEntry{},
+	}
+
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.Id
+	}
+	if err := validateBatchIds(ids); err != nil {
+		return result, err
+	}
+
+	for _, entry := range entries {
+		if err := s.deleteMessage(ctx, queueName, account, entry.ReceiptHandle); err != nil {
+			logs.GetLogger(ctx).Warn("Batch entry delete failed", logs.WithTraceFields(ctx, zap.String("id", entry.Id), zap.Error(err))...)
+			result.Failed = append(result.Failed, entity.NewBatchResultErrorEntry(entry.Id, err))
+			continue
+		}
+		result.Successful = append(result.Successful, entity.DeleteMessageBatchResultEntry{Id: entry.Id})
+	}
+	return result, nil
+}
+
+func (s *messageService) ChangeMessageVisibility(ctx context.Context, queueName, account, receiptHandle string, visibilityTimeout int) error {
+	ctx, span := traces.StartSpan(ctx, "changeMessageVisibility")
+	defer span.End()
+
+	if err := s.changeMessageVisibility(ctx, queueName, account, receiptHandle, visibilityTimeout); err != nil {
+		traces.RecordSpanError(ctx, span, "changeMessageVisibility error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *messageService) ChangeMessageVisibilityBatch(ctx context.Context, queueName, account string, entries []entity.ChangeMessageVisibilityBatchRequestEntry) (entity.ChangeMessageVisibilityBatchResult, error) {
+	ctx, span := traces.StartSpan(ctx, "changeMessageVisibilityBatch")
+	defer span.End()
+
+	result := entity.ChangeMessageVisibilityBatchResult{
+		Successful: []entity.ChangeMessageVisibilityBatchResultEntry{},
+		Failed:     []entity.BatchResultErrorEntry{},
+	}
+
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.Id
+	}
+	if err := validateBatchIds(ids); err != nil {
+		return result, err
+	}
+
+	for _, entry := range entries {
+		if err := s.changeMessageVisibility(ctx, queueName, account, entry.ReceiptHandle, entry.VisibilityTimeout); err != nil {
+			logs.GetLogger(ctx).Warn("Batch entry visibility change failed", logs.WithTraceFields(ctx, zap.String("id", entry.Id), zap.Error(err))...)
+			result.Failed = append(result.Failed, entity.NewBatchResultErrorEntry(entry.Id, err))
+			continue
+		}
+		result.Successful = append(result.Successful, entity.ChangeMessageVisibilityBatchResultEntry{Id: entry.Id})
+	}
+	return result, nil
+}
+
+func (s *messageService) changeMessageVisibility(ctx context.Context, queueName, account, receiptHandle string, visibilityTimeout int) error {
+	if err := validateVisibilityTimeout(visibilityTimeout); err != nil {
+		return err
+	}
+	handle, err := s.receipts.Decode(receiptHandle)
+	if err != nil {
+		return err
+	}
+	if handle.Stream != entity.StreamName(account, queueName) {
+		return entity.ErrReceiptHandleInvalid
+	}
+	if err := s.checkCurrentDelivery(ctx, handle); err != nil {
+		return err
+	}
+
+	_, err = s.natsRepo.GetMessage(ctx, handle.Stream, handle.StreamSeq)
+	switch {
+	case errors.Is(err, jetstream.ErrStreamNotFound):
+		return entity.ErrQueueNotFound
+	case errors.Is(err, jetstream.ErrMsgNotFound):
+		return entity.ErrReceiptHandleInvalid
+	case err != nil:
+		return err
+	}
+
+	cons, err := s.natsRepo.GetOrCreateConsumer(ctx, handle.Stream, handle.Consumer)
+	if err != nil {
+		return err
+	}
+	visibility := time.Duration(visibilityTimeout) * time.Second
+	return s.setVisibility(ctx, handle, visibility, cons.CachedInfo().Config.AckWait)
+}
+
+// setVisibility maps an SQS visibility timeout onto the consumer AckWait.
+// Extending by exactly the queue default is an in-progress ack; any other
+// value is a NAK delayed by the timeout, and 0 releases the message at once.
+func (s *messageService) setVisibility(ctx context.Context, handle entity.ReceiptHandle, visibility, queueVisibility time.Duration) error {
+	if visibility == queueVisibility {
+		return s.natsRepo.AckMessage(ctx, handle, repo.AckProgress)
+	}
+	return s.natsRepo.NakMessage(ctx, handle, visibility)
+}
+
+// receiveWaitTime resolves the long polling duration: the request value wins,
+// otherwise the queue ReceiveMessageWaitTimeSeconds attribute applies.
+func receiveWaitTime(attrs map[string]string, waitTimeSeconds *int) (time.Duration, error) {
+	if waitTimeSeconds != nil {
+		if *waitTimeSeconds < 0 || *waitTimeSeconds > entity.MaxWaitTimeSeconds {
+			return 0, fmt.Errorf("%w: WaitTimeSeconds must be between 0 and %d", entity.ErrInvalidParameter, entity.MaxWaitTimeSeconds)
+		}
+		return time.Duration(*waitTimeSeconds) * time.Second, nil
+	}
+	sec := queueIntAttribute(attrs, entity.AttrReceiveMessageWaitTimeSeconds, 0)
+	return time.Duration(sec) * time.Second, nil
+}
+
+// queueRedrivePolicy returns the dead-letter settings of the queue, if any.
+func queueRedrivePolicy(attrs map[string]string) (entity.RedrivePolicy, bool) {
+	value, ok := attrs[entity.AttrRedrivePolicy]
+	if !ok {
+		return entity.RedrivePolicy{}, false
+	}
+	policy, err := parseRedrivePolicy(value)
+	return policy, err == nil
+}
+
+// moveToDeadLetter republishes a message that exceeded maxReceiveCount to the
+// dead-letter queue with its original headers, then removes it from the source queue.
+func (s *messageService) moveToDeadLetter(ctx context.Context, msg jetstream.Msg, meta *jetstream.MsgMetadata, policy entity.RedrivePolicy) error {
+	_, dlqAccount, dlqName, err := parseQueueSrn(policy.DeadLetterTargetArn)
+	if err != nil {
+		return err
+	}
+	_, sourceName := entity.QueueName(meta.Stream)
+
+	header := nats.Header{}
+	for key, values := range msg.Headers() {
+		header[key] = append([]string(nil), values...)
+	}
+	header.Set(entity.HeaderMessageId, messageID(msg.Headers(), meta))
+	header.Set(entity.HeaderDeadLetterSource, sourceName)
+	// A redrive back to the source must not be dropped as a duplicate or delayed again
+	header.Del(jetstream.MsgIDHeader)
+	header.Del(entity.HeaderDelaySeconds)
+
+	subject := entity.QueueSubject(dlqAccount, dlqName, header.Get(entity.HeaderMessageGroupId))
+	if _, err := s.natsRepo.SendMessage(ctx, string(msg.Data()), subject, header); err != nil {
+		return err
+	}
+	if err := msg.Ack(); err != nil {
+		return err
+	}
+	if err := s.natsRepo.DeleteMessage(ctx, meta.Stream, meta.Sequence.Stream); err != nil {
+		return err
+	}
+
+	logs.GetLogger(ctx).Info("Message moved to dead-letter queue", logs.WithTraceFields(ctx,
+		zap.String("queue", meta.Stream), zap.String("deadLetterQueue", dlqName),
+		zap.Uint64("seq", meta.Sequence.Stream), zap.Uint64("deliveries", meta.NumDelivered))...)
+	return nil
+}
+
+func validateVisibilityTimeout(sec int) error {
+	if sec < 0 || sec > entity.MaxVisibilityTimeout {
+		return fmt.Errorf("%w: VisibilityTimeout must be between 0 and %d", entity.ErrInvalidParameter, entity.MaxVisibilityTimeout)
+	}
+	return nil
+}
+
+// deleteMessage acknowledges the delivery and removes the message from the stream.
+// A handle whose message is already gone (deleted, purged or expired) or was
+// received again since is stale.
+func (s *messageService) deleteMessage(ctx context.Context, queueName, account, receiptHandle string) error {
+	handle, err := s.receipts.Decode(receiptHandle)
+	if err != nil {
+		return err
+	}
+	if handle.Stream != entity.StreamName(account, queueName) {
+		return entity.ErrReceiptHandleInvalid
+	}
+	if err := s.checkCurrentDelivery(ctx, handle); err != nil {
+		return err
+	}
+
+	if err := s.natsRepo.AckMessage(ctx, handle, repo.AckAck); err != nil {
+		return err
+	}
+
+	err = s.natsRepo.DeleteMessage(ctx, handle.Stream, handle.StreamSeq)
+	switch {
+	case errors.Is(err, jetstream.ErrStreamNotFound):
+		return entity.ErrQueueNotFound
+	case errors.Is(err, jetstream.ErrMsgDeleteUnsuccessful):
+		return entity.ErrReceiptHandleInvalid
+	case err != nil:
+		return err
+	}
+	if err := s.valkeyRepo.ClearCurrentDelivery(ctx, handle.Stream, handle.StreamSeq); err != nil {
+		logs.GetLogger(ctx).Warn("Failed to clear message delivery", logs.WithTraceFields(ctx, zap.Error(err))...)
+	}
+	return nil
+}
+
+// recordDelivery makes handle the current delivery of its message, so the
+// receipt handles of earlier deliveries stop working.
+func (s *messageService) recordDelivery(ctx context.Context, handle entity.ReceiptHandle, retention time.Duration) {
+	if err := s.valkeyRepo.SetCurrentDelivery(ctx, handle.Stream, handle.StreamSeq, handle.ConsumerSeq, receiveStateRetention(retention)); err != nil {
+		logs.GetLogger(ctx).Warn("Failed to record message delivery", logs.WithTraceFields(ctx, zap.Error(err))...)
+	}
+}
+
+// checkCurrentDelivery rejects the receipt handle of a delivery superseded by
+// a later receive of the same message. Messages received before deliveries
+// were recorded have none and keep their handles.
+func (s *messageService) checkCurrentDelivery(ctx context.Context, handle entity.ReceiptHandle) error {
+	current, err := s.valkeyRepo.GetCurrentDelivery(ctx, handle.Stream, handle.StreamSeq)
+	if err != nil {
+		return err
+	}
+	if current != 0 && current != handle.ConsumerSeq {
+		return entity.ErrReceiptHandleInvalid
+	}
+	return nil
+}
+
+// queueMaximumMessageSize returns the MaximumMessageSize of the queue for a
+// message of size bytes. Messages within the lowest limit a queue can set skip
+// the stream lookup.
+func (s *messageService) queueMaximumMessageSize(ctx context.Context, queueName, account string, size int) (int, error) {
+	if size <= entity.MinMaximumMessageSize {
+		return entity.MaxMessageSize, nil
+	}
+	stream, err := s.natsRepo.GetStream(ctx, entity.StreamName(account, queueName))
+	if err != nil {
+		return 0, streamError(err)
+	}
+	return maximumMessageSize(stream.CachedInfo().Config), nil
+}
+
+// sendTarget validates the send options against the queue type and maxSize,
+// the MaximumMessageSize of the queue, and returns the subject and headers the
+// message is published with.
+func sendTarget(queueName, account, subject, id, message string, maxSize int, opts entity.SendOptions) (string, nats.Header, error) {
+	if err := validateMessageSize(message, opts.MessageAttributes, maxSize); err != nil {
+		return "", nil, err
+	}
+	if err := validateMessageAttributes(opts.MessageAttributes); err != nil {
+		return "", nil, err
+	}
+	if err := validateMessageSystemAttributes(opts.MessageSystemAttributes); err != nil {
+		return "", nil, err
+	}
+
+	header := messageHeader(id)
+	if err := setAttributeHeaders(header, entity.HeaderMessageAttributePrefix, opts.MessageAttributes); err != nil {
+		return "", nil, err
+	}
+	if err := setAttributeHeaders(header, entity.HeaderMessageSystemAttributePrefix, opts.MessageSystemAttributes); err != nil {
+		return "", nil, err
+	}
+	if opts.DelaySeconds != nil {
+		if err := validateDelaySeconds(*opts.DelaySeconds); err != nil {
+			return "", nil, err
+		}
+		header.Set(entity.HeaderDelaySeconds, strconv.Itoa(*opts.DelaySeconds))
+	}
+
+	if !entity.IsFifoQueue(queueName) {
+		if opts.MessageGroupId != "" || opts.MessageDeduplicationId != "" {
+			return "", nil, fmt.Errorf("%w: MessageGroupId and MessageDeduplicationId are only valid for FIFO queues", entity.ErrInvalidParameter)
+		}
+		// Messages only go to the queue itself; subject is kept for clients that still send the queue name
+		if subject != "" && subject != queueName {
+			return "", nil, fmt.Errorf("%w: subject must be the queue name", entity.ErrInvalidParameter)
+		}
+		if err := validateEncodedSize(message, header, maxSize); err != nil {
+			return "", nil, err
+		}
+		return entity.QueueSubject(account, queueName, ""), header, nil
+	}
+
+	if subject != "" {
+		return "", nil, fmt.Errorf("%w: subject cannot be set for FIFO queues", entity.ErrInvalidParameter)
+	}
+	if opts.DelaySeconds != nil {
+		return "", nil, fmt.Errorf("%w: DelaySeconds per message is not supported for FIFO queues, set the queue attribute", entity.ErrInvalidParameter)
+	}
+	if opts.MessageGroupId == "" {
+		return "", nil, fmt.Errorf("%w: MessageGroupId is required for FIFO queues", entity.ErrMissingParameter)
+	}
+	if !fifoIdPattern.MatchString(opts.MessageGroupId) {
+		return "", nil, fmt.Errorf("%w: MessageGroupId", entity.ErrInvalidParameter)
+	}
+	if opts.MessageDeduplicationId == "" {
+		return "", nil, fmt.Errorf("%w: MessageDeduplicationId is required unless %s is enabled", entity.ErrMissingParameter, entity.AttrContentBasedDeduplication)
+	}
+	if !fifoIdPattern.MatchString(opts.MessageDeduplicationId) {
+		return "", nil, fmt.Errorf("%w: MessageDeduplicationId", entity.ErrInvalidParameter)
+	}
+
+	header.Set(entity.HeaderMessageGroupId, opts.MessageGroupId)
+	header.Set(jetstream.MsgIDHeader, opts.MessageDeduplicationId)
+	if err := validateEncodedSize(message, header, maxSize); err != nil {
+		return "", nil, err
+	}
+	return entity.QueueSubject(account, queueName, opts.MessageGroupId), header, nil
+}
+
+// jsErrCodeMessageTooLarge is the JetStream error of a message above the
+// stream MaxMsgSize.
+const jsErrCodeMessageTooLarge jetstream.ErrorCode = 10054
+
+// publishError converts a publish error to the service error returned to
+// clients. Nothing answers a publish to the subject of a deleted queue.
+func publishError(err error) error {
+	var apiErr *jetstream.APIError
+	switch {
+	case errors.Is(err, jetstream.ErrNoStreamResponse):
+		return fmt.Errorf("%w: %v", entity.ErrQueueNotFound, err)
+	case errors.Is(err, nats.ErrMaxPayload),
+		errors.As(err, &apiErr) && apiErr.ErrorCode == jsErrCodeMessageTooLarge:
+		return fmt.Errorf("%w: %v", entity.ErrInvalidParameter, err)
+	}
+	return err
+}
+
+// messageSystemAttributes returns the requested system attributes of a received message.
+func messageSystemAttributes(header nats.Header, meta *jetstream.MsgMetadata, receiveCount uint64, names []string) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+	all := slices.Contains(names, entity.SystemAttrAll)
+	wants := func(name string) bool {
+		return all || slices.Contains(names, name)
+	}
+
+	attrs := make(map[string]string)
+	if wants(entity.SystemAttrSentTimestamp) {
+		attrs[entity.SystemAttrSentTimestamp] = strconv.FormatInt(meta.Timestamp.UnixMilli(), 10)
+	}
+	if wants(entity.SystemAttrApproximateReceiveCount) {
+		attrs[entity.SystemAttrApproximateReceiveCount] = strconv.FormatUint(receiveCount, 10)
+	}
+	if group := header.Get(entity.HeaderMessageGroupId); group != "" && wants(entity.SystemAttrMessageGroupId) {
+		attrs[entity.SystemAttrMessageGroupId] = group
+	}
+	if dedup := header.Get(jetstream.MsgIDHeader); dedup != "" && wants(entity.SystemAttrMessageDeduplicationId) {
+		attrs[entity.SystemAttrMessageDeduplicationId] = dedup
+	}
+	if wants(entity.MessageSystemAttributeAWSTraceHeader) {
+		sysAttrs := attributesFromHeader(header, entity.HeaderMessageSystemAttributePrefix)
+		if trace, ok := sysAttrs[entity.MessageSystemAttributeAWSTraceHeader]; ok {
+			attrs[entity.MessageSystemAttributeAWSTraceHeader] = trace.StringValue
+		}
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	return attrs
+}
+
+// messageHeader builds the NATS headers stored alongside a published message.
+func messageHeader(id string) nats.Header {
+	header := nats.Header{}
+	header.Set(entity.HeaderMessageId, id)
+	return header
+}
+
+// messageID returns the MessageId assigned at send time. Messages published
+// directly to NATS have none, so a stable id is derived from their stream position.
+func messageID(header nats.Header, meta *jetstream.MsgMetadata) string {
+	if id := header.Get(entity.HeaderMessageId); id != "" {
+		return id
+	}
+	name := meta.Stream + ":" + strconv.FormatUint(meta.Sequence.Stream, 10)
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(name)).String()
+}
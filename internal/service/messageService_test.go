@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"nats/internal/entity"
+	"nats/internal/repo"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteMessageStaleReceiptHandle(t *testing.T) {
+	natsRepo := newFakeNatsRepo()
+	valkeyRepo := newFakeValkeyRepo()
+	receipts := NewReceiptCodec("secret")
+	s := &messageService{natsRepo: natsRepo, valkeyRepo: valkeyRepo, receipts: receipts}
+	ctx := context.Background()
+
+	first := entity.ReceiptHandle{Stream: entity.StreamName("accountid", "orders"), Consumer: receiverConsumer, StreamSeq: 7, ConsumerSeq: 3, NumDelivered: 1}
+	second := first
+	second.ConsumerSeq, second.NumDelivered = 9, 2
+	staleReceipt, err := receipts.Encode(first)
+	assert.NoError(t, err)
+	receipt, err := receipts.Encode(second)
+	assert.NoError(t, err)
+
+	// The visibility timeout expired and another consumer received the message again
+	s.recordDelivery(ctx, first, 0)
+	s.recordDelivery(ctx, second, 0)
+
+	assert.ErrorIs(t, s.DeleteMessage(ctx, "orders", "accountid", staleReceipt), entity.ErrReceiptHandleInvalid)
+	assert.ErrorIs(t, s.ChangeMessageVisibility(ctx, "orders", "accountid", staleReceipt, 30), entity.ErrReceiptHandleInvalid)
+	assert.Empty(t, natsRepo.acks)
+	assert.Empty(t, natsRepo.deleted)
+
+	assert.NoError(t, s.DeleteMessage(ctx, "orders", "accountid", receipt))
+	assert.Equal(t, []uint64{7}, natsRepo.deleted)
+	assert.Zero(t, valkeyRepo.deliveries[7])
+}
+
+func TestVisibilityChangesAreNotReceives(t *testing.T) {
+	natsRepo := newFakeNatsRepo()
+	s := &messageService{natsRepo: natsRepo, valkeyRepo: newFakeValkeyRepo(), receipts: NewReceiptCodec("secret")}
+	ctx := context.Background()
+
+	cfg := repo.NewStreamConfig(entity.StreamName("accountid", "orders"), entity.StreamSubject("accountid", "orders"), entity.OwnerMetadata("accountid", "kr-west1"))
+	applyQueueAttributes(&cfg, map[string]string{
+		entity.AttrRedrivePolicy: `{"deadLetterTargetArn":"srn:scp:sns:kr-west1:accountid:orders-dlq","maxReceiveCount":2}`,
+	})
+	natsRepo.addStream(cfg)
+
+	var acks []string
+	delivery := func(numDelivered, consumerSeq uint64) jetstream.Msg {
+		return testMsg{
+			subject: entity.QueueSubject("accountid", "orders", ""),
+			header:  messageHeader("id-1"),
+			data:    []byte("body"),
+			acks:    &acks,
+			meta: &jetstream.MsgMetadata{
+				Stream:       cfg.Name,
+				Consumer:     receiverConsumer,
+				NumDelivered: numDelivered,
+				Sequence:     jetstream.SequencePair{Stream: 7, Consumer: consumerSeq},
+				Timestamp:    time.Now(),
+			},
+		}
+	}
+	receive := func(msg jetstream.Msg) []entity.Message {
+		natsRepo.fetches = append(natsRepo.fetches, []jetstream.Msg{msg})
+		opts := entity.ReceiveOptions{AttributeNames: []string{entity.SystemAttrApproximateReceiveCount}}
+		msgs, err := s.ReceiveMessage(ctx, "orders", "accountid", opts)
+		assert.NoError(t, err)
+		return msgs
+	}
+
+	msgs := receive(delivery(1, 1))
+	assert.Len(t, msgs, 1)
+	// The client keeps extending the visibility while it works on the message
+	for range 3 {
+		assert.NoError(t, s.ChangeMessageVisibility(ctx, "orders", "accountid", msgs[0].ReceiptHandle, 60))
+	}
+	assert.Len(t, natsRepo.naks, 3)
+
+	// The client crashed; the JetStream delivery count is well past the receives
+	msgs = receive(delivery(5, 6))
+	assert.Len(t, msgs, 1)
+	assert.Equal(t, "2", msgs[0].Attributes[entity.SystemAttrApproximateReceiveCount])
+	assert.Empty(t, natsRepo.sent)
+
+	// The third receive exceeds maxReceiveCount
+	msgs = receive(delivery(6, 9))
+	assert.Empty(t, msgs)
+	if assert.Len(t, natsRepo.sent, 1) {
+		assert.Equal(t, entity.QueueSubject("accountid", "orders-dlq", ""), natsRepo.sent[0].Subject)
+	}
+	assert.Equal(t, []string{repo.AckAck}, acks)
+	assert.Equal(t, []uint64{7}, natsRepo.deleted)
+}
+
+func TestReceiveMessageLongPollSkipsDeferredMessages(t *testing.T) {
+	natsRepo := newFakeNatsRepo()
+	s := &messageService{natsRepo: natsRepo, valkeyRepo: newFakeValkeyRepo(), receipts: NewReceiptCodec("secret")}
+	ctx := context.Background()
+
+	cfg := repo.NewStreamConfig(entity.StreamName("accountid", "orders"), entity.StreamSubject("accountid", "orders"), entity.OwnerMetadata("accountid", "kr-west1"))
+	natsRepo.addStream(cfg)
+
+	message := func(seq uint64, delaySeconds string) jetstream.Msg {
+		header := messageHeader("id-" + delaySeconds)
+		header.Set(entity.HeaderDelaySeconds, delaySeconds)
+		return testMsg{
+			subject: entity.QueueSubject("accountid", "orders", ""),
+			header:  header,
+			data:    []byte("body"),
+			meta: &jetstream.MsgMetadata{
+				Stream:       cfg.Name,
+				Consumer:     receiverConsumer,
+				NumDelivered: 1,
+				Sequence:     jetstream.SequencePair{Stream: seq, Consumer: seq},
+				Timestamp:    time.Now(),
+			},
+		}
+	}
+	// The first fetch only finds a delayed message, the ready one arrives later
+	natsRepo.fetches = [][]jetstream.Msg{{message(1, "60")}, {message(2, "0")}}
+
+	wait := 5
+	msgs, err := s.ReceiveMessage(ctx, "orders", "accountid", entity.ReceiveOptions{WaitTimeSeconds: &wait})
+	assert.NoError(t, err)
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, "id-0", msgs[0].MessageId)
+	}
+	assert.Equal(t, 2, natsRepo.fetchCalls)
+	assert.Len(t, natsRepo.naks, 1)
+
+	// Nothing deliverable: the long poll lasts the whole wait
+	natsRepo.fetches = [][]jetstream.Msg{{message(1, "60")}}
+	wait = 1
+	start := time.Now()
+	msgs, err = s.ReceiveMessage(ctx, "orders", "accountid", entity.ReceiveOptions{WaitTimeSeconds: &wait})
+	assert.NoError(t, err)
+	assert.Empty(t, msgs)
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+}
+
+func TestSendMessageBatchStandardQueue(t *testing.T) {
+	natsRepo := newFakeNatsRepo()
+	natsRepo.addStream(repo.NewStreamConfig(entity.StreamName("accountid", "orders"), entity.QueueSubject("accountid", "orders", ""), nil))
+	s := &messageService{natsRepo: natsRepo, valkeyRepo: newFakeValkeyRepo(), receipts: NewReceiptCodec("secret")}
+
+	result, err := s.SendMessageBatch(context.Background(), "orders", "accountid", []entity.SendMessageBatchRequestEntry{
+		{Id: "first", MessageBody: "one"},
+		{Id: "second", MessageBody: "two"},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Failed)
+	assert.Len(t, result.Successful, 2)
+	assert.Len(t, natsRepo.sent, 2)
+}
+
+func TestSendMessageQueueMaximumMessageSize(t *testing.T) {
+	natsRepo := newFakeNatsRepo()
+	cfg := repo.NewStreamConfig(entity.StreamName("accountid", "orders"), entity.QueueSubject("accountid", "orders", ""), nil)
+	applyQueueAttributes(&cfg, map[string]string{entity.AttrMaximumMessageSize: "2048"})
+	natsRepo.addStream(cfg)
+	s := &messageService{natsRepo: natsRepo, valkeyRepo: newFakeValkeyRepo(), receipts: NewReceiptCodec("secret")}
+	ctx := context.Background()
+
+	fits := strings.Repeat("a", 2048)
+	tooLarge := fits + "a"
+	_, err := s.SendMessage(ctx, "orders", "accountid", tooLarge, "", entity.SendOptions{})
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+	_, err = s.SendMessage(ctx, "orders", "accountid", fits, "", entity.SendOptions{})
+	assert.NoError(t, err)
+
+	result, err := s.SendMessageBatch(ctx, "orders", "accountid", []entity.SendMessageBatchRequestEntry{
+		{Id: "fits", MessageBody: fits},
+		{Id: "large", MessageBody: tooLarge},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, result.Successful, 1)
+	if assert.Len(t, result.Failed, 1) {
+		assert.Equal(t, "large", result.Failed[0].Id)
+	}
+	assert.Len(t, natsRepo.sent, 2)
+}
+
+func TestPublishError(t *testing.T) {
+	assert.ErrorIs(t, publishError(jetstream.ErrNoStreamResponse), entity.ErrQueueNotFound)
+	assert.ErrorIs(t, publishError(nats.ErrMaxPayload), entity.ErrInvalidParameter)
+	assert.ErrorIs(t, publishError(&jetstream.APIError{Code: 400, ErrorCode: jsErrCodeMessageTooLarge}), entity.ErrInvalidParameter)
+	assert.Equal(t, nats.ErrTimeout, publishError(nats.ErrTimeout))
+}
@@ -0,0 +1,406 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"nats/internal/context/logs"
+	"nats/internal/context/traces"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+const (
+	// moveTaskLeaseTTL is how long a task stays owned by an instance without a checkpoint.
+	moveTaskLeaseTTL = 30 * time.Second
+	// moveTaskCheckpoint is how often progress is persisted and the lease extended.
+	moveTaskCheckpoint = time.Second
+	// moveTaskResumeInterval is how often orphaned tasks of crashed instances are picked up.
+	moveTaskResumeInterval = 15 * time.Second
+	// maxMessagesPerSecond is the SQS upper bound of MaxNumberOfMessagesPerSecond.
+	maxMessagesPerSecond = 500
+)
+
+var (
+	errMoveTaskCancelled = errors.New("message move task cancelled")
+	errMoveTaskShutdown  = errors.New("api server shutdown")
+	errMoveTaskLeaseLost = errors.New("message move task lease taken by another instance")
+)
+
+// MoveTaskService redrives messages from a dead-letter queue back to a source queue.
+type MoveTaskService interface {
+	Start(ctx context.Context)
+	Stop()
+	StartMessageMoveTask(ctx context.Context, account, sourceArn, destinationArn string, maxPerSecond int) (string, error)
+	ListMessageMoveTasks(ctx context.Context, account, sourceArn string, maxResults int) ([]entity.MessageMoveTask, error)
+	CancelMessageMoveTask(ctx context.Context, account, taskHandle string) (int64, error)
+}
+
+type moveTaskService struct {
+	natsRepo   repo.NatsRepo
+	valkeyRepo repo.ValkeyRepo
+	region     string
+	instanceID string
+
+	// baseCtx parents every task so Stop interrupts them all
+	baseCtx context.Context
+	stop    context.CancelCauseFunc
+
+	mu      sync.Mutex
+	running map[string]context.CancelCauseFunc
+	wg      sync.WaitGroup
+}
+
+func NewMoveTaskService(natsRepo repo.NatsRepo, valkeyRepo repo.ValkeyRepo, cfg *config.Config) MoveTaskService {
+	baseCtx, stop := context.WithCancelCause(context.Background())
+	return &moveTaskService{
+		natsRepo:   natsRepo,
+		valkeyRepo: valkeyRepo,
+		region:     cfg.Region,
+		instanceID: uuid.NewString(),
+		baseCtx:    baseCtx,
+		stop:       stop,
+		running:    make(map[string]context.CancelCauseFunc),
+	}
+}
+
+// Start resumes tasks left RUNNING by a previous process and keeps adopting
+// tasks whose owner stopped extending its lease. ctx provides the logger.
+func (s *moveTaskService) Start(ctx context.Context) {
+	s.baseCtx = logs.WithLogger(s.baseCtx, logs.GetLogger(ctx))
+	ctx = s.baseCtx
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(moveTaskResumeInterval)
+		defer ticker.Stop()
+		for {
+			s.resumeOrphans(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop interrupts local tasks without changing their status, so another
+// instance (or the next start) resumes them from the last checkpoint.
+func (s *moveTaskService) Stop() {
+	s.stop(errMoveTaskShutdown)
+	s.wg.Wait()
+}
+
+func (s *moveTaskService) StartMessageMoveTask(ctx context.Context, account, sourceArn, destinationArn string, maxPerSecond int) (string, error) {
+	ctx, span := traces.StartSpan(ctx, "startMessageMoveTask")
+	defer span.End()
+
+	if maxPerSecond < 0 || maxPerSecond > maxMessagesPerSecond {
+		return "", fmt.Errorf("%w: MaxNumberOfMessagesPerSecond must be between 1 and %d", entity.ErrInvalidParameter, maxMessagesPerSecond)
+	}
+	sourceName, err := resolveQueueSrn(sourceArn, account, s.region)
+	if err != nil {
+		return "", fmt.Errorf("SourceArn: %w", err)
+	}
+	if destinationArn != "" {
+		destName, err := resolveQueueSrn(destinationArn, account, s.region)
+		if err != nil {
+			return "", fmt.Errorf("DestinationArn: %w", err)
+		}
+		if _, err := s.natsRepo.GetStream(ctx, entity.StreamName(account, destName)); err != nil {
+			return "", streamError(err)
+		}
+	}
+
+	source, err := s.natsRepo.GetStream(ctx, entity.StreamName(account, sourceName))
+	if err != nil {
+		return "", streamError(err)
+	}
+
+	recent, err := s.valkeyRepo.ListMoveTasks(ctx, sourceArn, 1)
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "valkeyRepo.ListMoveTasks error", err)
+		return "", err
+	}
+	if len(recent) > 0 && recent[0].Active() {
+		return "", entity.ErrMoveTaskAlreadyRunning
+	}
+
+	// Only the messages present now are moved
+	state := source.CachedInfo().State
+	task := entity.MessageMoveTaskRecord{
+		MessageMoveTask: entity.MessageMoveTask{
+			TaskHandle:                        uuid.NewString(),
+			SourceArn:                         sourceArn,
+			DestinationArn:                    destinationArn,
+			MaxNumberOfMessagesPerSecond:      maxPerSecond,
+			Status:                            entity.MoveTaskRunning,
+			ApproximateNumberOfMessagesToMove: int64(state.Msgs),
+			StartedTimestamp:                  time.Now().UnixMilli(),
+		},
+		NextSequence: state.FirstSeq,
+		EndSequence:  state.LastSeq,
+	}
+	if err := s.valkeyRepo.AddMoveTask(ctx, task); err != nil {
+		traces.RecordSpanError(ctx, span, "valkeyRepo.AddMoveTask error", err)
+		return "", err
+	}
+
+	s.launch(ctx, task)
+	return task.TaskHandle, nil
+}
+
+func (s *moveTaskService) ListMessageMoveTasks(ctx context.Context, account, sourceArn string, maxResults int) ([]entity.MessageMoveTask, error) {
+	if maxResults == 0 {
+		maxResults = 1
+	}
+	if maxResults < 1 || maxResults > 10 {
+		return nil, fmt.Errorf("%w: MaxResults must be between 1 and 10", entity.ErrInvalidParameter)
+	}
+	if _, err := resolveQueueSrn(sourceArn, account, s.region); err != nil {
+		return nil, fmt.Errorf("SourceArn: %w", err)
+	}
+
+	records, err := s.valkeyRepo.ListMoveTasks(ctx, sourceArn, maxResults)
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]entity.MessageMoveTask, len(records))
+	for i, record := range records {
+		tasks[i] = record.MessageMoveTask
+	}
+	return tasks, nil
+}
+
+func (s *moveTaskService) CancelMessageMoveTask(ctx context.Context, account, taskHandle string) (int64, error) {
+	task, err := s.valkeyRepo.GetMoveTask(ctx, taskHandle)
+	if err != nil {
+		return 0, entity.ErrMoveTaskNotFound
+	}
+	if _, err := resolveQueueSrn(task.SourceArn, account, s.region); err != nil {
+		return 0, err
+	}
+
+	// The owning instance sees CANCELLING at its next checkpoint
+	task, err = s.valkeyRepo.CancelMoveTask(ctx, taskHandle)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	cancel, ok := s.running[taskHandle]
+	s.mu.Unlock()
+	if ok {
+		cancel(errMoveTaskCancelled)
+	}
+	return task.ApproximateNumberOfMessagesMoved, nil
+}
+
+// resumeOrphans adopts RUNNING tasks that no live instance holds a lease for.
+func (s *moveTaskService) resumeOrphans(ctx context.Context) {
+	handles, err := s.valkeyRepo.ListRunningMoveTasks(ctx)
+	if err != nil {
+		logs.GetLogger(ctx).Warn("Failed to list running message move tasks", zap.Error(err))
+		return
+	}
+
+	for _, handle := range handles {
+		s.mu.Lock()
+		_, local := s.running[handle]
+		s.mu.Unlock()
+		if local {
+			continue
+		}
+
+		task, err := s.valkeyRepo.GetMoveTask(ctx, handle)
+		if err != nil {
+			continue
+		}
+		if s.launch(ctx, task) {
+			logs.GetLogger(ctx).Info("Resume message move task", zap.String("taskHandle", handle), zap.Uint64("nextSeq", task.NextSequence))
+		}
+	}
+}
+
+// launch runs the task in the background if this instance wins its lease.
+func (s *moveTaskService) launch(ctx context.Context, task entity.MessageMoveTaskRecord) bool {
+	ok, err := s.valkeyRepo.AcquireMoveTaskLease(ctx, task.TaskHandle, s.instanceID, moveTaskLeaseTTL)
+	if err != nil || !ok {
+		return false
+	}
+
+	// The task outlives the HTTP request that started it
+	taskCtx, cancel := context.WithCancelCause(s.baseCtx)
+
+	s.mu.Lock()
+	s.running[task.TaskHandle] = cancel
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, task.TaskHandle)
+			s.mu.Unlock()
+			cancel(nil)
+		}()
+		s.run(taskCtx, task)
+	}()
+	return true
+}
+
+func (s *moveTaskService) run(ctx context.Context, task entity.MessageMoveTaskRecord) {
+	logger := logs.GetLogger(ctx).With(zap.String("taskHandle", task.TaskHandle), zap.String("source", task.SourceArn))
+	err := s.move(ctx, &task)
+
+	// Persisting the final state must not depend on the cancelled task context
+	storeCtx := context.WithoutCancel(ctx)
+	switch {
+	case errors.Is(err, errMoveTaskLeaseLost):
+		logger.Warn("Message move task stopped, its lease is lost", zap.Error(err))
+		return
+	case errors.Is(err, errMoveTaskShutdown):
+		logger.Info("Message move task paused for shutdown", zap.Uint64("nextSeq", task.NextSequence))
+		_ = s.valkeyRepo.StoreMoveTask(storeCtx, task)
+		_ = s.valkeyRepo.ReleaseMoveTaskLease(storeCtx, task.TaskHandle)
+		return
+	case errors.Is(err, errMoveTaskCancelled):
+		task.Status = entity.MoveTaskCancelled
+	case err != nil:
+		task.Status = entity.MoveTaskFailed
+		task.FailureReason = err.Error()
+	default:
+		task.Status = entity.MoveTaskCompleted
+	}
+
+	if err := s.valkeyRepo.FinishMoveTask(storeCtx, task); err != nil {
+		logger.Error("Failed to store message move task result", zap.Error(err))
+	}
+	_ = s.valkeyRepo.ReleaseMoveTaskLease(storeCtx, task.TaskHandle)
+	logger.Info("Message move task finished", zap.String("status", task.Status),
+		zap.Int64("moved", task.ApproximateNumberOfMessagesMoved), zap.String("reason", task.FailureReason))
+}
+
+// move walks the dead-letter stream from the task cursor, republishing each
+// message to its destination before deleting it from the dead-letter queue.
+func (s *moveTaskService) move(ctx context.Context, task *entity.MessageMoveTaskRecord) error {
+	_, account, sourceName, err := parseQueueSrn(task.SourceArn)
+	if err != nil {
+		return err
+	}
+	destName := ""
+	if task.DestinationArn != "" {
+		_, _, destName, _ = parseQueueSrn(task.DestinationArn)
+	}
+
+	source, err := s.natsRepo.GetStream(ctx, entity.StreamName(account, sourceName))
+	if err != nil {
+		return err
+	}
+
+	var throttle <-chan time.Time
+	if task.MaxNumberOfMessagesPerSecond > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(task.MaxNumberOfMessagesPerSecond))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	lastCheckpoint := time.Now()
+	for ; task.NextSequence <= task.EndSequence && task.EndSequence > 0; task.NextSequence++ {
+		if err := context.Cause(ctx); err != nil {
+			return err
+		}
+		if time.Since(lastCheckpoint) >= moveTaskCheckpoint {
+			if err := s.checkpoint(ctx, task); err != nil {
+				return err
+			}
+			lastCheckpoint = time.Now()
+		}
+
+		raw, err := source.GetMsg(ctx, task.NextSequence)
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			continue
+		}
+		if err != nil {
+			if cause := context.Cause(ctx); cause != nil {
+				return cause
+			}
+			return fmt.Errorf("read message %d: %w", task.NextSequence, err)
+		}
+
+		target := destName
+		if target == "" {
+			target = raw.Header.Get(entity.HeaderDeadLetterSource)
+		}
+		if target == "" {
+			return fmt.Errorf("message %d has no source queue, set DestinationArn", task.NextSequence)
+		}
+
+		if throttle != nil {
+			select {
+			case <-throttle:
+			case <-ctx.Done():
+				return context.Cause(ctx)
+			}
+		}
+
+		header := nats.Header{}
+		for key, values := range raw.Header {
+			header[key] = append([]string(nil), values...)
+		}
+		header.Del(entity.HeaderDeadLetterSource)
+		header.Del(jetstream.MsgIDHeader)
+		header.Del(entity.HeaderDelaySeconds)
+
+		subject := entity.QueueSubject(account, target, header.Get(entity.HeaderMessageGroupId))
+		if _, err := s.natsRepo.SendMessage(ctx, string(raw.Data), subject, header); err != nil {
+			return fmt.Errorf("republish message %d to %s: %w", task.NextSequence, target, err)
+		}
+		if err := source.DeleteMsg(ctx, task.NextSequence); err != nil && !errors.Is(err, jetstream.ErrMsgDeleteUnsuccessful) {
+			return fmt.Errorf("delete message %d: %w", task.NextSequence, err)
+		}
+		task.ApproximateNumberOfMessagesMoved++
+	}
+	return nil
+}
+
+// checkpoint extends the lease, persists progress and picks up cancellations
+// requested through another instance.
+func (s *moveTaskService) checkpoint(ctx context.Context, task *entity.MessageMoveTaskRecord) error {
+	ok, err := s.valkeyRepo.AcquireMoveTaskLease(ctx, task.TaskHandle, s.instanceID, moveTaskLeaseTTL)
+	if err != nil {
+		// The lease may expire before it can be extended again, and whoever
+		// adopts the task then resumes it from the last checkpoint
+		return fmt.Errorf("%w: extend lease: %v", errMoveTaskLeaseLost, err)
+	}
+	if !ok {
+		return errMoveTaskLeaseLost
+	}
+	stored, err := s.valkeyRepo.CheckpointMoveTask(ctx, *task)
+	if err != nil {
+		return err
+	}
+	if !stored {
+		return errMoveTaskCancelled
+	}
+	return nil
+}
+
+// streamError converts a stream lookup error to the service error returned to clients.
+func streamError(err error) error {
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		return entity.ErrQueueNotFound
+	}
+	return err
+}
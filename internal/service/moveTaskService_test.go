@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"nats/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveTaskCheckpointLease(t *testing.T) {
+	valkeyRepo := newFakeValkeyRepo()
+	s := &moveTaskService{valkeyRepo: valkeyRepo, instanceID: "instance-1"}
+	task := &entity.MessageMoveTaskRecord{MessageMoveTask: entity.MessageMoveTask{TaskHandle: "task-1"}}
+
+	assert.NoError(t, s.checkpoint(context.Background(), task))
+
+	// A lease that cannot be extended stops the task without storing its state
+	valkeyRepo.leaseErr = errors.New("valkey unavailable")
+	assert.ErrorIs(t, s.checkpoint(context.Background(), task), errMoveTaskLeaseLost)
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+
+	"nats/internal/context/traces"
+	"nats/internal/entity"
+)
+
+// permissionLabelPattern is the SQS rule for AddPermission labels.
+var permissionLabelPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,80}$`)
+
+// permissionActions are the actions AddPermission may grant.
+var permissionActions = map[string]struct{}{
+	"*":                          {},
+	"SendMessage":                {},
+	"ReceiveMessage":             {},
+	"DeleteMessage":              {},
+	"ChangeMessageVisibility":    {},
+	"GetQueueAttributes":         {},
+	"GetQueueUrl":                {},
+	"ListDeadLetterSourceQueues": {},
+	"PurgeQueue":                 {},
+}
+
+// ownerActions change who may access the queue, only the owner may perform them.
+var ownerActions = map[string]struct{}{
+	"AddPermission":      {},
+	"RemovePermission":   {},
+	"SetQueueAttributes": {},
+}
+
+// AuthorizeQueueAction lets the owner do anything on its queue. Any other
+// account or service needs a statement of the queue Policy allowing the
+// action and no statement denying it.
+func (s *queueService) AuthorizeQueueAction(ctx context.Context, name, owner string, req entity.AccessRequest) error {
+	if req.Service == "" && req.Account == owner {
+		return nil
+	}
+	ctx, span := traces.StartSpan(ctx, "authorizeQueueAction")
+	defer span.End()
+
+	queue := makeQueueSrn(s.cfg.Region, owner, name)
+	principal := req.Account
+	if req.Service != "" {
+		principal = req.Service
+	}
+	denied := fmt.Errorf("%w: %s is not allowed to perform %s%s on %s", entity.ErrAuthorization, principal, entity.SqsActionPrefix, req.Action, queue.QueueSrn)
+
+	if _, ok := ownerActions[req.Action]; ok {
+		return denied
+	}
+	stream, err := s.queueStream(ctx, name, owner)
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "queueStream error", err)
+		return err
+	}
+	value, ok := entity.QueueAttributes(stream.CachedInfo().Config.Metadata)[entity.AttrPolicy]
+	if !ok {
+		return denied
+	}
+	policy, err := parsePolicy(value)
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "parsePolicy error", err)
+		return denied
+	}
+	req.Resource = queue.QueueSrn
+	if policy.Evaluate(req) != entity.PolicyAllow {
+		return denied
+	}
+	return nil
+}
+
+// AddPermission adds a statement labeled label to the queue Policy that allows
+// the accounts to perform the actions on the queue.
+func (s *queueService) AddPermission(ctx context.Context, name, account, label string, accounts, actions []string) error {
+	ctx, span := traces.StartSpan(ctx, "addPermission")
+	defer span.End()
+
+	if !permissionLabelPattern.MatchString(label) {
+		return fmt.Errorf("%w: label %q must be 1 to 80 alphanumeric characters, hyphens or underscores", entity.ErrInvalidParameter, label)
+	}
+	if len(accounts) == 0 {
+		return fmt.Errorf("%w: AWSAccountIds", entity.ErrMissingParameter)
+	}
+	for _, principal := range accounts {
+		if err := entity.ValidateAccountId(principal); err != nil {
+			return err
+		}
+	}
+	if len(actions) == 0 {
+		return fmt.Errorf("%w: Actions", entity.ErrMissingParameter)
+	}
+	statement := entity.PolicyStatement{
+		Sid:       label,
+		Effect:    entity.EffectAllow,
+		Principal: &entity.PolicyPrincipal{AWS: accounts},
+		Resource:  entity.StringList{makeQueueSrn(s.cfg.Region, account, name).QueueSrn},
+	}
+	for _, action := range actions {
+		if _, ok := permissionActions[action]; !ok {
+			return fmt.Errorf("%w: action %q cannot be granted", entity.ErrInvalidParameter, action)
+		}
+		statement.Action = append(statement.Action, entity.SqsActionPrefix+action)
+	}
+
+	err := s.updatePolicy(ctx, name, account, func(policy *entity.Policy) error {
+		for _, current := range policy.Statement {
+			if current.Sid == label {
+				return fmt.Errorf("%w: label %q already exists", entity.ErrInvalidParameter, label)
+			}
+		}
+		policy.Statement = append(policy.Statement, statement)
+		return nil
+	})
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "updatePolicy error", err)
+	}
+	return err
+}
+
+// RemovePermission removes the statement labeled label from the queue Policy.
+// Removing the last statement removes the Policy.
+func (s *queueService) RemovePermission(ctx context.Context, name, account, label string) error {
+	ctx, span := traces.StartSpan(ctx, "removePermission")
+	defer span.End()
+
+	if label == "" {
+		return fmt.Errorf("%w: Label", entity.ErrMissingParameter)
+	}
+	err := s.updatePolicy(ctx, name, account, func(policy *entity.Policy) error {
+		for i, current := range policy.Statement {
+			if current.Sid == label {
+				policy.Statement = append(policy.Statement[:i], policy.Statement[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: label %q does not exist", entity.ErrInvalidParameter, label)
+	})
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "updatePolicy error", err)
+	}
+	return err
+}
+
+// updatePolicy applies change to the Policy of the queue, or to an empty one,
+// and stores the result as the Policy attribute.
+func (s *queueService) updatePolicy(ctx context.Context, name, account string, change func(policy *entity.Policy) error) error {
+	return s.withQueueLock(ctx, entity.StreamName(account, name), func() error {
+		return s.changePolicy(ctx, name, account, change)
+	})
+}
+
+func (s *queueService) changePolicy(ctx context.Context, name, account string, change func(policy *entity.Policy) error) error {
+	stream, err := s.queueStream(ctx, name, account)
+	if err != nil {
+		return err
+	}
+
+	cfg := stream.CachedInfo().Config
+	policy := entity.Policy{Version: entity.PolicyVersion, Id: makeQueueSrn(s.cfg.Region, account, name).QueueSrn + "/SQSDefaultPolicy"}
+	if value, ok := entity.QueueAttributes(cfg.Metadata)[entity.AttrPolicy]; ok {
+		if policy, err = parsePolicy(value); err != nil {
+			return fmt.Errorf("%w: %s: %v", entity.ErrInvalidAttributeValue, entity.AttrPolicy, err)
+		}
+	}
+	if err := change(&policy); err != nil {
+		return err
+	}
+
+	var value string
+	if len(policy.Statement) > 0 {
+		b, err := json.Marshal(policy)
+		if err != nil {
+			return err
+		}
+		value = string(b)
+	}
+	cfg.Metadata = maps.Clone(cfg.Metadata)
+	applyQueueAttributes(&cfg, map[string]string{entity.AttrPolicy: value})
+	_, err = s.natsRepo.UpdateStream(ctx, cfg)
+	return err
+}
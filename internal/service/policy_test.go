@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"nats/internal/entity"
+	"nats/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testQueueSrn = "srn:scp:sns:kr-west1:accountid:orders"
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := parsePolicy(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"sqs:SendMessage"}]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StringList{"sqs:SendMessage"}, policy.Statement[0].Action)
+	assert.True(t, policy.Statement[0].Principal.Any)
+
+	for _, value := range []string{
+		`{"Statement":[{"Effect":"Maybe","Principal":"*","Action":"sqs:*"}]}`,
+		`{"Statement":[{"Effect":"Allow","Action":"sqs:*"}]}`,
+		`{"Statement":[{"Effect":"Allow","Principal":"*"}]}`,
+		`{"Statement":[{"Effect":"Allow","Principal":"*","Action":"sqs:*","Condition":{"DateGreaterThan":{"aws:CurrentTime":"2026-01-01"}}}]}`,
+		`not json`,
+	} {
+		_, err := parsePolicy(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	policy, err := parsePolicy(`{"Statement":[
+		{"Effect":"Allow","Principal":{"AWS":["partner"]},"Action":["SQS:SendMessage","sqs:Get*"],"Resource":"` + testQueueSrn + `",
+		 "Condition":{"IpAddress":{"aws:SourceIp":"10.0.0.0/8"}}},
+		{"Effect":"Allow","Principal":{"Service":"sns.amazonaws.com"},"Action":"sqs:SendMessage",
+		 "Condition":{"ArnLike":{"aws:SourceArn":"srn:scp:sns:kr-west1:accountid:orders-*"}}},
+		{"Effect":"Deny","Principal":"*","Action":"sqs:*","Condition":{"IpAddress":{"aws:SourceIp":"10.0.0.13"}}}
+	]}`)
+	assert.NoError(t, err)
+
+	fromIp := func(ip string) map[string]string { return map[string]string{entity.ConditionSourceIp: ip} }
+	for _, tc := range []struct {
+		req  entity.AccessRequest
+		want entity.PolicyDecision
+	}{
+		{entity.AccessRequest{Account: "partner", Action: "SendMessage", Resource: testQueueSrn, Conditions: fromIp("10.1.2.3")}, entity.PolicyAllow},
+		{entity.AccessRequest{Account: "partner", Action: "GetQueueAttributes", Resource: testQueueSrn, Conditions: fromIp("10.1.2.3")}, entity.PolicyAllow},
+		{entity.AccessRequest{Account: "partner", Action: "ReceiveMessage", Resource: testQueueSrn, Conditions: fromIp("10.1.2.3")}, entity.PolicyNoMatch},
+		{entity.AccessRequest{Account: "partner", Action: "SendMessage", Resource: testQueueSrn, Conditions: fromIp("192.168.0.1")}, entity.PolicyNoMatch},
+		{entity.AccessRequest{Account: "partner", Action: "SendMessage", Resource: testQueueSrn + "-dlq", Conditions: fromIp("10.1.2.3")}, entity.PolicyNoMatch},
+		{entity.AccessRequest{Account: "partner", Action: "SendMessage", Resource: testQueueSrn, Conditions: fromIp("10.0.0.13")}, entity.PolicyDeny},
+		{entity.AccessRequest{Account: "stranger", Action: "SendMessage", Resource: testQueueSrn, Conditions: fromIp("10.1.2.3")}, entity.PolicyNoMatch},
+		{entity.AccessRequest{Service: "sns.amazonaws.com", Action: "SendMessage", Resource: testQueueSrn,
+			Conditions: map[string]string{entity.ConditionSourceArn: "srn:scp:sns:kr-west1:accountid:orders-created"}}, entity.PolicyAllow},
+		{entity.AccessRequest{Service: "sns.amazonaws.com", Action: "SendMessage", Resource: testQueueSrn,
+			Conditions: map[string]string{entity.ConditionSourceArn: "srn:scp:sns:kr-west1:other:orders-created"}}, entity.PolicyNoMatch},
+		{entity.AccessRequest{Service: "sns.amazonaws.com", Action: "SendMessage", Resource: testQueueSrn}, entity.PolicyNoMatch},
+	} {
+		assert.Equal(t, tc.want, policy.Evaluate(tc.req), tc.req)
+	}
+}
+
+func TestAuthorizeQueueActionOwner(t *testing.T) {
+	s := &queueService{cfg: &config.Config{Region: "kr-west1"}}
+	ctx := context.Background()
+
+	// The owner is allowed without looking the queue up
+	assert.NoError(t, s.AuthorizeQueueAction(ctx, "orders", "accountid", entity.AccessRequest{Account: "accountid", Action: "AddPermission"}))
+	// Only the owner may change permissions, whatever the Policy says
+	assert.ErrorIs(t, s.AuthorizeQueueAction(ctx, "orders", "accountid", entity.AccessRequest{Account: "partner", Action: "AddPermission"}), entity.ErrAuthorization)
+}
+
+func TestAddPermissionValidation(t *testing.T) {
+	s := &queueService{cfg: &config.Config{Region: "kr-west1"}}
+	ctx := context.Background()
+
+	assert.ErrorIs(t, s.AddPermission(ctx, "orders", "accountid", "bad label", []string{"partner"}, []string{"SendMessage"}), entity.ErrInvalidParameter)
+	assert.ErrorIs(t, s.AddPermission(ctx, "orders", "accountid", "partner", nil, []string{"SendMessage"}), entity.ErrMissingParameter)
+	assert.ErrorIs(t, s.AddPermission(ctx, "orders", "accountid", "partner", []string{"partner"}, nil), entity.ErrMissingParameter)
+	assert.ErrorIs(t, s.AddPermission(ctx, "orders", "accountid", "partner", []string{"partner"}, []string{"DeleteQueue"}), entity.ErrInvalidParameter)
+	assert.ErrorIs(t, s.RemovePermission(ctx, "orders", "accountid", ""), entity.ErrMissingParameter)
+}
+
+func TestAddPermissionConcurrently(t *testing.T) {
+	valkeyRepo := newFakeValkeyRepo()
+	s := &queueService{natsRepo: newFakeNatsRepo(), valkeyRepo: valkeyRepo, cfg: &config.Config{Region: "kr-west1"}}
+	ctx := context.Background()
+	_, err := s.CreateQueue(ctx, "orders", "accountid", nil, nil)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			label := fmt.Sprintf("partner%d", i)
+			assert.NoError(t, s.AddPermission(ctx, "orders", "accountid", label, []string{label}, []string{"SendMessage"}))
+		}()
+	}
+	wg.Wait()
+
+	stream, err := s.queueStream(ctx, "orders", "accountid")
+	assert.NoError(t, err)
+	policy, err := parsePolicy(entity.QueueAttributes(stream.CachedInfo().Config.Metadata)[entity.AttrPolicy])
+	assert.NoError(t, err)
+	assert.Len(t, policy.Statement, 20, "no statement is lost to a concurrent update")
+	assert.Empty(t, valkeyRepo.queueLocks)
+}
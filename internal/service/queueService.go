@@ -2,53 +2,597 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"nats/internal/context/logs"
 	"nats/internal/context/traces"
 	"nats/internal/entity"
 	"nats/internal/repo"
 	"nats/pkg/config"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
 )
 
 type QueueService interface {
-	CreateQueue(ctx context.Context, name, account string) (entity.Queue, error)
-	DeleteQueue(ctx context.Context, name string) error
-	ListQueues(ctx context.Context, account string) ([]entity.Queue, error)
+	CreateQueue(ctx context.Context, name, account string, attributes, tags map[string]string) (entity.Queue, error)
+	DeleteQueue(ctx context.Context, name, account string) error
+	PurgeQueue(ctx context.Context, name, account string) error
+	ListQueues(ctx context.Context, account string, opts entity.ListQueuesOptions) (entity.ListQueuesResult, error)
+	GetQueueUrl(ctx context.Context, name, owner, account string) (entity.Queue, error)
+	ResolveQueueSrn(srn, account string) (string, error)
+	GetQueueAttributes(ctx context.Context, name, account string, names []string) (map[string]string, error)
+	SetQueueAttributes(ctx context.Context, name, account string, attributes map[string]string) error
+
+	TagQueue(ctx context.Context, name, account string, tags map[string]string) error
+	UntagQueue(ctx context.Context, name, account string, keys []string) error
+	ListQueueTags(ctx context.Context, name, account string) (map[string]string, error)
+
+	AuthorizeQueueAction(ctx context.Context, name, owner string, req entity.AccessRequest) error
+	AddPermission(ctx context.Context, name, account, label string, accounts, actions []string) error
+	RemovePermission(ctx context.Context, name, account, label string) error
 }
 
 type queueService struct {
-	natsRepo repo.NatsRepo
-	cfg      *config.Config
+	natsRepo   repo.NatsRepo
+	valkeyRepo repo.ValkeyRepo
+	cfg        *config.Config
 }
 
-func NewQueueService(natsRepo repo.NatsRepo, cfg *config.Config) QueueService {
-	return &queueService{natsRepo: natsRepo, cfg: cfg}
+func NewQueueService(natsRepo repo.NatsRepo, valkeyRepo repo.ValkeyRepo, cfg *config.Config) QueueService {
+	return &queueService{natsRepo: natsRepo, valkeyRepo: valkeyRepo, cfg: cfg}
 }
 
-func (s *queueService) CreateQueue(ctx context.Context, name, account string) (entity.Queue, error) {
-	_, err := s.natsRepo.CreateStream(ctx, name)
-	queue := makeQueueSrn(s.cfg.Region, account, name)
+// CreateQueue creates the stream of the queue. Creating an existing queue with
+// the same attributes returns it unchanged, different attributes fail with
+// ErrQueueAlreadyExists. Tags only apply to a newly created queue.
+func (s *queueService) CreateQueue(ctx context.Context, name, account string, attributes, tags map[string]string) (entity.Queue, error) {
+	ctx, span := traces.StartSpan(ctx, "createQueue")
+	defer span.End()
+
+	queue := s.queueOf(account, name)
+
+	if err := entity.ValidateQueueName(name); err != nil {
+		return queue, err
+	}
+	if err := validateQueueAttributes(attributes); err != nil {
+		return queue, err
+	}
+	if err := validateTags(tags); err != nil {
+		return queue, err
+	}
+	attributes, err := fifoQueueAttributes(name, attributes)
+	if err != nil {
+		return queue, err
+	}
+	if policy, ok := attributes[entity.AttrRedrivePolicy]; ok {
+		if err := s.checkDeadLetterTarget(ctx, queue.QueueSrn, policy); err != nil {
+			return queue, err
+		}
+	}
+
+	cfg := repo.NewStreamConfig(entity.StreamName(account, name), entity.StreamSubject(account, name),
+		entity.OwnerMetadata(account, s.cfg.Region))
+	applyQueueAttributes(&cfg, attributes)
+	if entity.IsFifoQueue(name) {
+		// Only FIFO sends carry a MessageDeduplicationId. JetStream rejects a
+		// window longer than MaxAge, which MessageRetentionPeriod can lower to 60s.
+		cfg.Duplicates = min(repo.FifoDeduplicationWindow, cfg.MaxAge)
+	}
+
+	existing, err := s.natsRepo.GetStream(ctx, cfg.Name)
+	if err == nil {
+		return queue, s.checkExistingQueue(existing, cfg, account, name)
+	}
+	if !errors.Is(err, jetstream.ErrStreamNotFound) {
+		traces.RecordSpanError(ctx, span, "natsRepo.GetStream error", err)
+		return queue, err
+	}
+
+	for key, value := range tags {
+		cfg.Metadata[entity.QueueTagKey(key)] = value
+	}
+	_, err = s.natsRepo.CreateStream(ctx, cfg)
+	if errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+		// Another request created the queue in between; it may well have
+		// asked for the same attributes
+		existing, err := s.natsRepo.GetStream(ctx, cfg.Name)
+		if err != nil {
+			traces.RecordSpanError(ctx, span, "natsRepo.GetStream error", err)
+			return queue, err
+		}
+		return queue, s.checkExistingQueue(existing, cfg, account, name)
+	}
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "natsRepo.CreateStream error", err)
+	}
 	return queue, err
 }
 
-func (s *queueService) DeleteQueue(ctx context.Context, name string) error {
-	return s.natsRepo.DeleteStream(ctx, name)
+// checkExistingQueue fails with ErrQueueAlreadyExists unless the existing
+// stream is the caller's queue with the requested attributes.
+func (s *queueService) checkExistingQueue(existing jetstream.Stream, cfg jetstream.StreamConfig, account, name string) error {
+	if !entity.IsOwnedBy(existing.CachedInfo().Config.Metadata, account, s.cfg.Region) {
+		return fmt.Errorf("%w: %s", entity.ErrQueueAlreadyExists, name)
+	}
+	if !sameQueueAttributes(existing.CachedInfo().Config, cfg) {
+		return fmt.Errorf("%w: %s", entity.ErrQueueAlreadyExists, name)
+	}
+	return nil
 }
 
-func (s *queueService) ListQueues(ctx context.Context, account string) ([]entity.Queue, error) {
-	ctx, span := traces.StartSpan(ctx, "listQueues")
+// sameQueueAttributes reports whether two stream configurations describe the
+// same queue attributes. LastModifiedTimestamp changes on every write and is ignored.
+func sameQueueAttributes(a, b jetstream.StreamConfig) bool {
+	attrsA, attrsB := configuredAttributes(a), configuredAttributes(b)
+	delete(attrsA, entity.AttrLastModifiedTimestamp)
+	delete(attrsB, entity.AttrLastModifiedTimestamp)
+	return maps.Equal(attrsA, attrsB)
+}
+
+// GetQueueAttributes returns the requested attributes. "All" selects every attribute.
+func (s *queueService) GetQueueAttributes(ctx context.Context, name, account string, names []string) (map[string]string, error) {
+	ctx, span := traces.StartSpan(ctx, "getQueueAttributes")
 	defer span.End()
 
-	namesCh, err := s.natsRepo.ListStreamNames(ctx)
+	if err := validateAttributeNames(names); err != nil {
+		return nil, err
+	}
+	stream, err := s.queueStream(ctx, name, account)
 	if err != nil {
-		traces.RecordSpanError(ctx, span, "natsRepo.ListStreamNames error", err)
+		traces.RecordSpanError(ctx, span, "natsRepo.GetStream error", err)
 		return nil, err
 	}
+	info := stream.CachedInfo()
 
-	var queues []entity.Queue
-	for name := range namesCh {
-		queues = append(queues, makeQueueSrn(s.cfg.Region, account, name))
+	attrs := configuredAttributes(info.Config)
+	attrs[entity.AttrCreatedTimestamp] = strconv.FormatInt(info.Created.Unix(), 10)
+	if _, ok := attrs[entity.AttrLastModifiedTimestamp]; !ok {
+		attrs[entity.AttrLastModifiedTimestamp] = attrs[entity.AttrCreatedTimestamp]
 	}
-	return queues, nil
+	attrs[entity.AttrQueueArn] = makeQueueSrn(s.cfg.Region, account, name).QueueSrn
+
+	visible, notVisible, delayed, err := s.approximateCounts(ctx, info)
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "approximateCounts error", err)
+		return nil, err
+	}
+	attrs[entity.AttrApproximateNumberOfMessages] = strconv.FormatUint(visible, 10)
+	attrs[entity.AttrApproximateNumberOfMessagesNotVisible] = strconv.FormatUint(notVisible, 10)
+	attrs[entity.AttrApproximateNumberOfMessagesDelayed] = strconv.FormatUint(delayed, 10)
+
+	if slices.Contains(names, entity.AttrAll) {
+		return attrs, nil
+	}
+	selected := make(map[string]string, len(names))
+	for _, n := range names {
+		if value, ok := attrs[n]; ok {
+			selected[n] = value
+		}
+	}
+	return selected, nil
+}
+
+// approximateCounts reads the message counts from the receiver consumer. Delayed
+// messages are in flight on the consumer, so they are taken out of the not visible count.
+// A queue that was never received from has every stored message visible.
+func (s *queueService) approximateCounts(ctx context.Context, info *jetstream.StreamInfo) (visible, notVisible, delayed uint64, err error) {
+	cons, err := s.natsRepo.GetConsumer(ctx, info.Config.Name, receiverConsumer)
+	if errors.Is(err, jetstream.ErrConsumerNotFound) {
+		return info.State.Msgs, 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	ci := cons.CachedInfo()
+
+	count, err := s.valkeyRepo.CountDelayedMessages(ctx, info.Config.Name)
+	if err != nil {
+		logs.GetLogger(ctx).Warn("Failed to count delayed messages", zap.String("stream", info.Config.Name), zap.Error(err))
+	}
+	delayed = min(uint64(max(count, 0)), uint64(ci.NumAckPending))
+	return ci.NumPending, uint64(ci.NumAckPending) - delayed, delayed, nil
+}
+
+// SetQueueAttributes updates the stream configuration of the queue. An empty
+// RedrivePolicy, RedriveAllowPolicy or Policy removes it. A new VisibilityTimeout
+// also applies to the receiver consumer so in-flight defaults follow the queue.
+func (s *queueService) SetQueueAttributes(ctx context.Context, name, account string, attributes map[string]string) error {
+	ctx, span := traces.StartSpan(ctx, "setQueueAttributes")
+	defer span.End()
+
+	if len(attributes) == 0 {
+		return fmt.Errorf("%w: Attributes", entity.ErrMissingParameter)
+	}
+	if _, ok := attributes[entity.AttrFifoQueue]; ok {
+		return fmt.Errorf("%w: %s cannot be changed after the queue is created", entity.ErrInvalidAttributeName, entity.AttrFifoQueue)
+	}
+	if err := validateQueueAttributes(attributes); err != nil {
+		return err
+	}
+	if _, err := fifoQueueAttributes(name, attributes); err != nil {
+		return err
+	}
+	if policy := attributes[entity.AttrRedrivePolicy]; policy != "" {
+		queue := makeQueueSrn(s.cfg.Region, account, name)
+		if err := s.checkDeadLetterTarget(ctx, queue.QueueSrn, policy); err != nil {
+			return err
+		}
+	}
+
+	streamName := entity.StreamName(account, name)
+	err := s.withQueueLock(ctx, streamName, func() error {
+		stream, err := s.queueStream(ctx, name, account)
+		if err != nil {
+			return err
+		}
+
+		cfg := stream.CachedInfo().Config
+		cfg.Metadata = maps.Clone(cfg.Metadata)
+		applyQueueAttributes(&cfg, attributes)
+		_, err = s.natsRepo.UpdateStream(ctx, cfg)
+		return err
+	})
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "updateStream error", err)
+		return err
+	}
+
+	if value, ok := attributes[entity.AttrVisibilityTimeout]; ok {
+		sec, _ := strconv.Atoi(value)
+		if err := s.natsRepo.UpdateConsumerAckWait(ctx, streamName, receiverConsumer, time.Duration(sec)*time.Second); err != nil {
+			traces.RecordSpanError(ctx, span, "natsRepo.UpdateConsumerAckWait error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// fifoQueueAttributes checks that the FifoQueue attribute agrees with the
+// queue name and records it for FIFO queues.
+func fifoQueueAttributes(name string, attributes map[string]string) (map[string]string, error) {
+	fifo := entity.IsFifoQueue(name)
+	if value, ok := attributes[entity.AttrFifoQueue]; ok && (value == "true") != fifo {
+		return nil, fmt.Errorf("%w: %s: the name of a FIFO queue must end with %s", entity.ErrInvalidAttributeValue, entity.AttrFifoQueue, entity.FifoQueueSuffix)
+	}
+	if !fifo {
+		if _, ok := attributes[entity.AttrContentBasedDeduplication]; ok {
+			return nil, fmt.Errorf("%w: %s is only valid for FIFO queues", entity.ErrInvalidAttributeName, entity.AttrContentBasedDeduplication)
+		}
+		return attributes, nil
+	}
+
+	attrs := maps.Clone(attributes)
+	if attrs == nil {
+		attrs = make(map[string]string, 1)
+	}
+	attrs[entity.AttrFifoQueue] = "true"
+	return attrs, nil
+}
+
+// checkDeadLetterTarget verifies that the dead-letter queue exists and that
+// its RedriveAllowPolicy lets the source queue use it.
+func (s *queueService) checkDeadLetterTarget(ctx context.Context, sourceSrn, value string) error {
+	policy, err := parseRedrivePolicy(value)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", entity.ErrInvalidAttributeValue, entity.AttrRedrivePolicy, err)
+	}
+	_, account, sourceName, _ := parseQueueSrn(sourceSrn)
+	// The dead-letter queue must live in the account and region of the source queue
+	dlqName, err := s.ResolveQueueSrn(policy.DeadLetterTargetArn, account)
+	if err != nil {
+		return err
+	}
+	if entity.IsFifoQueue(sourceName) != entity.IsFifoQueue(dlqName) {
+		return fmt.Errorf("%w: %s: the dead-letter queue must be of the same type as the source queue", entity.ErrInvalidAttributeValue, entity.AttrRedrivePolicy)
+	}
+
+	dlq, err := s.queueStream(ctx, dlqName, account)
+	if errors.Is(err, entity.ErrQueueNotFound) {
+		return fmt.Errorf("%w: %s: dead-letter queue does not exist", entity.ErrInvalidAttributeValue, entity.AttrRedrivePolicy)
+	}
+	if err != nil {
+		return err
+	}
+
+	dlqAttrs := entity.QueueAttributes(dlq.CachedInfo().Config.Metadata)
+	if value, ok := dlqAttrs[entity.AttrRedriveAllowPolicy]; ok {
+		allow, err := parseRedriveAllowPolicy(value)
+		if err != nil || !allow.Allows(sourceSrn) {
+			return fmt.Errorf("%w: %s: dead-letter queue does not allow this source queue", entity.ErrInvalidAttributeValue, entity.AttrRedrivePolicy)
+		}
+	}
+	return nil
+}
+
+func (s *queueService) DeleteQueue(ctx context.Context, name, account string) error {
+	if _, err := s.queueStream(ctx, name, account); err != nil {
+		return err
+	}
+	return s.natsRepo.DeleteStream(ctx, entity.StreamName(account, name))
+}
+
+// queueStream returns the stream of a queue of account in this region. A
+// stream owned by another account or region is reported as not found.
+func (s *queueService) queueStream(ctx context.Context, name, account string) (jetstream.Stream, error) {
+	stream, err := s.natsRepo.GetStream(ctx, entity.StreamName(account, name))
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		return nil, fmt.Errorf("%w: %s", entity.ErrQueueNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !entity.IsOwnedBy(stream.CachedInfo().Config.Metadata, account, s.cfg.Region) {
+		return nil, fmt.Errorf("%w: %s", entity.ErrQueueNotFound, name)
+	}
+	return stream, nil
+}
+
+// ResolveQueueSrn returns the queue name of an SRN addressed by account. An
+// SRN of another region does not exist here, one of another account is denied.
+func (s *queueService) ResolveQueueSrn(srn, account string) (string, error) {
+	return resolveQueueSrn(srn, account, s.cfg.Region)
+}
+
+func resolveQueueSrn(srn, account, region string) (string, error) {
+	srnRegion, srnAccount, name, err := parseQueueSrn(srn)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", entity.ErrInvalidParameter, err)
+	}
+	if err := entity.ValidateQueueName(name); err != nil {
+		return "", err
+	}
+	if srnRegion != region {
+		return "", fmt.Errorf("%w: %s", entity.ErrQueueNotFound, srn)
+	}
+	if srnAccount != account {
+		return "", fmt.Errorf("%w: %s", entity.ErrAuthorization, srn)
+	}
+	return name, nil
+}
+
+// purgeInterval is the minimum time between two purges of a queue.
+const purgeInterval = 60 * time.Second
+
+// PurgeQueue deletes every message of the queue. The receiver consumer is
+// dropped with it so in-flight deliveries are forgotten and their receipt
+// handles stop working; the next ReceiveMessage creates it again.
+func (s *queueService) PurgeQueue(ctx context.Context, name, account string) error {
+	ctx, span := traces.StartSpan(ctx, "purgeQueue")
+	defer span.End()
+
+	streamName := entity.StreamName(account, name)
+	if _, err := s.queueStream(ctx, name, account); err != nil {
+		traces.RecordSpanError(ctx, span, "queueStream error", err)
+		return err
+	}
+
+	ok, err := s.valkeyRepo.AcquirePurgeLock(ctx, streamName, purgeInterval)
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "valkeyRepo.AcquirePurgeLock error", err)
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", entity.ErrPurgeQueueInProgress, name)
+	}
+
+	if err := s.natsRepo.PurgeStream(ctx, streamName); err != nil {
+		traces.RecordSpanError(ctx, span, "natsRepo.PurgeStream error", err)
+		s.releasePurgeLock(ctx, streamName)
+		return err
+	}
+	if err := s.natsRepo.DeleteConsumer(ctx, streamName, receiverConsumer); err != nil {
+		traces.RecordSpanError(ctx, span, "natsRepo.DeleteConsumer error", err)
+		s.releasePurgeLock(ctx, streamName)
+		return err
+	}
+	if err := s.valkeyRepo.ClearDelayedMessages(ctx, streamName); err != nil {
+		logs.GetLogger(ctx).Warn("Failed to clear delayed messages", zap.String("stream", streamName), zap.Error(err))
+	}
+	return nil
+}
+
+// releasePurgeLock lets the client retry a purge that failed. Without it the
+// client would get PurgeQueueInProgress for a minute.
+func (s *queueService) releasePurgeLock(ctx context.Context, stream string) {
+	if err := s.valkeyRepo.ReleasePurgeLock(ctx, stream); err != nil {
+		logs.GetLogger(ctx).Warn("Failed to release purge lock", zap.String("stream", stream), zap.Error(err))
+	}
+}
+
+// The stream configuration holds the attributes, the Policy and the tags of a
+// queue. Each update reads it, changes it and writes it back, so concurrent
+// updates of one queue hold queueLock and do not lose each other's changes.
+const (
+	queueLockTTL   = 10 * time.Second
+	queueLockWait  = 5 * time.Second
+	queueLockRetry = 50 * time.Millisecond
+)
+
+// withQueueLock runs update while holding the lock of the stream, waiting up
+// to queueLockWait for a concurrent update to finish.
+func (s *queueService) withQueueLock(ctx context.Context, stream string, update func() error) error {
+	owner := uuid.NewString()
+	deadline := time.Now().Add(queueLockWait)
+	for {
+		ok, err := s.valkeyRepo.AcquireQueueLock(ctx, stream, owner, queueLockTTL)
+		if err != nil {
+			return err
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("update queue %s: too many concurrent updates", stream)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(queueLockRetry):
+		}
+	}
+	defer func() {
+		if err := s.valkeyRepo.ReleaseQueueLock(ctx, stream, owner); err != nil {
+			logs.GetLogger(ctx).Warn("Failed to release queue lock", zap.String("stream", stream), zap.Error(err))
+		}
+	}()
+	return update()
+}
+
+// ListQueues returns one page of the queue URLs of account. Streams are listed
+// in name order, so the NextToken is the last stream name of the page.
+func (s *queueService) ListQueues(ctx context.Context, account string, opts entity.ListQueuesOptions) (entity.ListQueuesResult, error) {
+	ctx, span := traces.StartSpan(ctx, "listQueues")
+	defer span.End()
+
+	result := entity.ListQueuesResult{QueueUrls: []string{}}
+	if opts.MaxResults < 0 || opts.MaxResults > entity.MaxListQueuesResults {
+		return result, fmt.Errorf("%w: MaxResults must be between 1 and %d", entity.ErrInvalidParameter, entity.MaxListQueuesResults)
+	}
+	if opts.NextToken != "" && opts.MaxResults == 0 {
+		return result, fmt.Errorf("%w: NextToken requires MaxResults", entity.ErrInvalidParameter)
+	}
+	from, err := decodeNextToken(opts.NextToken)
+	if err != nil {
+		return result, err
+	}
+	limit := opts.MaxResults
+	if limit == 0 {
+		limit = entity.MaxListQueuesResults
+	}
+
+	var last listPosition
+	err = listStreamsFrom(ctx, s.natsRepo, entity.AccountSubjects(account), from, func(info *jetstream.StreamInfo, pos listPosition) bool {
+		if !entity.IsOwnedBy(info.Config.Metadata, account, s.cfg.Region) {
+			return true
+		}
+		_, name := entity.QueueName(info.Config.Name)
+		if !strings.HasPrefix(name, opts.QueueNamePrefix) || !matchesTag(entity.QueueTags(info.Config.Metadata), opts) {
+			return true
+		}
+		if len(result.QueueUrls) == limit {
+			if opts.MaxResults > 0 {
+				result.NextToken = encodeNextToken(last)
+			}
+			return false
+		}
+		result.QueueUrls = append(result.QueueUrls, s.queueOf(account, name).QueueUrl)
+		last = pos
+		return true
+	})
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "natsRepo.ListStreams error", err)
+	}
+	return result, err
+}
+
+// listPosition is where a page of streams ended: the last stream of the page
+// and the number of streams up to and including it.
+type listPosition struct {
+	Offset int
+	After  string
+}
+
+// listStreamsFrom calls visit for the streams capturing subject that sort
+// after from, in name order, until visit returns false. The listing resumes at
+// the position of from instead of scanning every earlier stream again. Streams
+// created meanwhile only shift from to a later position and are skipped by
+// name; deleted ones shift it to an earlier position, so the listing starts over.
+func listStreamsFrom(ctx context.Context, natsRepo repo.NatsRepo, subject string, from listPosition, visit func(info *jetstream.StreamInfo, pos listPosition) bool) error {
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Start at the last stream of the previous page to detect a shift
+	offset := max(from.Offset-1, 0)
+	infoCh, listErr, err := natsRepo.ListStreams(listCtx, subject, offset)
+	if err != nil {
+		return err
+	}
+
+	pos := offset
+	for info := range infoCh {
+		pos++
+		if pos == offset+1 && offset > 0 && info.Config.Name > from.After {
+			cancel()
+			return listStreamsFrom(ctx, natsRepo, subject, listPosition{After: from.After}, visit)
+		}
+		if info.Config.Name <= from.After {
+			continue
+		}
+		if !visit(info, listPosition{Offset: pos, After: info.Config.Name}) {
+			return nil
+		}
+	}
+	// A listing cut short must not pass for the last page
+	return listErr()
+}
+
+// encodeNextToken hides the position a page ended at.
+func encodeNextToken(pos listPosition) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(pos.Offset) + ":" + pos.After))
+}
+
+func decodeNextToken(token string) (listPosition, error) {
+	var pos listPosition
+	if token == "" {
+		return pos, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return pos, fmt.Errorf("%w: invalid NextToken", entity.ErrInvalidParameter)
+	}
+	offset, after, ok := strings.Cut(string(decoded), ":")
+	pos.Offset, err = strconv.Atoi(offset)
+	if !ok || err != nil || pos.Offset < 0 {
+		return pos, fmt.Errorf("%w: invalid NextToken", entity.ErrInvalidParameter)
+	}
+	pos.After = after
+	return pos, nil
+}
+
+// GetQueueUrl resolves the name of an existing queue of owner to its URL and
+// SRN. Another account needs the GetQueueUrl permission in the queue Policy.
+func (s *queueService) GetQueueUrl(ctx context.Context, name, owner, account string) (entity.Queue, error) {
+	ctx, span := traces.StartSpan(ctx, "getQueueUrl")
+	defer span.End()
+
+	queue := s.queueOf(owner, name)
+	if err := entity.ValidateQueueName(name); err != nil {
+		return queue, err
+	}
+	if owner != account {
+		req := entity.AccessRequest{Account: account, Action: "GetQueueUrl"}
+		if err := s.AuthorizeQueueAction(ctx, name, owner, req); err != nil {
+			traces.RecordSpanError(ctx, span, "AuthorizeQueueAction error", err)
+			return queue, err
+		}
+	}
+	if _, err := s.queueStream(ctx, name, owner); err != nil {
+		traces.RecordSpanError(ctx, span, "queueStream error", err)
+		return queue, err
+	}
+	return queue, nil
+}
+
+// queueOf returns the SRN and the URL that address the queue.
+func (s *queueService) queueOf(account, name string) entity.Queue {
+	queue := makeQueueSrn(s.cfg.Region, account, name)
+	queue.QueueUrl = makeQueueUrl(s.cfg.Endpoint, account, name)
+	return queue
+}
+
+// makeQueueUrl builds <endpoint>/<account>/<name>, the path served by the
+// /:accountid/:queueid route.
+func makeQueueUrl(endpoint, account, name string) string {
+	return strings.TrimSuffix(endpoint, "/") + "/" + url.PathEscape(account) + "/" + url.PathEscape(name)
 }
 
 func makeQueueSrn(region, account, name string) entity.Queue {
@@ -62,3 +606,12 @@ func makeQueueSrn(region, account, name string) entity.Queue {
 	sb.WriteString(name)
 	return entity.Queue{QueueSrn: sb.String()}
 }
+
+// parseQueueSrn splits srn:scp:sns:<region>:<account>:<name> into its parts.
+func parseQueueSrn(srn string) (region, account, name string, err error) {
+	parts := strings.Split(srn, ":")
+	if len(parts) != 6 || parts[0] != "srn" || parts[1] != "scp" || parts[2] != "sns" || parts[5] == "" {
+		return "", "", "", fmt.Errorf("malformed queue srn %q", srn)
+	}
+	return parts[3], parts[4], parts[5], nil
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeQueueUrl(t *testing.T) {
+	assert.Equal(t, "http://localhost:8080/v1/accountid/orders", makeQueueUrl("http://localhost:8080/v1", "accountid", "orders"))
+	assert.Equal(t, "http://localhost:8080/v1/accountid/orders.fifo", makeQueueUrl("http://localhost:8080/v1/", "accountid", "orders.fifo"))
+}
+
+func TestResolveQueueSrn(t *testing.T) {
+	name, err := resolveQueueSrn("srn:scp:sns:kr-west1:accountid:orders", "accountid", "kr-west1")
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", name)
+
+	_, err = resolveQueueSrn("srn:scp:sns:kr-west1:other:orders", "accountid", "kr-west1")
+	assert.ErrorIs(t, err, entity.ErrAuthorization)
+
+	_, err = resolveQueueSrn("srn:scp:sns:kr-east1:accountid:orders", "accountid", "kr-west1")
+	assert.ErrorIs(t, err, entity.ErrQueueNotFound)
+
+	_, err = resolveQueueSrn("orders", "accountid", "kr-west1")
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+}
+
+func TestNextTokenRoundTrip(t *testing.T) {
+	pos := listPosition{Offset: 1042, After: entity.StreamName("accountid", "orders.fifo")}
+	from, err := decodeNextToken(encodeNextToken(pos))
+	assert.NoError(t, err)
+	assert.Equal(t, pos, from)
+
+	from, err = decodeNextToken("")
+	assert.NoError(t, err)
+	assert.Zero(t, from)
+
+	_, err = decodeNextToken("not base64!")
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+	_, err = decodeNextToken(base64.RawURLEncoding.EncodeToString([]byte("orders")))
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+}
+
+func TestValidateQueueName(t *testing.T) {
+	for _, name := range []string{"orders", "orders.fifo", "Orders_2-b", strings.Repeat("a", 80), strings.Repeat("a", 75) + ".fifo"} {
+		assert.NoError(t, entity.ValidateQueueName(name), name)
+	}
+	for _, name := range []string{"", ".fifo", "orders.txt", "orders fifo", "orders~fifo", "a.b", "orders*", strings.Repeat("a", 81), strings.Repeat("a", 76) + ".fifo"} {
+		assert.ErrorIs(t, entity.ValidateQueueName(name), entity.ErrInvalidParameter, name)
+	}
+
+	resp := entity.ErrorResponseOf(entity.ValidateQueueName("a.b"))
+	assert.Equal(t, "InvalidParameter", resp.Error.Code)
+	assert.Contains(t, resp.Error.Message, `"a.b"`)
+
+	assert.NoError(t, entity.ValidateAccountId("accountid"))
+	assert.ErrorIs(t, entity.ValidateAccountId("account.id"), entity.ErrInvalidParameter)
+	assert.ErrorIs(t, entity.ValidateAccountId("account~id"), entity.ErrInvalidParameter)
+}
+
+func TestCreateQueueDeduplicationWindow(t *testing.T) {
+	natsRepo := newFakeNatsRepo()
+	s := &queueService{natsRepo: natsRepo, cfg: &config.Config{Region: "kr-west1"}}
+	ctx := context.Background()
+
+	_, err := s.CreateQueue(ctx, "orders", "accountid", nil, nil)
+	assert.NoError(t, err)
+	_, err = s.CreateQueue(ctx, "orders.fifo", "accountid", nil, nil)
+	assert.NoError(t, err)
+
+	assert.Zero(t, natsRepo.streams[entity.StreamName("accountid", "orders")].Config.Duplicates)
+	assert.Equal(t, repo.FifoDeduplicationWindow, natsRepo.streams[entity.StreamName("accountid", "orders.fifo")].Config.Duplicates)
+}
+
+func TestCreateFifoQueueShortRetention(t *testing.T) {
+	natsRepo := newFakeNatsRepo()
+	s := &queueService{natsRepo: natsRepo, cfg: &config.Config{Region: "kr-west1"}}
+	ctx := context.Background()
+
+	_, err := s.CreateQueue(ctx, "orders.fifo", "accountid", map[string]string{entity.AttrMessageRetentionPeriod: "60"}, nil)
+	assert.NoError(t, err)
+	cfg := natsRepo.streams[entity.StreamName("accountid", "orders.fifo")].Config
+	assert.Equal(t, time.Minute, cfg.MaxAge)
+	assert.Equal(t, time.Minute, cfg.Duplicates, "the deduplication window never exceeds MaxAge")
+}
+
+func TestSetFifoQueueRetention(t *testing.T) {
+	natsRepo := newFakeNatsRepo()
+	s := &queueService{natsRepo: natsRepo, valkeyRepo: newFakeValkeyRepo(), cfg: &config.Config{Region: "kr-west1"}}
+	ctx := context.Background()
+	_, err := s.CreateQueue(ctx, "orders.fifo", "accountid", nil, nil)
+	assert.NoError(t, err)
+	_, err = s.CreateQueue(ctx, "orders", "accountid", nil, nil)
+	assert.NoError(t, err)
+	duplicates := func(name string) time.Duration {
+		return natsRepo.streams[entity.StreamName("accountid", name)].Config.Duplicates
+	}
+
+	assert.NoError(t, s.SetQueueAttributes(ctx, "orders.fifo", "accountid", map[string]string{entity.AttrMessageRetentionPeriod: "60"}))
+	assert.Equal(t, time.Minute, duplicates("orders.fifo"))
+	assert.NoError(t, s.SetQueueAttributes(ctx, "orders.fifo", "accountid", map[string]string{entity.AttrMessageRetentionPeriod: "3600"}))
+	assert.Equal(t, repo.FifoDeduplicationWindow, duplicates("orders.fifo"))
+
+	assert.NoError(t, s.SetQueueAttributes(ctx, "orders", "accountid", map[string]string{entity.AttrMessageRetentionPeriod: "60"}))
+	assert.Zero(t, duplicates("orders"))
+}
+
+// racingNatsRepo creates the winner stream of a concurrent CreateQueue right
+// before the first CreateStream.
+type racingNatsRepo struct {
+	*fakeNatsRepo
+	winner jetstream.StreamConfig
+}
+
+func (r *racingNatsRepo) CreateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	if r.winner.Name != "" {
+		r.addStream(r.winner)
+		r.winner = jetstream.StreamConfig{}
+	}
+	return r.fakeNatsRepo.CreateStream(ctx, cfg)
+}
+
+func TestCreateQueueConcurrently(t *testing.T) {
+	ctx := context.Background()
+	winner := repo.NewStreamConfig(entity.StreamName("accountid", "orders"), entity.StreamSubject("accountid", "orders"),
+		entity.OwnerMetadata("accountid", "kr-west1"))
+	applyQueueAttributes(&winner, map[string]string{entity.AttrVisibilityTimeout: "45"})
+
+	create := func(attributes map[string]string) error {
+		natsRepo := &racingNatsRepo{fakeNatsRepo: newFakeNatsRepo(), winner: winner}
+		s := &queueService{natsRepo: natsRepo, cfg: &config.Config{Region: "kr-west1"}}
+		_, err := s.CreateQueue(ctx, "orders", "accountid", attributes, nil)
+		return err
+	}
+	assert.NoError(t, create(map[string]string{entity.AttrVisibilityTimeout: "45"}))
+	assert.ErrorIs(t, create(map[string]string{entity.AttrVisibilityTimeout: "60"}), entity.ErrQueueAlreadyExists)
+}
+
+func TestPurgeQueueReleasesLockOnFailure(t *testing.T) {
+	natsRepo := newFakeNatsRepo()
+	valkeyRepo := newFakeValkeyRepo()
+	s := &queueService{natsRepo: natsRepo, valkeyRepo: valkeyRepo, cfg: &config.Config{Region: "kr-west1"}}
+	ctx := context.Background()
+	_, err := s.CreateQueue(ctx, "orders", "accountid", nil, nil)
+	assert.NoError(t, err)
+
+	natsRepo.purgeErr = errors.New("nats: timeout")
+	assert.ErrorIs(t, s.PurgeQueue(ctx, "orders", "accountid"), natsRepo.purgeErr)
+
+	// The failed purge does not block a retry, a successful one does
+	natsRepo.purgeErr = nil
+	assert.NoError(t, s.PurgeQueue(ctx, "orders", "accountid"))
+	assert.ErrorIs(t, s.PurgeQueue(ctx, "orders", "accountid"), entity.ErrPurgeQueueInProgress)
+}
+
+func TestListQueuesResumesAtNextToken(t *testing.T) {
+	natsRepo := newFakeNatsRepo()
+	s := &queueService{natsRepo: natsRepo, cfg: &config.Config{Region: "kr-west1", Endpoint: "http://localhost:8080/v1"}}
+	ctx := context.Background()
+	for i := range 5 {
+		_, err := s.CreateQueue(ctx, fmt.Sprintf("orders-%d", i), "accountid", nil, nil)
+		assert.NoError(t, err)
+	}
+	list := func(token string) entity.ListQueuesResult {
+		result, err := s.ListQueues(ctx, "accountid", entity.ListQueuesOptions{MaxResults: 2, NextToken: token})
+		assert.NoError(t, err)
+		return result
+	}
+
+	page := list("")
+	assert.Equal(t, []string{"http://localhost:8080/v1/accountid/orders-0", "http://localhost:8080/v1/accountid/orders-1"}, page.QueueUrls)
+	page = list(page.NextToken)
+	assert.Equal(t, []string{"http://localhost:8080/v1/accountid/orders-2", "http://localhost:8080/v1/accountid/orders-3"}, page.QueueUrls)
+	assert.Equal(t, []int{0, 1}, natsRepo.listOffsets, "the second page starts at the end of the first")
+
+	// A queue created before the position is skipped, a deleted one restarts the listing
+	next := page.NextToken
+	_, err := s.CreateQueue(ctx, "orders-00", "accountid", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://localhost:8080/v1/accountid/orders-4"}, list(next).QueueUrls)
+	delete(natsRepo.streams, entity.StreamName("accountid", "orders-00"))
+	delete(natsRepo.streams, entity.StreamName("accountid", "orders-0"))
+	assert.Equal(t, []string{"http://localhost:8080/v1/accountid/orders-4"}, list(next).QueueUrls)
+	assert.Equal(t, []int{0, 1, 3, 3, 0}, natsRepo.listOffsets)
+}
+
+func TestListQueuesListingFails(t *testing.T) {
+	natsRepo := newFakeNatsRepo()
+	s := &queueService{natsRepo: natsRepo, cfg: &config.Config{Region: "kr-west1", Endpoint: "http://localhost:8080/v1"}}
+	ctx := context.Background()
+	for i := range 5 {
+		_, err := s.CreateQueue(ctx, fmt.Sprintf("orders-%d", i), "accountid", nil, nil)
+		assert.NoError(t, err)
+	}
+
+	// A listing cut short fails instead of returning a truncated last page
+	natsRepo.listFailAfter = 3
+	_, err := s.ListQueues(ctx, "accountid", entity.ListQueuesOptions{})
+	assert.ErrorIs(t, err, nats.ErrTimeout)
+
+	// A page that ends before the failure is complete
+	result, err := s.ListQueues(ctx, "accountid", entity.ListQueuesOptions{MaxResults: 2})
+	assert.NoError(t, err)
+	assert.Len(t, result.QueueUrls, 2)
+	assert.NotEmpty(t, result.NextToken)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"nats/internal/entity"
+)
+
+// ReceiptCodec signs and verifies the opaque receipt handles handed to clients.
+type ReceiptCodec interface {
+	Encode(handle entity.ReceiptHandle) (string, error)
+	Decode(token string) (entity.ReceiptHandle, error)
+}
+
+type receiptCodec struct {
+	secret []byte
+}
+
+// NewReceiptCodec creates a codec signing handles with HMAC-SHA256.
+// Every API instance behind the same load balancer must share the secret.
+// An empty secret falls back to a random key that is only valid for this process.
+func NewReceiptCodec(secret string) ReceiptCodec {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+	}
+	return &receiptCodec{secret: key}
+}
+
+// Encode serializes the handle as base64url(payload) + "." + base64url(signature).
+func (c *receiptCodec) Encode(handle entity.ReceiptHandle) (string, error) {
+	payload, err := json.Marshal(handle)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(base64.RawURLEncoding.EncodeToString(payload))
+	sb.WriteByte('.')
+	sb.WriteString(base64.RawURLEncoding.EncodeToString(c.sign(payload)))
+	return sb.String(), nil
+}
+
+// Decode verifies the signature and returns the handle. Any malformed or
+// forged token yields entity.ErrReceiptHandleInvalid.
+func (c *receiptCodec) Decode(token string) (entity.ReceiptHandle, error) {
+	var handle entity.ReceiptHandle
+
+	encPayload, encSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return handle, entity.ErrReceiptHandleInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return handle, entity.ErrReceiptHandleInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil || !hmac.Equal(sig, c.sign(payload)) {
+		return handle, entity.ErrReceiptHandleInvalid
+	}
+	if err := json.Unmarshal(payload, &handle); err != nil {
+		return handle, entity.ErrReceiptHandleInvalid
+	}
+	if handle.Stream == "" || handle.Consumer == "" || handle.StreamSeq == 0 {
+		return handle, entity.ErrReceiptHandleInvalid
+	}
+	return handle, nil
+}
+
+func (c *receiptCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
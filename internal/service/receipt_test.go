@@ -0,0 +1,52 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"nats/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiptCodecRoundTrip(t *testing.T) {
+	codec := NewReceiptCodec("test-secret")
+	handle := entity.ReceiptHandle{
+		Stream:       "orders",
+		Consumer:     receiverConsumer,
+		StreamSeq:    42,
+		ConsumerSeq:  7,
+		NumDelivered: 2,
+		Timestamp:    1700000000000000000,
+	}
+
+	token, err := codec.Encode(handle)
+	assert.NoError(t, err)
+
+	decoded, err := codec.Decode(token)
+	assert.NoError(t, err)
+	assert.Equal(t, handle, decoded)
+}
+
+func TestReceiptCodecRejectsForgedHandle(t *testing.T) {
+	codec := NewReceiptCodec("test-secret")
+	token, err := codec.Encode(entity.ReceiptHandle{Stream: "orders", Consumer: receiverConsumer, StreamSeq: 1})
+	assert.NoError(t, err)
+
+	// Signed by another key
+	other := NewReceiptCodec("other-secret")
+	_, err = other.Decode(token)
+	assert.ErrorIs(t, err, entity.ErrReceiptHandleInvalid)
+
+	// Payload swapped while keeping the signature
+	forged, _ := codec.Encode(entity.ReceiptHandle{Stream: "orders", Consumer: receiverConsumer, StreamSeq: 2})
+	payload, _, _ := strings.Cut(forged, ".")
+	_, sig, _ := strings.Cut(token, ".")
+	_, err = codec.Decode(payload + "." + sig)
+	assert.ErrorIs(t, err, entity.ErrReceiptHandleInvalid)
+
+	for _, bad := range []string{"", "abc", "abc.def", "!!!.???"} {
+		_, err = codec.Decode(bad)
+		assert.ErrorIs(t, err, entity.ErrReceiptHandleInvalid, bad)
+	}
+}
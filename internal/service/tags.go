@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"nats/internal/context/traces"
+	"nats/internal/entity"
+)
+
+// Tag limits from the SQS documentation.
+const (
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+	reservedTagPrefix = "aws:"
+)
+
+// tagPattern is the character set SQS accepts in tag keys and values.
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+func validateTagKey(key string) error {
+	n := utf8.RuneCountInString(key)
+	if n == 0 || n > maxTagKeyLength || !tagPattern.MatchString(key) {
+		return fmt.Errorf("%w: invalid tag key %q", entity.ErrInvalidParameter, key)
+	}
+	if strings.HasPrefix(strings.ToLower(key), reservedTagPrefix) {
+		return fmt.Errorf("%w: tag key %q uses the reserved prefix %s", entity.ErrInvalidParameter, key, reservedTagPrefix)
+	}
+	return nil
+}
+
+// validateTags checks tag keys and values against the SQS rules.
+func validateTags(tags map[string]string) error {
+	if len(tags) > entity.MaxQueueTags {
+		return fmt.Errorf("%w: a queue can have at most %d tags", entity.ErrInvalidParameter, entity.MaxQueueTags)
+	}
+	for key, value := range tags {
+		if err := validateTagKey(key); err != nil {
+			return err
+		}
+		if utf8.RuneCountInString(value) > maxTagValueLength || !tagPattern.MatchString(value) {
+			return fmt.Errorf("%w: invalid value for tag %q", entity.ErrInvalidParameter, key)
+		}
+	}
+	return nil
+}
+
+// matchesTag reports whether the queue tags satisfy the ListQueues tag filter.
+func matchesTag(tags map[string]string, opts entity.ListQueuesOptions) bool {
+	if opts.TagKey == "" {
+		return true
+	}
+	value, ok := tags[opts.TagKey]
+	return ok && (opts.TagValue == "" || value == opts.TagValue)
+}
+
+// TagQueue adds or overwrites tags of the queue.
+func (s *queueService) TagQueue(ctx context.Context, name, account string, tags map[string]string) error {
+	ctx, span := traces.StartSpan(ctx, "tagQueue")
+	defer span.End()
+
+	if len(tags) == 0 {
+		return fmt.Errorf("%w: Tags", entity.ErrMissingParameter)
+	}
+	if err := validateTags(tags); err != nil {
+		return err
+	}
+	err := s.updateTags(ctx, name, account, func(current map[string]string) error {
+		maps.Copy(current, tags)
+		if len(current) > entity.MaxQueueTags {
+			return fmt.Errorf("%w: a queue can have at most %d tags", entity.ErrInvalidParameter, entity.MaxQueueTags)
+		}
+		return nil
+	})
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "updateTags error", err)
+	}
+	return err
+}
+
+// UntagQueue removes tags of the queue. Keys the queue does not carry are ignored.
+func (s *queueService) UntagQueue(ctx context.Context, name, account string, keys []string) error {
+	ctx, span := traces.StartSpan(ctx, "untagQueue")
+	defer span.End()
+
+	if len(keys) == 0 {
+		return fmt.Errorf("%w: TagKeys", entity.ErrMissingParameter)
+	}
+	for _, key := range keys {
+		if err := validateTagKey(key); err != nil {
+			return err
+		}
+	}
+	err := s.updateTags(ctx, name, account, func(current map[string]string) error {
+		for _, key := range keys {
+			delete(current, key)
+		}
+		return nil
+	})
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "updateTags error", err)
+	}
+	return err
+}
+
+func (s *queueService) ListQueueTags(ctx context.Context, name, account string) (map[string]string, error) {
+	ctx, span := traces.StartSpan(ctx, "listQueueTags")
+	defer span.End()
+
+	stream, err := s.queueStream(ctx, name, account)
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "queueStream error", err)
+		return nil, err
+	}
+	return entity.QueueTags(stream.CachedInfo().Config.Metadata), nil
+}
+
+// updateTags applies change to the current tags of the queue and stores the
+// result in the stream metadata. Tags are not queue attributes, so
+// LastModifiedTimestamp is left alone.
+func (s *queueService) updateTags(ctx context.Context, name, account string, change func(tags map[string]string) error) error {
+	return s.withQueueLock(ctx, entity.StreamName(account, name), func() error {
+		return s.changeTags(ctx, name, account, change)
+	})
+}
+
+func (s *queueService) changeTags(ctx context.Context, name, account string, change func(tags map[string]string) error) error {
+	stream, err := s.queueStream(ctx, name, account)
+	if err != nil {
+		return err
+	}
+
+	cfg := stream.CachedInfo().Config
+	tags := entity.QueueTags(cfg.Metadata)
+	if err := change(tags); err != nil {
+		return err
+	}
+
+	metadata := make(map[string]string, len(cfg.Metadata))
+	for key, value := range cfg.Metadata {
+		if !entity.IsQueueTagKey(key) {
+			metadata[key] = value
+		}
+	}
+	for key, value := range tags {
+		metadata[entity.QueueTagKey(key)] = value
+	}
+	cfg.Metadata = metadata
+	_, err = s.natsRepo.UpdateStream(ctx, cfg)
+	return err
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nats/internal/entity"
+	"nats/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTags(t *testing.T) {
+	assert.NoError(t, validateTags(map[string]string{"team": "payments", "cost center": "cc/1024@kr", "empty": ""}))
+
+	for _, tags := range []map[string]string{
+		{"": "value"},
+		{strings.Repeat("k", maxTagKeyLength+1): "value"},
+		{"team": strings.Repeat("v", maxTagValueLength+1)},
+		{"team#1": "value"},
+		{"aws:createdBy": "value"},
+	} {
+		assert.ErrorIs(t, validateTags(tags), entity.ErrInvalidParameter, tags)
+	}
+
+	tooMany := make(map[string]string, entity.MaxQueueTags+1)
+	for i := range entity.MaxQueueTags + 1 {
+		tooMany["key"+strconv.Itoa(i)] = "value"
+	}
+	assert.ErrorIs(t, validateTags(tooMany), entity.ErrInvalidParameter)
+}
+
+func TestMatchesTag(t *testing.T) {
+	tags := map[string]string{"team": "payments"}
+	assert.True(t, matchesTag(tags, entity.ListQueuesOptions{}))
+	assert.True(t, matchesTag(tags, entity.ListQueuesOptions{TagKey: "team"}))
+	assert.True(t, matchesTag(tags, entity.ListQueuesOptions{TagKey: "team", TagValue: "payments"}))
+	assert.False(t, matchesTag(tags, entity.ListQueuesOptions{TagKey: "team", TagValue: "search"}))
+	assert.False(t, matchesTag(tags, entity.ListQueuesOptions{TagKey: "owner"}))
+}
+
+func TestTagQueueConcurrently(t *testing.T) {
+	valkeyRepo := newFakeValkeyRepo()
+	s := &queueService{natsRepo: newFakeNatsRepo(), valkeyRepo: valkeyRepo, cfg: &config.Config{Region: "kr-west1"}}
+	ctx := context.Background()
+	_, err := s.CreateQueue(ctx, "orders", "accountid", nil, nil)
+	assert.NoError(t, err)
+
+	// A lock held by another instance is waited for
+	valkeyRepo.queueLocks[entity.StreamName("accountid", "orders")] = "other"
+	go func() {
+		time.Sleep(2 * queueLockRetry)
+		assert.NoError(t, valkeyRepo.ReleaseQueueLock(ctx, entity.StreamName("accountid", "orders"), "other"))
+	}()
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, s.TagQueue(ctx, "orders", "accountid", map[string]string{"key" + strconv.Itoa(i): "value"}))
+		}()
+	}
+	wg.Wait()
+
+	tags, err := s.ListQueueTags(ctx, "orders", "accountid")
+	assert.NoError(t, err)
+	assert.Len(t, tags, 20, "no tag is lost to a concurrent update")
+	assert.Empty(t, valkeyRepo.queueLocks)
+}
@@ -0,0 +1,699 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nats/internal/context/logs"
+	"nats/internal/context/traces"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxListTopicsResults is the SNS page size of ListTopics and ListSubscriptionsByTopic.
+	maxListTopicsResults = 100
+	// maxNotificationSubjectLength is the SNS upper bound of the Subject of a message.
+	maxNotificationSubjectLength = 100
+
+	// topicSyncInterval is how often subscriptions created on other instances are picked up.
+	topicSyncInterval = 10 * time.Second
+	// deliveryBatch and deliveryWait bound a single pull of a subscription consumer.
+	deliveryBatch = 10
+	deliveryWait  = 5 * time.Second
+	// deliveryAckWait redelivers a notification whose delivery never finished.
+	deliveryAckWait = 30 * time.Second
+	// maxDeliveryBackoff caps the delay between two delivery attempts of a notification.
+	maxDeliveryBackoff = 5 * time.Minute
+	// maxDeliveryAttempts gives up on a notification after about four hours of retries.
+	maxDeliveryAttempts = 55
+)
+
+// TopicService manages topics and their subscriptions. Every subscription is
+// a durable consumer of the topic stream, so each subscribed queue receives
+// the published messages at its own pace.
+type TopicService interface {
+	Start(ctx context.Context)
+	Stop()
+
+	CreateTopic(ctx context.Context, name, account string) (entity.Topic, error)
+	DeleteTopic(ctx context.Context, topicArn, account string) error
+	ListTopics(ctx context.Context, account, nextToken string) (entity.ListTopicsResult, error)
+
+	Subscribe(ctx context.Context, topicArn, account, protocol, endpoint string) (string, error)
+	Unsubscribe(ctx context.Context, subscriptionArn, account string) error
+	ListSubscriptionsByTopic(ctx context.Context, topicArn, account, nextToken string) (entity.ListSubscriptionsResult, error)
+
+	Publish(ctx context.Context, topicArn, account, message string, opts entity.PublishOptions) (string, error)
+}
+
+type topicService struct {
+	natsRepo   repo.NatsRepo
+	queueSvc   QueueService
+	messageSvc MessageService
+	region     string
+	endpoint   string
+
+	// baseCtx parents every delivery worker so Stop interrupts them all
+	baseCtx context.Context
+	stop    context.CancelFunc
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc // keyed by <stream>/<consumer>
+	wg      sync.WaitGroup
+}
+
+func NewTopicService(natsRepo repo.NatsRepo, queueSvc QueueService, messageSvc MessageService, cfg *config.Config) TopicService {
+	baseCtx, stop := context.WithCancel(context.Background())
+	return &topicService{
+		natsRepo:   natsRepo,
+		queueSvc:   queueSvc,
+		messageSvc: messageSvc,
+		region:     cfg.Region,
+		endpoint:   cfg.Endpoint,
+		baseCtx:    baseCtx,
+		stop:       stop,
+		running:    make(map[string]context.CancelFunc),
+	}
+}
+
+// Start runs a delivery worker for every subscription and keeps adding the
+// subscriptions created on other instances. ctx provides the logger.
+func (s *topicService) Start(ctx context.Context) {
+	s.baseCtx = logs.WithLogger(s.baseCtx, logs.GetLogger(ctx))
+	ctx = s.baseCtx
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(topicSyncInterval)
+		defer ticker.Stop()
+		for {
+			s.syncSubscriptions(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop interrupts the delivery workers. Notifications in flight are
+// redelivered once their AckWait expires.
+func (s *topicService) Stop() {
+	s.stop()
+	s.wg.Wait()
+}
+
+// CreateTopic creates the stream of the topic. Creating an existing topic returns it.
+func (s *topicService) CreateTopic(ctx context.Context, name, account string) (entity.Topic, error) {
+	ctx, span := traces.StartSpan(ctx, "createTopic")
+	defer span.End()
+
+	topic := entity.Topic{TopicArn: makeTopicSrn(s.region, account, name)}
+	if err := entity.ValidateTopicName(name); err != nil {
+		return topic, err
+	}
+
+	if _, err := s.topicStream(ctx, name, account); !errors.Is(err, entity.ErrTopicNotFound) {
+		return topic, err
+	}
+	cfg := repo.NewTopicStreamConfig(entity.TopicStreamName(account, name), entity.TopicSubject(account, name), entity.OwnerMetadata(account, s.region))
+	if _, err := s.natsRepo.CreateStream(ctx, cfg); err != nil {
+		traces.RecordSpanError(ctx, span, "natsRepo.CreateStream error", err)
+		return topic, err
+	}
+	return topic, nil
+}
+
+// DeleteTopic deletes the topic with its subscriptions and undelivered messages.
+func (s *topicService) DeleteTopic(ctx context.Context, topicArn, account string) error {
+	ctx, span := traces.StartSpan(ctx, "deleteTopic")
+	defer span.End()
+
+	name, err := resolveTopicSrn(topicArn, account, s.region)
+	if err != nil {
+		return err
+	}
+	if _, err := s.topicStream(ctx, name, account); err != nil {
+		return err
+	}
+	streamName := entity.TopicStreamName(account, name)
+	if err := s.natsRepo.DeleteStream(ctx, streamName); err != nil {
+		traces.RecordSpanError(ctx, span, "natsRepo.DeleteStream error", err)
+		return err
+	}
+	s.stopWorkers(streamName + "/")
+	return nil
+}
+
+// ListTopics returns one page of the topics of account, in name order.
+func (s *topicService) ListTopics(ctx context.Context, account, nextToken string) (entity.ListTopicsResult, error) {
+	ctx, span := traces.StartSpan(ctx, "listTopics")
+	defer span.End()
+
+	result := entity.ListTopicsResult{Topics: []entity.Topic{}}
+	from, err := decodeNextToken(nextToken)
+	if err != nil {
+		return result, err
+	}
+
+	var last listPosition
+	err = listStreamsFrom(ctx, s.natsRepo, entity.AccountTopicSubjects(account), from, func(info *jetstream.StreamInfo, pos listPosition) bool {
+		if !entity.IsOwnedBy(info.Config.Metadata, account, s.region) {
+			return true
+		}
+		if len(result.Topics) == maxListTopicsResults {
+			result.NextToken = encodeNextToken(last)
+			return false
+		}
+		_, name := entity.TopicName(info.Config.Name)
+		result.Topics = append(result.Topics, entity.Topic{TopicArn: makeTopicSrn(s.region, account, name)})
+		last = pos
+		return true
+	})
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "natsRepo.ListStreams error", err)
+	}
+	return result, err
+}
+
+// Subscribe subscribes a queue of this region to the topic and returns the
+// SubscriptionArn. The queue receives the messages published from now on.
+// Subscribing the same queue again returns the existing subscription.
+func (s *topicService) Subscribe(ctx context.Context, topicArn, account, protocol, endpoint string) (string, error) {
+	ctx, span := traces.StartSpan(ctx, "subscribe")
+	defer span.End()
+
+	name, err := resolveTopicSrn(topicArn, account, s.region)
+	if err != nil {
+		return "", err
+	}
+	if protocol != entity.ProtocolSqs {
+		return "", fmt.Errorf("%w: unsupported protocol %q, only %s is supported", entity.ErrInvalidParameter, protocol, entity.ProtocolSqs)
+	}
+	if err := validateQueueEndpoint(endpoint, s.region); err != nil {
+		return "", err
+	}
+	if _, err := s.topicStream(ctx, name, account); err != nil {
+		return "", err
+	}
+
+	streamName := entity.TopicStreamName(account, name)
+	subscriptions, err := s.subscriptions(ctx, streamName)
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "subscriptions error", err)
+		return "", err
+	}
+	for _, sub := range subscriptions {
+		if sub.Config.Metadata[entity.MetadataSubscriptionProtocol] == protocol && sub.Config.Metadata[entity.MetadataSubscriptionEndpoint] == endpoint {
+			return topicArn + ":" + sub.Name, nil
+		}
+	}
+
+	id := uuid.NewString()
+	cons, err := s.natsRepo.CreateConsumer(ctx, streamName, jetstream.ConsumerConfig{
+		Durable:       id,
+		DeliverPolicy: jetstream.DeliverNewPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       deliveryAckWait,
+		MaxDeliver:    maxDeliveryAttempts,
+		Metadata: map[string]string{
+			entity.MetadataSubscriptionProtocol: protocol,
+			entity.MetadataSubscriptionEndpoint: endpoint,
+			entity.MetadataSubscriptionOwner:    account,
+		},
+	})
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "natsRepo.CreateConsumer error", err)
+		return "", err
+	}
+	s.launch(streamName, cons.CachedInfo().Name)
+	return topicArn + ":" + id, nil
+}
+
+// Unsubscribe deletes the subscription. Notifications not yet delivered to its queue are dropped.
+func (s *topicService) Unsubscribe(ctx context.Context, subscriptionArn, account string) error {
+	ctx, span := traces.StartSpan(ctx, "unsubscribe")
+	defer span.End()
+
+	topicArn, id, ok := cutSubscriptionSrn(subscriptionArn)
+	if !ok {
+		return fmt.Errorf("%w: malformed SubscriptionArn %q", entity.ErrInvalidParameter, subscriptionArn)
+	}
+	name, err := resolveTopicSrn(topicArn, account, s.region)
+	if err != nil {
+		return err
+	}
+	if _, err := s.topicStream(ctx, name, account); err != nil {
+		return err
+	}
+
+	streamName := entity.TopicStreamName(account, name)
+	if _, err := s.natsRepo.GetConsumer(ctx, streamName, id); errors.Is(err, jetstream.ErrConsumerNotFound) {
+		return fmt.Errorf("%w: %s", entity.ErrSubscriptionNotFound, subscriptionArn)
+	} else if err != nil {
+		traces.RecordSpanError(ctx, span, "natsRepo.GetConsumer error", err)
+		return err
+	}
+	if err := s.natsRepo.DeleteConsumer(ctx, streamName, id); err != nil {
+		traces.RecordSpanError(ctx, span, "natsRepo.DeleteConsumer error", err)
+		return err
+	}
+	s.stopWorkers(streamName + "/" + id)
+	return nil
+}
+
+// ListSubscriptionsByTopic returns one page of the subscriptions of the topic,
+// ordered by subscription id.
+func (s *topicService) ListSubscriptionsByTopic(ctx context.Context, topicArn, account, nextToken string) (entity.ListSubscriptionsResult, error) {
+	ctx, span := traces.StartSpan(ctx, "listSubscriptionsByTopic")
+	defer span.End()
+
+	result := entity.ListSubscriptionsResult{Subscriptions: []entity.Subscription{}}
+	name, err := resolveTopicSrn(topicArn, account, s.region)
+	if err != nil {
+		return result, err
+	}
+	from, err := decodeNextToken(nextToken)
+	if err != nil {
+		return result, err
+	}
+	if _, err := s.topicStream(ctx, name, account); err != nil {
+		return result, err
+	}
+
+	subscriptions, err := s.subscriptions(ctx, entity.TopicStreamName(account, name))
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "subscriptions error", err)
+		return result, err
+	}
+	var last listPosition
+	for _, sub := range subscriptions {
+		if sub.Name <= from.After {
+			continue
+		}
+		if len(result.Subscriptions) == maxListTopicsResults {
+			result.NextToken = encodeNextToken(last)
+			break
+		}
+		result.Subscriptions = append(result.Subscriptions, entity.Subscription{
+			SubscriptionArn: topicArn + ":" + sub.Name,
+			Owner:           sub.Config.Metadata[entity.MetadataSubscriptionOwner],
+			Protocol:        sub.Config.Metadata[entity.MetadataSubscriptionProtocol],
+			Endpoint:        sub.Config.Metadata[entity.MetadataSubscriptionEndpoint],
+			TopicArn:        topicArn,
+		})
+		last = listPosition{After: sub.Name}
+	}
+	return result, nil
+}
+
+// Publish stores the message in the topic stream and returns its MessageId.
+// Without subscriptions the message is dropped.
+func (s *topicService) Publish(ctx context.Context, topicArn, account, message string, opts entity.PublishOptions) (string, error) {
+	ctx, span := traces.StartSpan(ctx, "publish")
+	defer span.End()
+
+	name, err := resolveTopicSrn(topicArn, account, s.region)
+	if err != nil {
+		return "", err
+	}
+	if message == "" {
+		return "", fmt.Errorf("%w: Message", entity.ErrMissingParameter)
+	}
+	if err := validateNotificationSubject(opts.Subject); err != nil {
+		return "", err
+	}
+	if err := validateMessageSize(message, opts.MessageAttributes, entity.MaxMessageSize); err != nil {
+		return "", err
+	}
+	if err := validateMessageAttributes(opts.MessageAttributes); err != nil {
+		return "", err
+	}
+	if _, err := s.topicStream(ctx, name, account); err != nil {
+		return "", err
+	}
+
+	id := uuid.NewString()
+	header := nats.Header{}
+	header.Set(entity.HeaderNotificationId, id)
+	header.Set(entity.HeaderNotificationTimestamp, time.Now().UTC().Format(entity.NotificationTimestampFormat))
+	if opts.Subject != "" {
+		header.Set(entity.HeaderNotificationSubject, opts.Subject)
+	}
+	if err := setAttributeHeaders(header, entity.HeaderMessageAttributePrefix, opts.MessageAttributes); err != nil {
+		return "", err
+	}
+	if err := s.validateNotificationSize(topicArn, header, message); err != nil {
+		return "", err
+	}
+	if _, err := s.natsRepo.SendMessage(ctx, message, entity.TopicSubject(account, name), header); err != nil {
+		traces.RecordSpanError(ctx, span, "natsRepo.SendMessage error", err)
+		return "", err
+	}
+	return id, nil
+}
+
+// topicStream returns the stream of a topic of account in this region.
+func (s *topicService) topicStream(ctx context.Context, name, account string) (jetstream.Stream, error) {
+	stream, err := s.natsRepo.GetStream(ctx, entity.TopicStreamName(account, name))
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		return nil, fmt.Errorf("%w: %s", entity.ErrTopicNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !entity.IsOwnedBy(stream.CachedInfo().Config.Metadata, account, s.region) {
+		return nil, fmt.Errorf("%w: %s", entity.ErrTopicNotFound, name)
+	}
+	return stream, nil
+}
+
+// subscriptions returns the subscription consumers of a topic stream ordered by name.
+func (s *topicService) subscriptions(ctx context.Context, stream string) ([]*jetstream.ConsumerInfo, error) {
+	infoCh, err := s.natsRepo.ListConsumers(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+	var subscriptions []*jetstream.ConsumerInfo
+	for info := range infoCh {
+		if info.Config.Metadata[entity.MetadataSubscriptionProtocol] != "" {
+			subscriptions = append(subscriptions, info)
+		}
+	}
+	slices.SortFunc(subscriptions, func(a, b *jetstream.ConsumerInfo) int { return strings.Compare(a.Name, b.Name) })
+	return subscriptions, nil
+}
+
+// syncSubscriptions starts a delivery worker for every subscription of the
+// topics of this region that has none on this instance.
+func (s *topicService) syncSubscriptions(ctx context.Context) {
+	infoCh, listErr, err := s.natsRepo.ListStreams(ctx, entity.AllTopicSubjects, 0)
+	if err != nil {
+		logs.GetLogger(ctx).Warn("Failed to list topics", zap.Error(err))
+		return
+	}
+	var streams []string
+	for info := range infoCh {
+		if info.Config.Metadata[entity.MetadataOwnerRegion] == s.region {
+			streams = append(streams, info.Config.Name)
+		}
+	}
+	if err := listErr(); err != nil && ctx.Err() == nil {
+		logs.GetLogger(ctx).Warn("Failed to list topics", zap.Error(err))
+	}
+
+	for _, stream := range streams {
+		subscriptions, err := s.subscriptions(ctx, stream)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Failed to list subscriptions", zap.String("stream", stream), zap.Error(err))
+			continue
+		}
+		for _, sub := range subscriptions {
+			s.launch(stream, sub.Name)
+		}
+	}
+}
+
+// launch runs the delivery worker of a subscription unless it already runs here.
+func (s *topicService) launch(stream, consumer string) {
+	key := stream + "/" + consumer
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.running[key]; ok || s.baseCtx.Err() != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.baseCtx)
+	s.running[key] = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, key)
+			s.mu.Unlock()
+			cancel()
+		}()
+		s.deliverLoop(ctx, stream, consumer)
+	}()
+}
+
+// stopWorkers cancels the local delivery workers whose key starts with prefix.
+func (s *topicService) stopWorkers(prefix string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, cancel := range s.running {
+		if strings.HasPrefix(key, prefix) {
+			cancel()
+		}
+	}
+}
+
+// deliverLoop pulls the notifications of one subscription and delivers them
+// to its queue until the subscription or its topic is deleted.
+func (s *topicService) deliverLoop(ctx context.Context, stream, consumer string) {
+	logger := logs.GetLogger(ctx).With(zap.String("stream", stream), zap.String("subscription", consumer))
+
+	cons, err := s.natsRepo.GetConsumer(ctx, stream, consumer)
+	if err != nil {
+		logger.Warn("Failed to open subscription", zap.Error(err))
+		return
+	}
+	account, name := entity.TopicName(stream)
+	topicArn := makeTopicSrn(s.region, account, name)
+	sub := entity.Subscription{
+		SubscriptionArn: topicArn + ":" + consumer,
+		Endpoint:        cons.CachedInfo().Config.Metadata[entity.MetadataSubscriptionEndpoint],
+		TopicArn:        topicArn,
+	}
+
+	for ctx.Err() == nil {
+		msgs, err := s.natsRepo.FetchMessages(ctx, cons, deliveryBatch, deliveryWait)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			_, err := s.natsRepo.GetConsumer(ctx, stream, consumer)
+			if errors.Is(err, jetstream.ErrConsumerNotFound) || errors.Is(err, jetstream.ErrStreamNotFound) {
+				logger.Info("Subscription deleted")
+				return
+			}
+			logger.Warn("Failed to fetch notifications", zap.Error(err))
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		for _, msg := range msgs {
+			s.deliver(ctx, sub, account, msg)
+		}
+	}
+}
+
+// deliver sends one notification to the queue of the subscription.
+// Notifications the queue rejects, including ones above its stream limit, or
+// that cannot reach a deleted or denying queue, are dropped on the first
+// attempt. Other failures are retried with a growing delay, up to
+// maxDeliveryAttempts times.
+func (s *topicService) deliver(ctx context.Context, sub entity.Subscription, topicOwner string, msg jetstream.Msg) {
+	logger := logs.GetLogger(ctx).With(zap.String("subscription", sub.SubscriptionArn), zap.String("endpoint", sub.Endpoint))
+
+	body, err := json.Marshal(s.notificationOf(sub, msg.Headers(), string(msg.Data())))
+	if err == nil {
+		err = s.sendToQueue(ctx, sub, topicOwner, string(body))
+	}
+	var delivered uint64 = 1
+	if meta, err := msg.Metadata(); err == nil {
+		delivered = meta.NumDelivered
+	}
+	switch {
+	case err == nil:
+		_ = msg.Ack()
+	case errors.Is(err, entity.ErrInvalidParameter), errors.Is(err, entity.ErrMissingParameter):
+		logger.Error("Notification rejected by the queue", zap.Error(err))
+		_ = msg.Term()
+	case errors.Is(err, entity.ErrQueueNotFound), errors.Is(err, entity.ErrAuthorization):
+		logger.Error("Notification dropped, the queue does not exist or denies the topic", zap.Error(err))
+		_ = msg.Term()
+	case delivered >= maxDeliveryAttempts:
+		logger.Error("Notification dropped after the last delivery attempt", zap.Uint64("attempt", delivered), zap.Error(err))
+		_ = msg.Term()
+	default:
+		logger.Warn("Failed to deliver notification", zap.Uint64("attempt", delivered), zap.Error(err))
+		_ = msg.NakWithDelay(deliveryBackoff(delivered))
+	}
+}
+
+// sendToQueue sends the notification body to the subscribed queue. A queue
+// of another account must allow the topic in its Policy.
+func (s *topicService) sendToQueue(ctx context.Context, sub entity.Subscription, topicOwner, body string) error {
+	_, owner, queueName, err := parseQueueSrn(sub.Endpoint)
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrInvalidParameter, err)
+	}
+	if owner != topicOwner {
+		req := entity.AccessRequest{
+			Service: entity.SnsServicePrincipal,
+			Action:  "SendMessage",
+			Conditions: map[string]string{
+				entity.ConditionSourceArn:     sub.TopicArn,
+				entity.ConditionSourceAccount: topicOwner,
+			},
+		}
+		if err := s.queueSvc.AuthorizeQueueAction(ctx, queueName, owner, req); err != nil {
+			return err
+		}
+	}
+	_, err = s.messageSvc.SendMessage(ctx, queueName, owner, body, "", entity.SendOptions{})
+	return err
+}
+
+// validateNotificationSize checks that the message still fits in a queue once
+// wrapped in the SNS JSON envelope, which escapes it and adds the attributes
+// in base64. Subscription ids all have the length of a UUID.
+func (s *topicService) validateNotificationSize(topicArn string, header nats.Header, message string) error {
+	sub := entity.Subscription{SubscriptionArn: topicArn + ":" + uuid.Nil.String(), TopicArn: topicArn}
+	body, err := json.Marshal(s.notificationOf(sub, header, message))
+	if err != nil {
+		return err
+	}
+	if len(body) > entity.MaxMessageSize {
+		return fmt.Errorf("%w: message must be shorter than %d bytes once wrapped in the notification, it is %d bytes",
+			entity.ErrInvalidParameter, entity.MaxMessageSize, len(body))
+	}
+	return nil
+}
+
+// notificationOf wraps a message published to the topic in the SNS JSON envelope.
+func (s *topicService) notificationOf(sub entity.Subscription, header nats.Header, message string) entity.Notification {
+	notification := entity.Notification{
+		Type:           entity.NotificationTypeNotification,
+		MessageId:      header.Get(entity.HeaderNotificationId),
+		TopicArn:       sub.TopicArn,
+		Subject:        header.Get(entity.HeaderNotificationSubject),
+		Message:        message,
+		Timestamp:      header.Get(entity.HeaderNotificationTimestamp),
+		UnsubscribeURL: s.unsubscribeUrl(sub.SubscriptionArn),
+	}
+	for name, value := range attributesFromHeader(header, entity.HeaderMessageAttributePrefix) {
+		if notification.MessageAttributes == nil {
+			notification.MessageAttributes = make(map[string]entity.NotificationAttribute)
+		}
+		attr := entity.NotificationAttribute{Type: value.DataType, Value: value.StringValue}
+		if strings.HasPrefix(value.DataType, entity.DataTypeBinary) {
+			attr.Value = base64.StdEncoding.EncodeToString(value.BinaryValue)
+		}
+		notification.MessageAttributes[name] = attr
+	}
+	return notification
+}
+
+// unsubscribeUrl builds the ?Action=unsubscribe request of a subscription.
+func (s *topicService) unsubscribeUrl(subscriptionArn string) string {
+	topicArn, _, _ := cutSubscriptionSrn(subscriptionArn)
+	_, account, _, _ := parseQueueSrn(topicArn)
+	return strings.TrimSuffix(s.endpoint, "/") + "/" + url.PathEscape(account) +
+		"?Action=unsubscribe&SubscriptionArn=" + url.QueryEscape(subscriptionArn)
+}
+
+// deliveryBackoff doubles the delay of every attempt, from one second up to maxDeliveryBackoff.
+func deliveryBackoff(delivered uint64) time.Duration {
+	if delivered < 1 {
+		delivered = 1
+	}
+	return min(time.Second<<min(delivered-1, 16), maxDeliveryBackoff)
+}
+
+// validateQueueEndpoint checks that the endpoint of an sqs subscription is a
+// standard queue of this region.
+func validateQueueEndpoint(endpoint, region string) error {
+	endpointRegion, account, name, err := parseQueueSrn(endpoint)
+	if err != nil {
+		return fmt.Errorf("%w: Endpoint: %v", entity.ErrInvalidParameter, err)
+	}
+	if endpointRegion != region {
+		return fmt.Errorf("%w: Endpoint %s is not a queue of region %s", entity.ErrInvalidParameter, endpoint, region)
+	}
+	if err := entity.ValidateAccountId(account); err != nil {
+		return err
+	}
+	if err := entity.ValidateQueueName(name); err != nil {
+		return err
+	}
+	if entity.IsFifoQueue(name) {
+		return fmt.Errorf("%w: Endpoint %s is a FIFO queue, which needs a FIFO topic", entity.ErrInvalidParameter, endpoint)
+	}
+	return nil
+}
+
+// validateNotificationSubject applies the SNS rules: printable ASCII without
+// line breaks, shorter than 100 characters.
+func validateNotificationSubject(subject string) error {
+	if len(subject) > maxNotificationSubjectLength {
+		return fmt.Errorf("%w: Subject must be shorter than %d characters", entity.ErrInvalidParameter, maxNotificationSubjectLength)
+	}
+	for _, r := range subject {
+		if r < 0x20 || r > 0x7e {
+			return fmt.Errorf("%w: Subject must be printable ASCII text", entity.ErrInvalidParameter)
+		}
+	}
+	return nil
+}
+
+// topicSrnPrefix starts the resource of a topic SRN. Queue names never
+// contain '/', so a topic and a queue of the same name have different SRNs.
+const topicSrnPrefix = "topic/"
+
+// makeTopicSrn returns srn:scp:sns:<region>:<account>:topic/<topic>.
+func makeTopicSrn(region, account, name string) string {
+	return makeQueueSrn(region, account, topicSrnPrefix+name).QueueSrn
+}
+
+// resolveTopicSrn returns the topic name of an SRN addressed by account. An
+// SRN of another region does not exist here, one of another account is denied.
+func resolveTopicSrn(srn, account, region string) (string, error) {
+	srnRegion, srnAccount, resource, err := parseQueueSrn(srn)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", entity.ErrInvalidParameter, err)
+	}
+	name, ok := strings.CutPrefix(resource, topicSrnPrefix)
+	if !ok {
+		return "", fmt.Errorf("%w: %s is not a topic srn", entity.ErrInvalidParameter, srn)
+	}
+	if err := entity.ValidateTopicName(name); err != nil {
+		return "", err
+	}
+	if srnRegion != region {
+		return "", fmt.Errorf("%w: %s", entity.ErrTopicNotFound, srn)
+	}
+	if srnAccount != account {
+		return "", fmt.Errorf("%w: %s", entity.ErrAuthorization, srn)
+	}
+	return name, nil
+}
+
+// cutSubscriptionSrn splits <topic srn>:<subscription id>.
+func cutSubscriptionSrn(srn string) (topicArn, id string, ok bool) {
+	i := strings.LastIndex(srn, ":")
+	if i < 0 || i == len(srn)-1 {
+		return "", "", false
+	}
+	return srn[:i], srn[i+1:], true
+}
@@ -0,0 +1,457 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicStreamName(t *testing.T) {
+	stream := entity.TopicStreamName("accountid", "orders")
+	account, name := entity.TopicName(stream)
+	assert.Equal(t, "accountid", account)
+	assert.Equal(t, "orders", name)
+
+	// A topic never collides with the stream or subjects of a queue
+	assert.NotEqual(t, entity.StreamName("accountid", "orders"), stream)
+	assert.False(t, strings.HasPrefix(entity.TopicSubject("accountid", "fifo"), "accountid."))
+	assert.NotEqual(t, entity.StreamName("topic", "accountid.fifo"), entity.TopicStreamName("accountid", "fifo"))
+}
+
+func TestValidateTopicNameLength(t *testing.T) {
+	// The longest names still make a stream name JetStream accepts
+	account := strings.Repeat("a", 64)
+	assert.NoError(t, entity.ValidateAccountId(account))
+	name := strings.Repeat("t", entity.MaxTopicNameLength)
+	assert.NoError(t, entity.ValidateTopicName(name))
+	assert.Len(t, entity.TopicStreamName(account, name), 255)
+
+	assert.ErrorIs(t, entity.ValidateTopicName(name+"t"), entity.ErrInvalidParameter)
+	assert.ErrorIs(t, entity.ValidateAccountId(account+"a"), entity.ErrInvalidParameter)
+}
+
+func TestResolveTopicSrn(t *testing.T) {
+	topicArn := makeTopicSrn("kr-west1", "accountid", "orders")
+	assert.Equal(t, "srn:scp:sns:kr-west1:accountid:topic/orders", topicArn)
+	name, err := resolveTopicSrn(topicArn, "accountid", "kr-west1")
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", name)
+
+	// A topic never has the SRN of a queue of the same name
+	assert.NotEqual(t, makeQueueSrn("kr-west1", "accountid", "orders").QueueSrn, topicArn)
+	_, err = resolveTopicSrn("srn:scp:sns:kr-west1:accountid:orders", "accountid", "kr-west1")
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+	_, err = resolveQueueSrn(topicArn, "accountid", "kr-west1")
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+	assert.ErrorIs(t, validateQueueEndpoint(topicArn, "kr-west1"), entity.ErrInvalidParameter)
+
+	_, err = resolveTopicSrn("srn:scp:sns:kr-west1:other:topic/orders", "accountid", "kr-west1")
+	assert.ErrorIs(t, err, entity.ErrAuthorization)
+	_, err = resolveTopicSrn("srn:scp:sns:kr-east1:accountid:topic/orders", "accountid", "kr-west1")
+	assert.ErrorIs(t, err, entity.ErrTopicNotFound)
+	_, err = resolveTopicSrn("srn:scp:sns:kr-west1:accountid:topic/orders.fifo", "accountid", "kr-west1")
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+	_, err = resolveTopicSrn("orders", "accountid", "kr-west1")
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+
+	topicArn, id, ok := cutSubscriptionSrn("srn:scp:sns:kr-west1:accountid:topic/orders:5f0c")
+	assert.True(t, ok)
+	assert.Equal(t, "srn:scp:sns:kr-west1:accountid:topic/orders", topicArn)
+	assert.Equal(t, "5f0c", id)
+}
+
+func TestValidateQueueEndpoint(t *testing.T) {
+	assert.NoError(t, validateQueueEndpoint("srn:scp:sns:kr-west1:partner:orders", "kr-west1"))
+	assert.ErrorIs(t, validateQueueEndpoint("srn:scp:sns:kr-east1:partner:orders", "kr-west1"), entity.ErrInvalidParameter)
+	assert.ErrorIs(t, validateQueueEndpoint("srn:scp:sns:kr-west1:partner:orders.fifo", "kr-west1"), entity.ErrInvalidParameter)
+	assert.ErrorIs(t, validateQueueEndpoint("https://example.com/hook", "kr-west1"), entity.ErrInvalidParameter)
+}
+
+func TestValidateNotificationSubject(t *testing.T) {
+	assert.NoError(t, validateNotificationSubject(""))
+	assert.NoError(t, validateNotificationSubject("Order #42 created!"))
+	assert.ErrorIs(t, validateNotificationSubject(strings.Repeat("s", maxNotificationSubjectLength+1)), entity.ErrInvalidParameter)
+	assert.ErrorIs(t, validateNotificationSubject("line\nbreak"), entity.ErrInvalidParameter)
+	assert.ErrorIs(t, validateNotificationSubject("주문"), entity.ErrInvalidParameter)
+}
+
+func TestDeliveryBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, deliveryBackoff(0))
+	assert.Equal(t, time.Second, deliveryBackoff(1))
+	assert.Equal(t, 8*time.Second, deliveryBackoff(4))
+	assert.Equal(t, maxDeliveryBackoff, deliveryBackoff(100))
+}
+
+func TestNotificationOf(t *testing.T) {
+	s := &topicService{region: "kr-west1", endpoint: "http://localhost:8080/v1"}
+
+	header := nats.Header{}
+	header.Set(entity.HeaderNotificationId, "0b3d")
+	header.Set(entity.HeaderNotificationSubject, "created")
+	header.Set(entity.HeaderNotificationTimestamp, "2026-10-17T09:30:00.000Z")
+	assert.NoError(t, setAttributeHeaders(header, entity.HeaderMessageAttributePrefix, map[string]entity.MessageAttributeValue{
+		"kind":  {DataType: entity.DataTypeString, StringValue: "order"},
+		"image": {DataType: entity.DataTypeBinary, BinaryValue: []byte{1, 2}},
+	}))
+
+	sub := entity.Subscription{
+		SubscriptionArn: "srn:scp:sns:kr-west1:accountid:topic/orders:5f0c",
+		Endpoint:        "srn:scp:sns:kr-west1:accountid:orders-queue",
+		TopicArn:        "srn:scp:sns:kr-west1:accountid:topic/orders",
+	}
+	notification := s.notificationOf(sub, header, `{"id":42}`)
+
+	body, err := json.Marshal(notification)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"Type": "Notification",
+		"MessageId": "0b3d",
+		"TopicArn": "srn:scp:sns:kr-west1:accountid:topic/orders",
+		"Subject": "created",
+		"Message": "{\"id\":42}",
+		"Timestamp": "2026-10-17T09:30:00.000Z",
+		"UnsubscribeURL": "http://localhost:8080/v1/accountid?Action=unsubscribe&SubscriptionArn=srn%3Ascp%3Asns%3Akr-west1%3Aaccountid%3Atopic%2Forders%3A5f0c",
+		"MessageAttributes": {
+			"kind": {"Type": "String", "Value": "order"},
+			"image": {"Type": "Binary", "Value": "AQI="}
+		}
+	}`, string(body))
+}
+
+func TestValidateNotificationSize(t *testing.T) {
+	s := &topicService{region: "kr-west1", endpoint: "http://localhost:8080/v1"}
+	topicArn := "srn:scp:sns:kr-west1:accountid:topic/orders"
+	header := nats.Header{}
+	header.Set(entity.HeaderNotificationId, uuid.NewString())
+	header.Set(entity.HeaderNotificationTimestamp, time.Now().UTC().Format(entity.NotificationTimestampFormat))
+
+	assert.NoError(t, s.validateNotificationSize(topicArn, header, strings.Repeat("a", entity.MaxMessageSize/2)))
+	// A message within the limit may not fit once escaped in the envelope
+	message := strings.Repeat(`"`, entity.MaxMessageSize/2)
+	assert.NoError(t, validateMessageSize(message, nil, entity.MaxMessageSize))
+	assert.ErrorIs(t, s.validateNotificationSize(topicArn, header, message), entity.ErrInvalidParameter)
+}
+
+// fakeMessageService records the notifications sent to queues, or fails with err.
+type fakeMessageService struct {
+	MessageService
+
+	mu   sync.Mutex
+	sent map[string][]string // bodies keyed by <owner>/<queue>
+	err  error
+}
+
+func (m *fakeMessageService) SendMessage(ctx context.Context, name, account, message, subject string, opts entity.SendOptions) (entity.SendMessageResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return entity.SendMessageResult{}, m.err
+	}
+	if m.sent == nil {
+		m.sent = make(map[string][]string)
+	}
+	m.sent[account+"/"+name] = append(m.sent[account+"/"+name], message)
+	return entity.SendMessageResult{}, nil
+}
+
+func TestDeliver(t *testing.T) {
+	ctx := context.Background()
+	sub := entity.Subscription{
+		SubscriptionArn: "srn:scp:sns:kr-west1:accountid:topic/orders:5f0c",
+		Endpoint:        "srn:scp:sns:kr-west1:accountid:orders-queue",
+		TopicArn:        "srn:scp:sns:kr-west1:accountid:topic/orders",
+	}
+	tests := []struct {
+		name      string
+		err       error
+		endpoint  string
+		delivered uint64
+		want      string
+	}{
+		{name: "delivered", want: repo.AckAck},
+		{name: "rejected", err: fmt.Errorf("%w: message too long", entity.ErrInvalidParameter), want: repo.AckTerm},
+		{name: "queue deleted", err: fmt.Errorf("%w: orders-queue", entity.ErrQueueNotFound), want: repo.AckTerm},
+		{name: "denied", err: entity.ErrAuthorization, want: repo.AckTerm},
+		{name: "queue stream gone", err: publishError(jetstream.ErrNoStreamResponse), want: repo.AckTerm},
+		{name: "above stream limit", err: publishError(&jetstream.APIError{ErrorCode: jsErrCodeMessageTooLarge}), want: repo.AckTerm},
+		{name: "other account without queue", endpoint: "srn:scp:sns:kr-west1:partner:orders-queue", want: repo.AckTerm},
+		{name: "unavailable", err: errors.New("nats: timeout"), delivered: 3, want: repo.AckNak},
+		{name: "last attempt", err: errors.New("nats: timeout"), delivered: maxDeliveryAttempts, want: repo.AckTerm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			natsRepo := newFakeNatsRepo()
+			s := &topicService{
+				natsRepo:   natsRepo,
+				queueSvc:   &queueService{natsRepo: natsRepo, cfg: &config.Config{Region: "kr-west1"}},
+				messageSvc: &fakeMessageService{err: tt.err},
+				region:     "kr-west1",
+				endpoint:   "http://localhost:8080/v1",
+			}
+			sub := sub
+			if tt.endpoint != "" {
+				sub.Endpoint = tt.endpoint
+			}
+			var acks []string
+			msg := testMsg{header: nats.Header{}, data: []byte("hello"), acks: &acks}
+			if tt.delivered > 0 {
+				msg.meta = &jetstream.MsgMetadata{NumDelivered: tt.delivered}
+			}
+			s.deliver(ctx, sub, "accountid", msg)
+			assert.Equal(t, []string{tt.want}, acks)
+		})
+	}
+}
+
+// topicNatsRepo adds the subscription consumers of topic streams to
+// fakeNatsRepo. A message published to a topic is queued for each of them.
+type topicNatsRepo struct {
+	*fakeNatsRepo
+
+	mu        sync.Mutex
+	consumers map[string]*jetstream.ConsumerInfo // keyed by <stream>/<consumer>
+	pending   map[string][]jetstream.Msg
+}
+
+func newTopicNatsRepo() *topicNatsRepo {
+	return &topicNatsRepo{
+		fakeNatsRepo: newFakeNatsRepo(),
+		consumers:    make(map[string]*jetstream.ConsumerInfo),
+		pending:      make(map[string][]jetstream.Msg),
+	}
+}
+
+func (r *topicNatsRepo) CreateConsumer(ctx context.Context, stream string, cfg jetstream.ConsumerConfig) (jetstream.Consumer, error) {
+	if _, err := r.GetStream(ctx, stream); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info := &jetstream.ConsumerInfo{Stream: stream, Name: cfg.Durable, Config: cfg}
+	r.consumers[stream+"/"+cfg.Durable] = info
+	return fakeConsumer{info: info}, nil
+}
+
+func (r *topicNatsRepo) GetConsumer(ctx context.Context, stream, name string) (jetstream.Consumer, error) {
+	if _, err := r.GetStream(ctx, stream); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info, ok := r.consumers[stream+"/"+name]
+	if !ok {
+		return nil, jetstream.ErrConsumerNotFound
+	}
+	return fakeConsumer{info: info}, nil
+}
+
+func (r *topicNatsRepo) ListConsumers(ctx context.Context, stream string) (<-chan *jetstream.ConsumerInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	infoCh := make(chan *jetstream.ConsumerInfo, len(r.consumers))
+	for _, info := range r.consumers {
+		if info.Stream == stream {
+			infoCh <- info
+		}
+	}
+	close(infoCh)
+	return infoCh, nil
+}
+
+func (r *topicNatsRepo) DeleteConsumer(ctx context.Context, stream, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.consumers, stream+"/"+name)
+	return nil
+}
+
+func (r *topicNatsRepo) DeleteStream(ctx context.Context, name string) error {
+	r.fakeNatsRepo.mu.Lock()
+	delete(r.streams, name)
+	r.fakeNatsRepo.mu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, info := range r.consumers {
+		if info.Stream == name {
+			delete(r.consumers, key)
+		}
+	}
+	return nil
+}
+
+func (r *topicNatsRepo) SendMessage(ctx context.Context, message, subject string, header nats.Header) (*jetstream.PubAck, error) {
+	ack, err := r.fakeNatsRepo.SendMessage(ctx, message, subject, header)
+	if err != nil {
+		return nil, err
+	}
+	r.fakeNatsRepo.mu.Lock()
+	var streams []string
+	for name, info := range r.streams {
+		if slices.Contains(info.Config.Subjects, subject) {
+			streams = append(streams, name)
+		}
+	}
+	r.fakeNatsRepo.mu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, info := range r.consumers {
+		if slices.Contains(streams, info.Stream) {
+			r.pending[key] = append(r.pending[key], testMsg{subject: subject, header: header, data: []byte(message), acks: new([]string)})
+		}
+	}
+	return ack, nil
+}
+
+// FetchMessages hands out the messages queued for the consumer. A deleted
+// consumer fails; one deleted while a fetch waits is only noticed by the next
+// fetch, as with a pull request already sent to the server.
+func (r *topicNatsRepo) FetchMessages(ctx context.Context, cons jetstream.Consumer, batch int, wait time.Duration) ([]jetstream.Msg, error) {
+	info := cons.CachedInfo()
+	key := info.Stream + "/" + info.Name
+	if _, err := r.GetConsumer(ctx, info.Stream, info.Name); err != nil {
+		return nil, err
+	}
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(wait)
+	for {
+		r.mu.Lock()
+		msgs := r.pending[key][:min(batch, len(r.pending[key]))]
+		r.pending[key] = r.pending[key][len(msgs):]
+		r.mu.Unlock()
+		if len(msgs) > 0 {
+			return msgs, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// sentTo returns the notifications delivered to a queue of accountid.
+func (m *fakeMessageService) sentTo(queue string) []entity.Notification {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var notifications []entity.Notification
+	for _, body := range m.sent["accountid/"+queue] {
+		var notification entity.Notification
+		if err := json.Unmarshal([]byte(body), &notification); err == nil {
+			notifications = append(notifications, notification)
+		}
+	}
+	return notifications
+}
+
+// workers returns the keys of the delivery workers running on this instance.
+func (s *topicService) workers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Sorted(maps.Keys(s.running))
+}
+
+func TestTopicFanOut(t *testing.T) {
+	ctx := context.Background()
+	natsRepo := newTopicNatsRepo()
+	messageSvc := &fakeMessageService{}
+	cfg := &config.Config{Region: "kr-west1", Endpoint: "http://localhost:8080/v1"}
+	s := NewTopicService(natsRepo, &queueService{natsRepo: natsRepo, cfg: cfg}, messageSvc, cfg).(*topicService)
+	s.Start(ctx)
+	defer s.Stop()
+
+	topic, err := s.CreateTopic(ctx, "orders", "accountid")
+	assert.NoError(t, err)
+	subA, err := s.Subscribe(ctx, topic.TopicArn, "accountid", entity.ProtocolSqs, "srn:scp:sns:kr-west1:accountid:queue-a")
+	assert.NoError(t, err)
+	subB, err := s.Subscribe(ctx, topic.TopicArn, "accountid", entity.ProtocolSqs, "srn:scp:sns:kr-west1:accountid:queue-b")
+	assert.NoError(t, err)
+	assert.NotEqual(t, subA, subB)
+
+	// Subscribing a queue again returns its subscription
+	again, err := s.Subscribe(ctx, topic.TopicArn, "accountid", entity.ProtocolSqs, "srn:scp:sns:kr-west1:accountid:queue-a")
+	assert.NoError(t, err)
+	assert.Equal(t, subA, again)
+	subscriptions, err := s.ListSubscriptionsByTopic(ctx, topic.TopicArn, "accountid", "")
+	assert.NoError(t, err)
+	assert.Len(t, subscriptions.Subscriptions, 2)
+	assert.Len(t, s.workers(), 2)
+	for _, info := range natsRepo.consumers {
+		assert.Equal(t, maxDeliveryAttempts, info.Config.MaxDeliver)
+	}
+
+	// Every subscribed queue receives the message in the notification envelope
+	id, err := s.Publish(ctx, topic.TopicArn, "accountid", `{"id":42}`, entity.PublishOptions{Subject: "created"})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return len(messageSvc.sentTo("queue-a")) == 1 && len(messageSvc.sentTo("queue-b")) == 1
+	}, time.Second, 5*time.Millisecond)
+	for queue, sub := range map[string]string{"queue-a": subA, "queue-b": subB} {
+		notification := messageSvc.sentTo(queue)[0]
+		assert.Equal(t, id, notification.MessageId)
+		assert.Equal(t, topic.TopicArn, notification.TopicArn)
+		assert.Equal(t, `{"id":42}`, notification.Message)
+		assert.Equal(t, s.unsubscribeUrl(sub), notification.UnsubscribeURL)
+	}
+
+	// Unsubscribe stops the worker of the subscription right away
+	assert.NoError(t, s.Unsubscribe(ctx, subB, "accountid"))
+	assert.Eventually(t, func() bool { return len(s.workers()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.ErrorIs(t, s.Unsubscribe(ctx, subB, "accountid"), entity.ErrSubscriptionNotFound)
+
+	_, err = s.Publish(ctx, topic.TopicArn, "accountid", "second", entity.PublishOptions{})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(messageSvc.sentTo("queue-a")) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Len(t, messageSvc.sentTo("queue-b"), 1)
+
+	// DeleteTopic stops the remaining workers
+	assert.NoError(t, s.DeleteTopic(ctx, topic.TopicArn, "accountid"))
+	assert.Eventually(t, func() bool { return len(s.workers()) == 0 }, time.Second, 5*time.Millisecond)
+	_, err = s.Publish(ctx, topic.TopicArn, "accountid", "third", entity.PublishOptions{})
+	assert.ErrorIs(t, err, entity.ErrTopicNotFound)
+}
+
+func TestTopicFanOutUnreachableQueue(t *testing.T) {
+	ctx := context.Background()
+	natsRepo := newTopicNatsRepo()
+	messageSvc := &fakeMessageService{}
+	cfg := &config.Config{Region: "kr-west1", Endpoint: "http://localhost:8080/v1"}
+	s := NewTopicService(natsRepo, &queueService{natsRepo: natsRepo, cfg: cfg}, messageSvc, cfg).(*topicService)
+	s.Start(ctx)
+	defer s.Stop()
+
+	topic, err := s.CreateTopic(ctx, "orders", "accountid")
+	assert.NoError(t, err)
+	_, err = s.Subscribe(ctx, topic.TopicArn, "accountid", entity.ProtocolSqs, "srn:scp:sns:kr-west1:accountid:queue-a")
+	assert.NoError(t, err)
+	// A queue of another account without a Policy never receives anything
+	_, err = s.Subscribe(ctx, topic.TopicArn, "accountid", entity.ProtocolSqs, "srn:scp:sns:kr-west1:partner:queue-b")
+	assert.NoError(t, err)
+
+	for i := range 3 {
+		_, err := s.Publish(ctx, topic.TopicArn, "accountid", fmt.Sprintf("message %d", i), entity.PublishOptions{})
+		assert.NoError(t, err)
+	}
+	assert.Eventually(t, func() bool { return len(messageSvc.sentTo("queue-a")) == 3 }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, messageSvc.sent["partner/queue-b"])
+}
@@ -3,7 +3,6 @@ wrk.headers["Content-Type"] = "application/json"
 wrk.body = [[
 {
   "topicName": "sns-wrk-test",
-  "message": "hello from wrk",
-  "subject": "sns.wrk.test"
+  "message": "hello from wrk"
 }
 ]]
\ No newline at end of file
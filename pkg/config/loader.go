@@ -1,7 +1,9 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -10,12 +12,16 @@ const configPath = "configs/config.yaml"
 
 // 전체 설정 구조체 정의
 type Config struct {
-	Region  string        `yaml:"region"`
-	Env     string        `yaml:"env"`
-	Log     LoggerConfig  `yaml:"log"`
-	Nats    NatsConfig    `yaml:"nats"`
-	Valkey  ValkeyConfig  `yaml:"valkey"`
-	Message MessageConfig `yaml:"message"`
+	Region         string        `yaml:"region"`
+	Env            string        `yaml:"env"`
+	Endpoint       string        `yaml:"endpoint"`       // 큐 URL 의 기준 주소 (API 버전 경로 포함)
+	DefaultAccount string        `yaml:"defaultAccount"` // AWS 프로토콜 요청에 계정이 없을 때 사용하는 계정
+	TrustedProxies []string      `yaml:"trustedProxies"` // X-Forwarded-For 를 믿을 프록시 CIDR, 비어 있으면 접속 주소 사용
+	Log            LoggerConfig  `yaml:"log"`
+	Nats           NatsConfig    `yaml:"nats"`
+	Valkey         ValkeyConfig  `yaml:"valkey"`
+	Message        MessageConfig `yaml:"message"`
+	Auth           AuthConfig    `yaml:"auth"`
 }
 
 type LoggerConfig struct {
@@ -33,7 +39,21 @@ type ValkeyConfig struct {
 }
 
 type MessageConfig struct {
-	Worker int `yaml:"worker"`
+	Worker        int    `yaml:"worker"`
+	ReceiptSecret string `yaml:"receiptSecret"` // HMAC key for receipt handles, shared by all API instances
+}
+
+type AuthConfig struct {
+	Enabled             bool              `yaml:"enabled"`
+	Store               string            `yaml:"store"`               // config: keys 목록 사용, valkey: accesskey:<id> 조회
+	MaxClockSkewSeconds int               `yaml:"maxClockSkewSeconds"` // 서명 시각 허용 오차, 0 이면 300
+	Keys                []AccessKeyConfig `yaml:"keys"`
+}
+
+type AccessKeyConfig struct {
+	AccessKeyId     string `yaml:"accessKeyId"`
+	SecretAccessKey string `yaml:"secretAccessKey"`
+	Account         string `yaml:"account"`
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -48,5 +68,44 @@ func LoadConfig(path string) (*Config, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
+	cfg.expandSecrets()
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
+
+// expandSecrets 는 비밀 값 필드가 ${ENV_NAME} 형식이면 환경 변수 값으로 바꾼다.
+// 파일 전체가 아닌 이 필드들만 바꾸므로 '$' 가 들어간 다른 값은 그대로 남는다.
+func (c *Config) expandSecrets() {
+	c.Valkey.Password = expandSecret(c.Valkey.Password)
+	c.Message.ReceiptSecret = expandSecret(c.Message.ReceiptSecret)
+	for i := range c.Auth.Keys {
+		c.Auth.Keys[i].SecretAccessKey = expandSecret(c.Auth.Keys[i].SecretAccessKey)
+	}
+}
+
+func expandSecret(value string) string {
+	if name, ok := strings.CutPrefix(value, "${"); ok && strings.HasSuffix(name, "}") {
+		return os.Getenv(strings.TrimSuffix(name, "}"))
+	}
+	return value
+}
+
+// validate 는 receipt secret 이 비어 있거나, 인증이 켜져 있는데 서명 키의 secret 이
+// 비어 있으면 시작을 막는다. receipt secret 이 없으면 다른 인스턴스가 발급한
+// receipt handle 을 검증할 수 없다.
+func (c *Config) validate() error {
+	if c.Message.ReceiptSecret == "" {
+		return fmt.Errorf("message.receiptSecret is empty: set it through the environment")
+	}
+	if !c.Auth.Enabled || c.Auth.Store == "valkey" {
+		return nil
+	}
+	for i, key := range c.Auth.Keys {
+		if key.SecretAccessKey == "" {
+			return fmt.Errorf("auth.keys[%d].secretAccessKey of %s is empty: set it through the environment", i, key.AccessKeyId)
+		}
+	}
+	return nil
+}
@@ -1,18 +1,61 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestSampleConfigFile(t *testing.T) {
+	t.Setenv("RECEIPT_SECRET", "receipt-secret")
+	t.Setenv("AUTH_LOCAL_SECRET_ACCESS_KEY", "local-dev-secret")
 	config, err := LoadConfig("../../configs/config.yaml")
 	assert.NoError(t, err)
 	if assert.NotNil(t, config) {
 		assert.Equal(t, "kr-west1", config.Region)
 		assert.Equal(t, "dev2", config.Env)
+		assert.Equal(t, "http://localhost:8080/v1", config.Endpoint)
+		assert.Equal(t, "accountid", config.DefaultAccount)
 		assert.Equal(t, 5, config.Nats.ConnPoolCnt)
 		assert.Equal(t, "localhost:6379", config.Valkey.Addr)
+		assert.True(t, config.Auth.Enabled)
+		if assert.Len(t, config.Auth.Keys, 1) {
+			assert.Equal(t, "accountid", config.Auth.Keys[0].Account)
+			assert.Equal(t, "local-dev-secret", config.Auth.Keys[0].SecretAccessKey)
+		}
+	}
+}
+
+func TestConfigSecretsFromEnvironment(t *testing.T) {
+	t.Setenv("RECEIPT_SECRET", "")
+	t.Setenv("AUTH_LOCAL_SECRET_ACCESS_KEY", "local-dev-secret")
+	_, err := LoadConfig("../../configs/config.yaml")
+	assert.ErrorContains(t, err, "message.receiptSecret")
+
+	t.Setenv("RECEIPT_SECRET", "receipt-secret")
+	t.Setenv("AUTH_LOCAL_SECRET_ACCESS_KEY", "")
+	_, err = LoadConfig("../../configs/config.yaml")
+	assert.ErrorContains(t, err, "auth.keys[0].secretAccessKey")
+
+	// '$' in a secret is kept as is
+	t.Setenv("RECEIPT_SECRET", "receipt$secret")
+	t.Setenv("AUTH_LOCAL_SECRET_ACCESS_KEY", "local-dev-${HOME}")
+	config, err := LoadConfig("../../configs/config.yaml")
+	assert.NoError(t, err)
+	if assert.NotNil(t, config) {
+		assert.Equal(t, "receipt$secret", config.Message.ReceiptSecret)
+		assert.Equal(t, "local-dev-${HOME}", config.Auth.Keys[0].SecretAccessKey)
+	}
+}
+
+func TestConfigLiteralSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("message:\n  receiptSecret: \"pa$$word\"\nauth:\n  enabled: false\n"), 0o600))
+	config, err := LoadConfig(path)
+	assert.NoError(t, err)
+	if assert.NotNil(t, config) {
+		assert.Equal(t, "pa$$word", config.Message.ReceiptSecret)
 	}
 }